@@ -0,0 +1,32 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/simonasgal/jaeger-doris/internal"
+)
+
+// newAdminHandler serves pprof and the maintenance endpoints on the admin port.
+func newAdminHandler(backend *internal.DorisStorage, logger *zap.Logger) http.Handler {
+	mux := http.NewServeMux()
+
+	// registered by net/http/pprof
+	mux.Handle("/debug/pprof/", http.DefaultServeMux)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	mux.HandleFunc("POST /admin/cache/refresh", func(w http.ResponseWriter, r *http.Request) {
+		ctx := internal.LoggerWithContext(r.Context(), logger)
+		err := backend.RefreshCache(ctx)
+		if err != nil {
+			logger.Error("failed to refresh cache", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
@@ -12,11 +12,13 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/reflection"
 
@@ -29,12 +31,6 @@ var configPath string
 const serviceName = "jaeger-doris"
 
 func main() {
-
-	// for debugging only. remove after finished or make it nicer
-	go func() {
-		http.ListenAndServe(":9090", nil)
-	}()
-
 	cfg := &internal.Config{}
 	command := &cobra.Command{
 		Use:   serviceName,
@@ -118,23 +114,56 @@ func run(ctx context.Context, cfg *internal.Config) error {
 	defer backend.Close()
 
 	logger := internal.LoggerFromContext(ctx)
-	grpcHandlerOpts := &shared.GRPCHandlerOptions{SpanBatchSize: int(cfg.Service.GRPCSpanBatchSize)}
+	grpcHandlerOpts := &shared.GRPCHandlerOptions{
+		SpanBatchSize:   int(cfg.Service.GRPCSpanBatchSize),
+		StreamSpans:     cfg.Service.StreamSpans,
+		MaxMessageBytes: int(cfg.Service.GRPCMaxMessageBytes),
+		Logger:          logger,
+	}
 
 	grpcHandler := shared.NewGRPCHandlerWithPlugins(backend, nil, nil, grpcHandlerOpts)
 	compressor, err := grpc.NewGZIPCompressorWithLevel(6)
 	if err != nil {
 		return err
 	}
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	tenancyManager := tenancy.NewManager(&tenancy.Options{
+		Enabled: cfg.Service.Tenancy.Enabled,
+		Header:  cfg.Service.Tenancy.Header,
+		Tenants: cfg.Service.Tenancy.Tenants,
+	})
+
+	accessControl, err := internal.NewAccessControl(logger.With(zap.String("access", "control")), cfg.Service.AccessControl)
+	if err != nil {
+		return err
+	}
+
+	auditLog, err := internal.NewAuditLog(logger.With(zap.String("audit", "log")), cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() { _ = auditLog.Close() }()
+
+	tlsConfig, err := cfg.Service.TLS.ServerTLSConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load TLS config: %w", err)
+	}
+	var serverOpts []grpc.ServerOption
+	if tlsConfig != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	grpcServer := grpc.NewServer(append(serverOpts,
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 			ctx = internal.LoggerWithContext(ctx, logger)
 			res, err := handler(ctx, req)
 			if err != nil && err != context.Canceled {
 				logger.Error("gRPC interceptor", zap.Error(err))
 			}
 			return res, err
-		}),
-		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		},
+			// audit first, so that requests rejected by tenancy or access control are recorded
+			auditLog.UnaryServerInterceptor(), tenancyUnaryInterceptor(tenancyManager), accessControl.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 			ctx := internal.LoggerWithContext(stream.Context(), logger)
 			stream = &contextServerStream{
 				ServerStream: stream,
@@ -145,11 +174,13 @@ func run(ctx context.Context, cfg *internal.Config) error {
 				logger.Error("gRPC interceptor", zap.Error(err))
 			}
 			return err
-		}),
+		},
+			// audit first, so that requests rejected by tenancy or access control are recorded
+			auditLog.StreamServerInterceptor(), tenancyStreamInterceptor(tenancyManager), accessControl.StreamServerInterceptor()),
 		// use deprecated method temporary since it just works
 		// TODO: switch to encoding/gzip
 		grpc.RPCCompressor(compressor),
-	)
+	)...)
 
 	reflection.Register(grpcServer)
 	healthServer := health.NewServer()
@@ -157,6 +188,7 @@ func run(ctx context.Context, cfg *internal.Config) error {
 	if err != nil {
 		return err
 	}
+	registerServices(grpcServer, cfg, backend, logger)
 
 	grpcListener, err := net.Listen("tcp", cfg.Service.Address())
 	if err != nil {
@@ -170,6 +202,45 @@ func run(ctx context.Context, cfg *internal.Config) error {
 		errCh <- grpcServer.Serve(grpcListener)
 	}()
 
+	adminServer := &http.Server{
+		Addr:    cfg.Service.AdminAddress(),
+		Handler: newAdminHandler(backend, logger),
+	}
+	go func() {
+		err := adminServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("admin server failed", zap.Error(err))
+		}
+	}()
+	defer func() { _ = adminServer.Close() }()
+
+	if cfg.Service.HTTPPort != 0 {
+		mux := http.NewServeMux()
+		internal.NewHTTPHandler(logger.With(zap.String("http", "query")), backend).RegisterRoutes(mux)
+		handler := accessControl.HTTPHandler(mux)
+		if tenancyManager.Enabled {
+			handler = tenancy.ExtractTenantHTTPHandler(tenancyManager, handler)
+		}
+		handler = auditLog.HTTPHandler(handler)
+		httpServer := &http.Server{
+			Addr:      cfg.Service.HTTPAddress(),
+			Handler:   handler,
+			TLSConfig: tlsConfig,
+		}
+		go func() {
+			var err error
+			if tlsConfig != nil {
+				err = httpServer.ListenAndServeTLS("", "")
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("HTTP server failed", zap.Error(err))
+			}
+		}()
+		defer func() { _ = httpServer.Close() }()
+	}
+
 	logger.Info("start")
 	<-ctx.Done()
 	logger.Info("exiting")
@@ -192,3 +263,36 @@ func run(ctx context.Context, cfg *internal.Config) error {
 	return err
 
 }
+
+// registerServices registers the optional query services of cfg on the gRPC server.
+func registerServices(grpcServer *grpc.Server, cfg *internal.Config, backend *internal.DorisStorage, logger *zap.Logger) {
+	if cfg.Service.APIv3 {
+		internal.NewQueryService(logger.With(zap.String("grpc", "api_v3")), backend).Register(grpcServer)
+	}
+	if cfg.Service.SPM {
+		internal.NewMetricsQueryService(logger.With(zap.String("grpc", "metrics")), backend).Register(grpcServer)
+	}
+	if cfg.Service.AnalyticsAPI {
+		internal.NewAnalyticsService(logger.With(zap.String("grpc", "analytics")), backend).Register(grpcServer)
+	}
+}
+
+// tenancyUnaryInterceptor attaches the tenant of the request to the context, if tenancy is enabled.
+func tenancyUnaryInterceptor(tm *tenancy.Manager) grpc.UnaryServerInterceptor {
+	if !tm.Enabled {
+		return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(ctx, req)
+		}
+	}
+	return tenancy.NewGuardingUnaryInterceptor(tm)
+}
+
+// tenancyStreamInterceptor attaches the tenant of the request to the context, if tenancy is enabled.
+func tenancyStreamInterceptor(tm *tenancy.Manager) grpc.StreamServerInterceptor {
+	if !tm.Enabled {
+		return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, stream)
+		}
+	}
+	return tenancy.NewGuardingStreamInterceptor(tm)
+}
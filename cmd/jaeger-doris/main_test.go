@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/simonasgal/jaeger-doris/internal"
+)
+
+func TestRegisterServicesAnalytics(t *testing.T) {
+	const method = "/jaeger_doris.analytics.v1.AnalyticsService/GetDurationHistogram"
+
+	for _, enabled := range []bool{true, false} {
+		cfg := &internal.Config{Service: &internal.ServiceConfig{AnalyticsAPI: enabled}}
+
+		listener := bufconn.Listen(1024 * 1024)
+		server := grpc.NewServer()
+		registerServices(server, cfg, &internal.DorisStorage{}, zap.NewNop())
+		go func() { _ = server.Serve(listener) }()
+
+		conn, err := grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+
+		// the request is rejected by the service before it queries doris
+		request, err := structpb.NewStruct(map[string]any{"service": "checkout"})
+		require.NoError(t, err)
+		err = conn.Invoke(context.Background(), method, request, &structpb.Struct{})
+		if enabled {
+			require.Equal(t, codes.InvalidArgument, status.Code(err), err)
+		} else {
+			require.Equal(t, codes.Unimplemented, status.Code(err), err)
+		}
+
+		_ = conn.Close()
+		server.Stop()
+	}
+}
@@ -0,0 +1,27 @@
+package dorisstorage
+
+import (
+	"github.com/simonasgal/jaeger-doris/internal"
+)
+
+// Config is the configuration of the extension in the collector YAML, doris has the same
+// fields as doris in the configuration of jaeger-doris.
+type Config struct {
+	Doris         *internal.DorisConfig `mapstructure:"doris"`
+	TimeoutSecond int64                 `mapstructure:"timeout"` // like service.timeout
+}
+
+func (cfg *Config) Validate() error {
+	return cfg.storageConfig().Validate()
+}
+
+// storageConfig returns the configuration of DorisStorage, its service options are the defaults.
+func (cfg *Config) storageConfig() *internal.Config {
+	c := &internal.Config{
+		Service: &internal.ServiceConfig{TimeoutSecond: cfg.TimeoutSecond},
+		Doris:   cfg.Doris,
+	}
+	c.InitDefaults()
+	cfg.Doris = c.Doris
+	return c
+}
@@ -0,0 +1,165 @@
+package dorisstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jaegertracing/jaeger/pkg/metrics"
+	"github.com/jaegertracing/jaeger/storage"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/jaegertracing/jaeger/storage_v2"
+	spanstore_v2 "github.com/jaegertracing/jaeger/storage_v2/spanstore"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+	"go.uber.org/zap"
+
+	"github.com/simonasgal/jaeger-doris/internal"
+)
+
+var (
+	_ Extension            = (*storageExt)(nil)
+	_ storage.Factory      = (*factory)(nil)
+	_ spanstore_v2.Factory = (*factoryV2)(nil)
+)
+
+var errNotStarted = errors.New("doris storage extension is not started")
+
+// Extension opens DorisStorage when the collector starts, and provides it to other components
+// as jaeger storage factories. Like the jaeger_storage extension, it must be declared before
+// the components using it.
+type Extension interface {
+	extension.Extension
+	Factory() storage.Factory
+	FactoryV2() spanstore_v2.Factory
+}
+
+type storageExt struct {
+	config *Config
+	logger *zap.Logger
+
+	storage *internal.DorisStorage
+}
+
+func newStorageExt(config *Config, telset component.TelemetrySettings) *storageExt {
+	return &storageExt{
+		config: config,
+		logger: telset.Logger,
+	}
+}
+
+// GetStorageFactory locates the doris_storage extension with the given name in host, the name
+// is empty for the extension declared as "doris_storage".
+func GetStorageFactory(name string, host component.Host) (storage.Factory, error) {
+	ext, err := findExtension(name, host)
+	if err != nil {
+		return nil, err
+	}
+	return ext.Factory(), nil
+}
+
+// GetStorageFactoryV2 is GetStorageFactory for the OTLP storage API of jaeger v2.
+func GetStorageFactoryV2(name string, host component.Host) (spanstore_v2.Factory, error) {
+	ext, err := findExtension(name, host)
+	if err != nil {
+		return nil, err
+	}
+	return ext.FactoryV2(), nil
+}
+
+func findExtension(name string, host component.Host) (Extension, error) {
+	id := component.NewIDWithName(componentType, name)
+	comp, ok := host.GetExtensions()[id]
+	if !ok {
+		return nil, fmt.Errorf("cannot find extension '%s' (make sure it's defined earlier in the config)", id)
+	}
+	ext, ok := comp.(Extension)
+	if !ok {
+		return nil, fmt.Errorf("extension '%s' is not a doris storage extension", id)
+	}
+	return ext, nil
+}
+
+func (s *storageExt) Start(ctx context.Context, _ component.Host) error {
+	ds, err := internal.NewDorisStorage(internal.LoggerWithContext(ctx, s.logger), s.config.storageConfig())
+	if err != nil {
+		return fmt.Errorf("failed to initialize doris storage: %w", err)
+	}
+	s.storage = ds
+	return nil
+}
+
+func (s *storageExt) Shutdown(context.Context) error {
+	if s.storage == nil {
+		return nil
+	}
+	return s.storage.Close()
+}
+
+func (s *storageExt) Factory() storage.Factory {
+	return &factory{ext: s}
+}
+
+func (s *storageExt) FactoryV2() spanstore_v2.Factory {
+	return &factoryV2{ext: s}
+}
+
+// factory is the jaeger storage.Factory of the extension, the storage is owned by the extension.
+type factory struct {
+	ext *storageExt
+}
+
+func (*factory) Initialize(metrics.Factory, *zap.Logger) error {
+	return nil
+}
+
+func (f *factory) CreateSpanReader() (spanstore.Reader, error) {
+	if f.ext.storage == nil {
+		return nil, errNotStarted
+	}
+	return f.ext.storage.SpanReader(), nil
+}
+
+func (f *factory) CreateSpanWriter() (spanstore.Writer, error) {
+	if f.ext.storage == nil {
+		return nil, errNotStarted
+	}
+	return f.ext.storage.SpanWriter(), nil
+}
+
+func (f *factory) CreateDependencyReader() (dependencystore.Reader, error) {
+	if f.ext.storage == nil {
+		return nil, errNotStarted
+	}
+	return f.ext.storage.DependencyReader(), nil
+}
+
+// factoryV2 is the OTLP storage factory of jaeger v2, its traces are built directly from the doris records.
+type factoryV2 struct {
+	ext *storageExt
+}
+
+var _ storage_v2.FactoryBase = (*factoryV2)(nil)
+
+func (*factoryV2) Initialize(context.Context) error {
+	return nil
+}
+
+func (*factoryV2) Close(context.Context) error {
+	return nil
+}
+
+func (f *factoryV2) CreateTraceReader() (spanstore_v2.Reader, error) {
+	if f.ext.storage == nil {
+		return nil, errNotStarted
+	}
+	return f.ext.storage.TraceReader(), nil
+}
+
+func (f *factoryV2) CreateTraceWriter() (spanstore_v2.Writer, error) {
+	if f.ext.storage == nil {
+		return nil, errNotStarted
+	}
+	return f.ext.storage.TraceWriter(), nil
+}
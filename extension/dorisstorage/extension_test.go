@@ -0,0 +1,72 @@
+package dorisstorage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/extension"
+)
+
+func TestConfig(t *testing.T) {
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	require.Error(t, cfg.Validate())
+
+	conf := confmap.NewFromStringMap(map[string]any{
+		"timeout": 10,
+		"doris": map[string]any{
+			"endpoint": "doris:9030",
+			"username": "root",
+			"table":    "otel_traces",
+			"find_traces": map[string]any{
+				"sort": "longest",
+			},
+		},
+	})
+	require.NoError(t, conf.Unmarshal(cfg))
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, int64(10), cfg.TimeoutSecond)
+	require.Equal(t, "doris:9030", cfg.Doris.Endpoint)
+	require.Equal(t, "otel_traces", cfg.Doris.Table)
+	require.Equal(t, "longest", cfg.Doris.FindTraces.Sort)
+	require.NotNil(t, cfg.Doris.SchemaMapping)
+}
+
+func TestGetStorageFactory(t *testing.T) {
+	id := component.NewIDWithName(componentType, "traces")
+	ext, err := NewFactory().CreateExtension(context.Background(), extension.Settings{
+		ID:                id,
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}, NewFactory().CreateDefaultConfig())
+	require.NoError(t, err)
+
+	host := &storageHost{extensions: map[component.ID]component.Component{id: ext}}
+	f, err := GetStorageFactory("traces", host)
+	require.NoError(t, err)
+	_, err = f.CreateSpanReader()
+	require.ErrorIs(t, err, errNotStarted)
+
+	f2, err := GetStorageFactoryV2("traces", host)
+	require.NoError(t, err)
+	_, err = f2.CreateTraceReader()
+	require.ErrorIs(t, err, errNotStarted)
+
+	_, err = GetStorageFactory("", host)
+	require.ErrorContains(t, err, "cannot find extension 'doris_storage'")
+	require.NoError(t, ext.Shutdown(context.Background()))
+}
+
+type storageHost struct {
+	extensions map[component.ID]component.Component
+}
+
+func (*storageHost) GetFactory(component.Kind, component.Type) component.Factory {
+	return nil
+}
+
+func (h *storageHost) GetExtensions() map[component.ID]component.Component {
+	return h.extensions
+}
@@ -0,0 +1,30 @@
+package dorisstorage
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+
+	"github.com/simonasgal/jaeger-doris/internal"
+)
+
+// componentType is the name of this extension in configuration.
+var componentType = component.MustNewType("doris_storage")
+
+func NewFactory() extension.Factory {
+	return extension.NewFactory(
+		componentType,
+		createDefaultConfig,
+		createExtension,
+		component.StabilityLevelAlpha,
+	)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{Doris: &internal.DorisConfig{}}
+}
+
+func createExtension(_ context.Context, set extension.Settings, cfg component.Config) (extension.Extension, error) {
+	return newStorageExt(cfg.(*Config), set.TelemetrySettings), nil
+}
@@ -0,0 +1,276 @@
+package internal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// bearerTokenKey is the metadata key of the bearer token propagated by jaeger-query
+// with --query.bearer-token-propagation.
+const bearerTokenKey = "bearer.token"
+
+var errNoIdentity = errors.New("no caller identity")
+
+// AccessControl maps the identity of callers to the services they may read. The permissions
+// are attached to the request context by the interceptors and enforced by accessControlledReader.
+type AccessControl struct {
+	logger     *zap.Logger
+	cfg        *AccessControlConfig
+	jwtKey     any
+	jwtMethods []string
+}
+
+func NewAccessControl(logger *zap.Logger, cfg *AccessControlConfig) (*AccessControl, error) {
+	ac := &AccessControl{
+		logger: logger,
+		cfg:    cfg,
+	}
+
+	if cfg.Enabled && cfg.IdentitySource == IdentitySourceJWT {
+		var err error
+		ac.jwtKey, ac.jwtMethods, err = loadJWTKey(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jwt key: %w", err)
+		}
+	}
+
+	return ac, nil
+}
+
+func loadJWTKey(cfg *AccessControlConfig) (any, []string, error) {
+	if cfg.JWTSecret != "" {
+		return []byte(cfg.JWTSecret), []string{"HS256", "HS384", "HS512"}, nil
+	}
+
+	b, err := os.ReadFile(cfg.JWTKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, nil, errors.New("no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return key, []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}, nil
+	case *ecdsa.PublicKey:
+		return key, []string{"ES256", "ES384", "ES512"}, nil
+	case ed25519.PublicKey:
+		return key, []string{"EdDSA"}, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// servicePermissions lists the service patterns a caller may read, nil permits everything.
+type servicePermissions struct {
+	identity string
+	services []string
+}
+
+func (p *servicePermissions) Allowed(serviceName string) bool {
+	if p == nil {
+		return true
+	}
+	for _, pattern := range p.services {
+		if ok, _ := path.Match(pattern, serviceName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type servicePermissionsKey struct{}
+
+func permissionsWithContext(ctx context.Context, p *servicePermissions) context.Context {
+	return context.WithValue(ctx, servicePermissionsKey{}, p)
+}
+
+func permissionsFromContext(ctx context.Context) *servicePermissions {
+	p, _ := ctx.Value(servicePermissionsKey{}).(*servicePermissions)
+	return p
+}
+
+// permissions returns the services of all rules matching the identity, or the default
+// services if no rule matches.
+func (ac *AccessControl) permissions(identity string) *servicePermissions {
+	p := &servicePermissions{identity: identity}
+	if identity != "" {
+		for _, rule := range ac.cfg.Rules {
+			for _, pattern := range rule.Identities {
+				if ok, _ := path.Match(pattern, identity); ok {
+					p.services = append(p.services, rule.Services...)
+					break
+				}
+			}
+		}
+	}
+	if len(p.services) == 0 {
+		p.services = ac.cfg.DefaultServices
+	}
+	return p
+}
+
+// withPermissions attaches the permissions of the identity to ctx. Callers which cannot be
+// identified get the default services.
+func (ac *AccessControl) withPermissions(ctx context.Context, identity string, err error) context.Context {
+	if err != nil && !errors.Is(err, errNoIdentity) {
+		ac.logger.Debug("failed to identify caller", zap.Error(err))
+	}
+	setAuditIdentity(ctx, identity)
+	return permissionsWithContext(ctx, ac.permissions(identity))
+}
+
+func (ac *AccessControl) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if ac.cfg.Enabled {
+			identity, err := ac.identifyGRPC(ctx)
+			ctx = ac.withPermissions(ctx, identity, err)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (ac *AccessControl) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if ac.cfg.Enabled {
+			identity, err := ac.identifyGRPC(stream.Context())
+			stream = &permissionsServerStream{
+				ServerStream: stream,
+				ctx:          ac.withPermissions(stream.Context(), identity, err),
+			}
+		}
+		return handler(srv, stream)
+	}
+}
+
+type permissionsServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *permissionsServerStream) Context() context.Context {
+	return ss.ctx
+}
+
+// HTTPHandler attaches the permissions of the caller to the requests passed to h.
+func (ac *AccessControl) HTTPHandler(h http.Handler) http.Handler {
+	if !ac.cfg.Enabled {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := ac.identifyHTTP(r)
+		h.ServeHTTP(w, r.WithContext(ac.withPermissions(r.Context(), identity, err)))
+	})
+}
+
+func (ac *AccessControl) identifyGRPC(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	switch ac.cfg.IdentitySource {
+	case IdentitySourceHeader:
+		return firstValue(md.Get(ac.cfg.IdentityHeader))
+	case IdentitySourceMTLS:
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return "", errNoIdentity
+		}
+		tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+		if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+			return "", errNoIdentity
+		}
+		return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName, nil
+	default:
+		token, err := firstValue(md.Get(bearerTokenKey))
+		if err != nil {
+			token, err = bearerToken(md.Get("authorization"))
+			if err != nil {
+				return "", err
+			}
+		}
+		return ac.identityFromToken(token)
+	}
+}
+
+func (ac *AccessControl) identifyHTTP(r *http.Request) (string, error) {
+	switch ac.cfg.IdentitySource {
+	case IdentitySourceHeader:
+		return firstValue(r.Header.Values(ac.cfg.IdentityHeader))
+	case IdentitySourceMTLS:
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return "", errNoIdentity
+		}
+		return r.TLS.VerifiedChains[0][0].Subject.CommonName, nil
+	default:
+		token, err := bearerToken(r.Header.Values("Authorization"))
+		if err != nil {
+			return "", err
+		}
+		return ac.identityFromToken(token)
+	}
+}
+
+// identityFromToken verifies the JWT and returns its identity claim.
+func (ac *AccessControl) identityFromToken(token string) (string, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return ac.jwtKey, nil
+	}, jwt.WithValidMethods(ac.jwtMethods))
+	if err != nil {
+		return "", err
+	}
+
+	switch v := claims[ac.cfg.JWTClaim].(type) {
+	case string:
+		return v, nil
+	case []any:
+		// e.g. the first of several groups
+		if len(v) > 0 {
+			if s, ok := v[0].(string); ok {
+				return s, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("jwt has no string claim %s", ac.cfg.JWTClaim)
+}
+
+func firstValue(values []string) (string, error) {
+	if len(values) == 0 || values[0] == "" {
+		return "", errNoIdentity
+	}
+	return values[0], nil
+}
+
+func bearerToken(values []string) (string, error) {
+	v, err := firstValue(values)
+	if err != nil {
+		return "", err
+	}
+	token, ok := strings.CutPrefix(v, "Bearer ")
+	if !ok {
+		return "", errNoIdentity
+	}
+	return token, nil
+}
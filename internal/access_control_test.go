@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jaegertracing/jaeger/model"
+	dependencyStoreMocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAccessControlPermissions(t *testing.T) {
+	ac, err := NewAccessControl(zap.NewNop(), &AccessControlConfig{
+		Enabled:        true,
+		IdentitySource: IdentitySourceJWT,
+		JWTClaim:       "groups",
+		JWTSecret:      "secret",
+		Rules: []*AccessControlRule{
+			{Identities: []string{"team-a"}, Services: []string{"checkout", "payment-*"}},
+			{Identities: []string{"admin-*"}, Services: []string{"*"}},
+		},
+		DefaultServices: []string{"frontend"},
+	})
+	require.NoError(t, err)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"groups": []any{"team-a"}}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(bearerTokenKey, token))
+	identity, err := ac.identifyGRPC(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "team-a", identity)
+
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"groups": "admin-1"}).SignedString([]byte("other"))
+	require.NoError(t, err)
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+forged))
+	_, err = ac.identifyGRPC(ctx)
+	require.Error(t, err)
+
+	p := ac.permissions("team-a")
+	require.True(t, p.Allowed("checkout"))
+	require.True(t, p.Allowed("payment-api"))
+	require.False(t, p.Allowed("frontend"))
+
+	require.True(t, ac.permissions("admin-1").Allowed("frontend"))
+	require.Equal(t, []string{"frontend"}, ac.permissions("").services)
+
+	var none *servicePermissions
+	require.True(t, none.Allowed("anything"))
+}
+
+func TestAccessControlledReaderFilterSpans(t *testing.T) {
+	permissions := &servicePermissions{identity: "team-a", services: []string{"checkout"}}
+	newSpans := func() []*model.Span {
+		return []*model.Span{
+			{SpanID: 1, OperationName: "GET", Process: &model.Process{ServiceName: "checkout"}},
+			{SpanID: 2, OperationName: "SELECT", Process: &model.Process{ServiceName: "db"}, Tags: []model.KeyValue{model.String("db.statement", "SELECT 1")}},
+		}
+	}
+
+	strip := newAccessControlledReader(nil, nil, nil, AccessDeniedSpansStrip)
+	spans := strip.filterSpans(permissions, newSpans())
+	require.Len(t, spans, 1)
+	require.Equal(t, model.SpanID(1), spans[0].SpanID)
+
+	redact := newAccessControlledReader(nil, nil, nil, AccessDeniedSpansRedact)
+	spans = redact.filterSpans(permissions, newSpans())
+	require.Len(t, spans, 2)
+	require.Equal(t, redactedOperationName, spans[1].OperationName)
+	require.Empty(t, spans[1].Tags)
+
+	ctx := permissionsWithContext(context.Background(), permissions)
+	require.NoError(t, checkServicePermission(ctx, "checkout"))
+	require.Equal(t, codes.PermissionDenied, status.Code(checkServicePermission(ctx, "db")))
+	require.Equal(t, codes.PermissionDenied, status.Code(checkServicePermission(ctx, "")))
+	require.NoError(t, checkServicePermission(context.Background(), ""))
+}
+
+func TestAccessControlServicePatternEscaping(t *testing.T) {
+	permissions := &servicePermissions{identity: "team-a", services: []string{"team-a-*"}}
+	ctx := permissionsWithContext(context.Background(), permissions)
+
+	// the pattern matches, the name must stay a single literal in the queries
+	serviceName := "team-a-x' OR '1'='1"
+	require.NoError(t, checkServicePermission(ctx, serviceName))
+
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	query := queryFindTraceIDs(schema, "otel2.traces", &spanstore.TraceQueryParameters{ServiceName: serviceName, NumTraces: 10}, time.Local, nil, FindTracesSortNewest, nil, nil)
+	require.Contains(t, query, `service_name = 'team-a-x\' OR \'1\'=\'1'`)
+
+	query = queryGetOperations(schema, "otel2.traces", spanstore.OperationQueryParameters{ServiceName: serviceName}, time.Now(), 0, time.Local)
+	require.Contains(t, query, `service_name = "team-a-x\' OR \'1\'=\'1"`)
+}
+
+func TestAccessControlledReaderGetDependencies(t *testing.T) {
+	depsReader := &dependencyStoreMocks.Reader{}
+	depsReader.On("GetDependencies", mock.Anything, mock.Anything, time.Hour).
+		Return([]model.DependencyLink{
+			{Parent: "frontend", Child: "checkout", CallCount: 3},
+			{Parent: "checkout", Child: "db", CallCount: 1},
+		}, nil)
+	reader := newAccessControlledReader(nil, nil, depsReader, AccessDeniedSpansStrip)
+
+	links, err := reader.GetDependencies(context.Background(), time.Now(), time.Hour)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+
+	ctx := permissionsWithContext(context.Background(), &servicePermissions{identity: "team-a", services: []string{"frontend", "checkout"}})
+	links, err = reader.GetDependencies(ctx, time.Now(), time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []model.DependencyLink{{Parent: "frontend", Child: "checkout", CallCount: 3}}, links)
+}
@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/plugin/storage/grpc/shared"
+)
+
+const redactedOperationName = "redacted"
+
+var (
+	_ spanstore.Reader             = (*accessControlledReader)(nil)
+	_ dependencystore.Reader       = (*accessControlledReader)(nil)
+	_ shared.TraceParametersReader = (*accessControlledReader)(nil)
+	_ shared.StreamingSpanReader   = (*accessControlledReader)(nil)
+)
+
+// accessControlledReader enforces the service permissions attached to the request context
+// by AccessControl, requests without permissions are passed through unchanged.
+type accessControlledReader struct {
+	next         spanstore.Reader // serves GetServices and GetOperations, possibly cached
+	dr           *dorisReader
+	dependencies dependencystore.Reader
+	deniedSpans  string
+}
+
+func newAccessControlledReader(next spanstore.Reader, dr *dorisReader, dependencies dependencystore.Reader, deniedSpans string) *accessControlledReader {
+	return &accessControlledReader{
+		next:         next,
+		dr:           dr,
+		dependencies: dependencies,
+		deniedSpans:  deniedSpans,
+	}
+}
+
+func (ar *accessControlledReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
+	return ar.GetTraceWithParameters(ctx, shared.GetTraceParameters{TraceID: traceID})
+}
+
+func (ar *accessControlledReader) GetTraceWithParameters(ctx context.Context, query shared.GetTraceParameters) (*model.Trace, error) {
+	trace, err := ar.dr.GetTraceWithParameters(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	trace.Spans = ar.filterSpans(permissionsFromContext(ctx), trace.Spans)
+	if len(trace.Spans) == 0 {
+		return nil, spanstore.ErrTraceNotFound
+	}
+	return trace, nil
+}
+
+func (ar *accessControlledReader) StreamTrace(ctx context.Context, query shared.GetTraceParameters, fn func(spans []*model.Span) error) error {
+	permissions := permissionsFromContext(ctx)
+	if permissions == nil {
+		return ar.dr.StreamTrace(ctx, query, fn)
+	}
+
+	count := 0
+	err := ar.dr.StreamTrace(ctx, query, func(spans []*model.Span) error {
+		spans = ar.filterSpans(permissions, spans)
+		if len(spans) == 0 {
+			return nil
+		}
+		count += len(spans)
+		return fn(spans)
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return spanstore.ErrTraceNotFound
+	}
+	return nil
+}
+
+func (ar *accessControlledReader) GetServices(ctx context.Context) ([]string, error) {
+	services, err := ar.next.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := permissionsFromContext(ctx)
+	if permissions == nil {
+		return services, nil
+	}
+
+	allowed := make([]string, 0, len(services))
+	for _, service := range services {
+		if permissions.Allowed(service) {
+			allowed = append(allowed, service)
+		}
+	}
+	return allowed, nil
+}
+
+func (ar *accessControlledReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	err := checkServicePermission(ctx, query.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	return ar.next.GetOperations(ctx, query)
+}
+
+func (ar *accessControlledReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	err := checkServicePermission(ctx, query.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	traces, err := ar.dr.FindTraces(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// matching traces may contain spans of other services
+	permissions := permissionsFromContext(ctx)
+	if permissions == nil {
+		return traces, nil
+	}
+	allowed := make([]*model.Trace, 0, len(traces))
+	for _, trace := range traces {
+		trace.Spans = ar.filterSpans(permissions, trace.Spans)
+		if len(trace.Spans) > 0 {
+			allowed = append(allowed, trace)
+		}
+	}
+	return allowed, nil
+}
+
+func (ar *accessControlledReader) StreamTraces(ctx context.Context, query *spanstore.TraceQueryParameters, fn func(spans []*model.Span) error) error {
+	err := checkServicePermission(ctx, query.ServiceName)
+	if err != nil {
+		return err
+	}
+
+	permissions := permissionsFromContext(ctx)
+	if permissions == nil {
+		return ar.dr.StreamTraces(ctx, query, fn)
+	}
+	return ar.dr.StreamTraces(ctx, query, func(spans []*model.Span) error {
+		spans = ar.filterSpans(permissions, spans)
+		if len(spans) == 0 {
+			return nil
+		}
+		return fn(spans)
+	})
+}
+
+func (ar *accessControlledReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	err := checkServicePermission(ctx, query.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	return ar.dr.FindTraceIDs(ctx, query)
+}
+
+func (ar *accessControlledReader) FindTraceIDsPage(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, string, error) {
+	err := checkServicePermission(ctx, query.ServiceName)
+	if err != nil {
+		return nil, "", err
+	}
+	return ar.dr.FindTraceIDsPage(ctx, query)
+}
+
+// SearchTraceQL restricts all spans of the search, and of the summaries, to the allowed services.
+func (ar *accessControlledReader) SearchTraceQL(ctx context.Context, query *traceQLQuery, startTimeMin, startTimeMax time.Time, limit int) ([]*traceSummary, error) {
+	var services []string
+	if permissionsFromContext(ctx) != nil {
+		var err error
+		services, err = ar.GetServices(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ar.dr.searchTraceQL(ctx, query, startTimeMin, startTimeMax, limit, services)
+}
+
+// GetOperationStats requires the permission to read the service of the query.
+func (ar *accessControlledReader) GetOperationStats(ctx context.Context, query *analyticsQuery, errors bool) ([]*operationStats, error) {
+	err := checkServicePermission(ctx, query.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	return ar.dr.getOperationStats(ctx, query, errors)
+}
+
+// GetDurationHistogram requires the permission to read the service of the query.
+func (ar *accessControlledReader) GetDurationHistogram(ctx context.Context, query *analyticsQuery) ([]*durationBucket, error) {
+	err := checkServicePermission(ctx, query.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	return ar.dr.getDurationHistogram(ctx, query)
+}
+
+// GetLogs drops the log records of disallowed services.
+func (ar *accessControlledReader) GetLogs(ctx context.Context, traceID model.TraceID, spanID string, startTime, endTime time.Time, limit int) ([]*logRecord, error) {
+	records, err := ar.dr.getLogs(ctx, traceIDToString(traceID), spanID, startTime, endTime, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := permissionsFromContext(ctx)
+	if permissions == nil {
+		return records, nil
+	}
+	allowed := records[:0]
+	for _, lr := range records {
+		if permissions.Allowed(lr.ServiceName) {
+			allowed = append(allowed, lr)
+		}
+	}
+	return allowed, nil
+}
+
+// GetDependencies drops the links from or to disallowed services.
+func (ar *accessControlledReader) GetDependencies(ctx context.Context, endTs time.Time, lookback time.Duration) ([]model.DependencyLink, error) {
+	links, err := ar.dependencies.GetDependencies(ctx, endTs, lookback)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := permissionsFromContext(ctx)
+	if permissions == nil {
+		return links, nil
+	}
+	allowed := make([]model.DependencyLink, 0, len(links))
+	for _, link := range links {
+		if permissions.Allowed(link.Parent) && permissions.Allowed(link.Child) {
+			allowed = append(allowed, link)
+		}
+	}
+	return allowed, nil
+}
+
+// checkServicePermission rejects searches of disallowed services, and searches across all
+// services of restricted callers.
+func checkServicePermission(ctx context.Context, serviceName string) error {
+	permissions := permissionsFromContext(ctx)
+	if permissions == nil {
+		return nil
+	}
+	if serviceName == "" || !permissions.Allowed(serviceName) {
+		return status.Errorf(codes.PermissionDenied, "%q may not read service %q", permissions.identity, serviceName)
+	}
+	return nil
+}
+
+// filterSpans strips the spans of disallowed services, or redacts them to keep the structure of the trace.
+func (ar *accessControlledReader) filterSpans(permissions *servicePermissions, spans []*model.Span) []*model.Span {
+	if permissions == nil {
+		return spans
+	}
+
+	allowed := spans[:0]
+	for _, span := range spans {
+		if span.Process != nil && permissions.Allowed(span.Process.ServiceName) {
+			allowed = append(allowed, span)
+			continue
+		}
+		if ar.deniedSpans == AccessDeniedSpansRedact {
+			allowed = append(allowed, redactedSpan(span))
+		}
+	}
+	return allowed
+}
+
+func redactedSpan(span *model.Span) *model.Span {
+	serviceName := ""
+	if span.Process != nil {
+		serviceName = span.Process.ServiceName
+	}
+	return &model.Span{
+		TraceID:       span.TraceID,
+		SpanID:        span.SpanID,
+		OperationName: redactedOperationName,
+		References:    span.References,
+		Flags:         span.Flags,
+		StartTime:     span.StartTime,
+		Duration:      span.Duration,
+		Process:       &model.Process{ServiceName: serviceName},
+		Warnings:      []string{"span redacted, the service may not be read"},
+	}
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// analyticsExampleTraces is the number of example traces of each result, the traces of the slowest spans.
+const analyticsExampleTraces = 3
+
+// analyticsQuery selects the spans of a service, and optionally of an operation and a span kind, in a time range.
+type analyticsQuery struct {
+	ServiceName  string
+	Operation    string
+	SpanKind     string // jaeger span kind, e.g. server
+	StartTimeMin time.Time
+	StartTimeMax time.Time
+	Limit        int
+}
+
+// operationStats are the aggregated spans of an operation, durations are in microseconds.
+type operationStats struct {
+	Operation  string   `json:"operation"`
+	Count      int64    `json:"count"`
+	Errors     int64    `json:"errors"`
+	ErrorRatio float64  `json:"errorRatio"`
+	P50        int64    `json:"p50"`
+	P99        int64    `json:"p99"`
+	TraceIDs   []string `json:"traceIDs"`
+}
+
+// durationBucket counts the spans with durations from Min, inclusive, to Max microseconds.
+type durationBucket struct {
+	Min      int64    `json:"min"`
+	Max      int64    `json:"max"`
+	Count    int64    `json:"count"`
+	TraceIDs []string `json:"traceIDs"`
+}
+
+// getOperationStats returns the operations of the service with the highest p99 of the durations,
+// or with the highest ratio of errors when errors is set.
+func (dr *dorisReader) getOperationStats(ctx context.Context, query *analyticsQuery, errors bool) ([]*operationStats, error) {
+	schema := dr.cfg.Doris.SchemaMapping
+	predicates := analyticsPredicates(schema, query, dr.cfg.Doris.Location)
+
+	result := make([]*operationStats, 0)
+	index := make(map[string]*operationStats)
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		stats := &operationStats{Operation: record[schema.SpanName], TraceIDs: make([]string, 0, analyticsExampleTraces)}
+		stats.Count, _ = strconv.ParseInt(record["c"], 10, 64)
+		stats.Errors, _ = strconv.ParseInt(record["e"], 10, 64)
+		if stats.Count > 0 {
+			stats.ErrorRatio = float64(stats.Errors) / float64(stats.Count)
+		}
+		// PERCENTILE_APPROX returns a DOUBLE
+		p50, _ := strconv.ParseFloat(record["p50"], 64)
+		p99, _ := strconv.ParseFloat(record["p99"], 64)
+		stats.P50, stats.P99 = int64(p50), int64(p99)
+		result = append(result, stats)
+		index[stats.Operation] = stats
+		return nil
+	}
+
+	err := executeQuery(ctx, dr.db, dr.cfg, queryOperationStats(schema, dr.cfg.Doris.TableFullName(), predicates, errors, query.Limit), f)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	operations := make([]string, 0, len(result))
+	for _, stats := range result {
+		operations = append(operations, stats.Operation)
+	}
+	predicates = append(predicates, fmt.Sprintf(`%s IN (%s)`, schema.SpanName, quoteStringLiterals(operations)))
+	if errors {
+		predicates = append(predicates, fmt.Sprintf(`%s = '%s'`, schema.StatusCode, schema.statusCodeValue(StatusCodeError)))
+	}
+
+	err = dr.getExampleTraceIDs(ctx, schema.SpanName, predicates, func(k string, traceID string) {
+		if stats, ok := index[k]; ok {
+			stats.TraceIDs = append(stats.TraceIDs, traceID)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getDurationHistogram counts the spans of the operation in buckets of durations that double.
+func (dr *dorisReader) getDurationHistogram(ctx context.Context, query *analyticsQuery) ([]*durationBucket, error) {
+	schema := dr.cfg.Doris.SchemaMapping
+	predicates := analyticsPredicates(schema, query, dr.cfg.Doris.Location)
+
+	result := make([]*durationBucket, 0)
+	index := make(map[int64]*durationBucket)
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		k, err := parseBucketIndex(record["k"])
+		if err != nil {
+			dr.logger.Warn("Failed to parse bucket of histogram", zap.Error(err))
+			return nil
+		}
+		bucket := newDurationBucket(k)
+		bucket.Count, _ = strconv.ParseInt(record["c"], 10, 64)
+		result = append(result, bucket)
+		index[k] = bucket
+		return nil
+	}
+
+	err := executeQuery(ctx, dr.db, dr.cfg, queryDurationHistogram(schema, dr.cfg.Doris.TableFullName(), predicates), f)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	err = dr.getExampleTraceIDs(ctx, durationBucketExpr(schema), predicates, func(k string, traceID string) {
+		i, err := parseBucketIndex(k)
+		if err != nil {
+			return
+		}
+		if bucket, ok := index[i]; ok {
+			bucket.TraceIDs = append(bucket.TraceIDs, traceID)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getExampleTraceIDs calls f with the trace IDs of the slowest matching spans of each value of key.
+func (dr *dorisReader) getExampleTraceIDs(ctx context.Context, key string, predicates []string, f func(k string, traceID string)) error {
+	schema := dr.cfg.Doris.SchemaMapping
+	sql := queryExampleTraceIDs(schema, dr.cfg.Doris.TableFullName(), key, predicates, analyticsExampleTraces)
+	return executeQuery(ctx, dr.db, dr.cfg, sql, func(ctx context.Context, cfg *Config, record map[string]string) error {
+		f(record["k"], schema.decodeID(record[schema.TraceID]))
+		return nil
+	})
+}
+
+func analyticsPredicates(schema *SchemaMapping, query *analyticsQuery, location *time.Location) []string {
+	predicates := []string{fmt.Sprintf(`%s = '%s'`, schema.ServiceName, escapeStringLiteral(query.ServiceName))}
+	if query.Operation != "" {
+		predicates = append(predicates, fmt.Sprintf(`%s = '%s'`, schema.SpanName, escapeStringLiteral(query.Operation)))
+	}
+	if query.SpanKind != "" {
+		predicates = append(predicates, fmt.Sprintf(`%s = '%s'`, schema.SpanKind, schema.spanKindValue(jeagerToOtelSpanKind[query.SpanKind])))
+	}
+	return append(predicates, timeRangePredicates(schema, "", query.StartTimeMin, query.StartTimeMax, location)...)
+}
+
+// parseBucketIndex parses the index of a histogram bucket, FLOOR may return a DOUBLE.
+func parseBucketIndex(v string) (int64, error) {
+	k, err := strconv.ParseFloat(v, 64)
+	return int64(k), err
+}
+
+func newDurationBucket(k int64) *durationBucket {
+	bucket := &durationBucket{Max: 1 << (k + 1), TraceIDs: make([]string, 0, analyticsExampleTraces)}
+	if k > 0 {
+		// the first bucket also holds the durations of 0
+		bucket.Min = 1 << k
+	}
+	return bucket
+}
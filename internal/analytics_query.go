@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// analyticsServiceName is the gRPC service of the span analytics. Its requests and responses are
+// google.protobuf.Struct messages holding the parameters and the JSON responses of the
+// /api/analytics endpoints, so that the service needs no generated code.
+const analyticsServiceName = "jaeger_doris.analytics.v1.AnalyticsService"
+
+// the descriptor of the service is registered for the gRPC reflection service
+func init() {
+	method := func(name string) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".google.protobuf.Struct"),
+			OutputType: proto.String(".google.protobuf.Struct"),
+		}
+	}
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("jaeger_doris/analytics.proto"),
+		Package:    proto.String("jaeger_doris.analytics.v1"),
+		Dependency: []string{"google/protobuf/struct.proto"},
+		Syntax:     proto.String("proto3"),
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("AnalyticsService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("GetSlowOperations"),
+				method("GetErrorOperations"),
+				method("GetDurationHistogram"),
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err == nil {
+		err = protoregistry.GlobalFiles.RegisterFile(file)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+type analyticsServiceServer interface {
+	GetSlowOperations(ctx context.Context, request *structpb.Struct) (*structpb.Struct, error)
+	GetErrorOperations(ctx context.Context, request *structpb.Struct) (*structpb.Struct, error)
+	GetDurationHistogram(ctx context.Context, request *structpb.Struct) (*structpb.Struct, error)
+}
+
+var _ analyticsServiceServer = (*AnalyticsService)(nil)
+
+var analyticsServiceDesc = grpc.ServiceDesc{
+	ServiceName: analyticsServiceName,
+	HandlerType: (*analyticsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetSlowOperations", Handler: analyticsMethodHandler("GetSlowOperations", analyticsServiceServer.GetSlowOperations)},
+		{MethodName: "GetErrorOperations", Handler: analyticsMethodHandler("GetErrorOperations", analyticsServiceServer.GetErrorOperations)},
+		{MethodName: "GetDurationHistogram", Handler: analyticsMethodHandler("GetDurationHistogram", analyticsServiceServer.GetDurationHistogram)},
+	},
+	Metadata: "jaeger_doris/analytics.proto",
+}
+
+// analyticsMethodHandler is the handler protoc-gen-go-grpc would generate for the method.
+func analyticsMethodHandler(name string, call func(analyticsServiceServer, context.Context, *structpb.Struct) (*structpb.Struct, error)) func(any, context.Context, func(any) error, grpc.UnaryServerInterceptor) (any, error) {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		request := &structpb.Struct{}
+		err := dec(request)
+		if err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(analyticsServiceServer), ctx, request)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/" + analyticsServiceName + "/" + name,
+		}
+		return interceptor(ctx, request, info, func(ctx context.Context, req any) (any, error) {
+			return call(srv.(analyticsServiceServer), ctx, req.(*structpb.Struct))
+		})
+	}
+}
+
+// AnalyticsService serves the span analytics of the /api/analytics endpoints on the gRPC port.
+type AnalyticsService struct {
+	logger *zap.Logger
+	reader *accessControlledReader
+}
+
+func NewAnalyticsService(logger *zap.Logger, ds *DorisStorage) *AnalyticsService {
+	return &AnalyticsService{
+		logger: logger,
+		reader: ds.access,
+	}
+}
+
+func (as *AnalyticsService) Register(s *grpc.Server) {
+	s.RegisterService(&analyticsServiceDesc, as)
+}
+
+func (as *AnalyticsService) GetSlowOperations(ctx context.Context, request *structpb.Struct) (*structpb.Struct, error) {
+	return as.getOperationStats(ctx, request, false)
+}
+
+func (as *AnalyticsService) GetErrorOperations(ctx context.Context, request *structpb.Struct) (*structpb.Struct, error) {
+	return as.getOperationStats(ctx, request, true)
+}
+
+func (as *AnalyticsService) getOperationStats(ctx context.Context, request *structpb.Struct, errors bool) (*structpb.Struct, error) {
+	query, err := parseAnalyticsParameters(structParameter(request))
+	if err != nil {
+		return nil, analyticsStatusError(err)
+	}
+	stats, err := as.reader.GetOperationStats(LoggerWithContext(ctx, as.logger), query, errors)
+	if err != nil {
+		return nil, err
+	}
+	return toStruct(&structuredResponse{Data: stats, Total: len(stats), Limit: query.Limit})
+}
+
+func (as *AnalyticsService) GetDurationHistogram(ctx context.Context, request *structpb.Struct) (*structpb.Struct, error) {
+	query, err := parseAnalyticsParameters(structParameter(request))
+	if err != nil {
+		return nil, analyticsStatusError(err)
+	}
+	if query.Operation == "" {
+		return nil, status.Error(codes.InvalidArgument, "parameter 'operation' is required")
+	}
+	buckets, err := as.reader.GetDurationHistogram(LoggerWithContext(ctx, as.logger), query)
+	if err != nil {
+		return nil, err
+	}
+	return toStruct(&structuredResponse{Data: buckets, Total: len(buckets)})
+}
+
+// structParameter returns the string and number fields of request as strings.
+func structParameter(request *structpb.Struct) func(name string) string {
+	return func(name string) string {
+		switch v := request.GetFields()[name].GetKind().(type) {
+		case *structpb.Value_StringValue:
+			return v.StringValue
+		case *structpb.Value_NumberValue:
+			return strconv.FormatFloat(v.NumberValue, 'f', -1, 64)
+		default:
+			return ""
+		}
+	}
+}
+
+func analyticsStatusError(err error) error {
+	var badRequest *badRequestError
+	if errors.As(err, &badRequest) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
+
+// toStruct converts v to a Struct through its JSON encoding.
+func toStruct(v any) (*structpb.Struct, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	err = protojson.Unmarshal(b, s)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
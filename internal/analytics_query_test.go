@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestAnalyticsService(t *testing.T) {
+	cfg := &Config{}
+	require.NoError(t, cfg.Init(configPath))
+	require.NoError(t, cfg.Validate())
+
+	var queries []string
+	db := newFakeDB(t, func(query string) ([]string, [][]driver.Value, error) {
+		queries = append(queries, query)
+		if strings.Contains(query, "PERCENTILE_APPROX") {
+			return []string{cfg.Doris.SchemaMapping.SpanName, "c", "e", "p50", "p99"}, [][]driver.Value{
+				{[]byte("pay"), []byte("10"), []byte("1"), []byte("1000.0"), []byte("5000.0")},
+			}, nil
+		}
+		return []string{"k", cfg.Doris.SchemaMapping.TraceID}, [][]driver.Value{
+			{[]byte("pay"), []byte("01020301000000000000000000000000")},
+		}, nil
+	})
+	dr := &dorisReader{logger: zap.NewNop(), db: db, cfg: cfg}
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	(&AnalyticsService{logger: zap.NewNop(), reader: newAccessControlledReader(dr, dr, nil, "")}).Register(server)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	request, err := structpb.NewStruct(map[string]any{"service": "checkout", "start": 1704070861000000, "end": "1704074461000000"})
+	require.NoError(t, err)
+	response := &structpb.Struct{}
+	err = conn.Invoke(context.Background(), "/"+analyticsServiceName+"/GetSlowOperations", request, response)
+	require.NoError(t, err)
+
+	require.Contains(t, queries[0], "service_name = 'checkout'")
+	require.Equal(t, map[string]any{
+		"data": []any{map[string]any{
+			"operation":  "pay",
+			"count":      float64(10),
+			"errors":     float64(1),
+			"errorRatio": 0.1,
+			"p50":        float64(1000),
+			"p99":        float64(5000),
+			"traceIDs":   []any{"01020301000000000000000000000000"},
+		}},
+		"total":  float64(1),
+		"limit":  float64(defaultHTTPQueryLimit),
+		"offset": float64(0),
+		"errors": nil,
+	}, response.AsMap())
+
+	request, err = structpb.NewStruct(map[string]any{"service": "checkout"})
+	require.NoError(t, err)
+	err = conn.Invoke(context.Background(), "/"+analyticsServiceName+"/GetDurationHistogram", request, response)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(analyticsServiceName)
+	require.NoError(t, err)
+	require.Equal(t, 3, desc.(protoreflect.ServiceDescriptor).Methods().Len())
+}
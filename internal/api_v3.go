@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/cmd/query/app/api_v3"
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/plugin/storage/grpc/shared"
+)
+
+var _ api_v3.QueryServiceServer = (*QueryService)(nil)
+
+// QueryService serves the api_v3 query service of jaeger-query. The traces are built as OTLP
+// directly from the doris records instead of being converted from model.Span.
+type QueryService struct {
+	logger *zap.Logger
+	dr     *dorisReader
+	reader *accessControlledReader
+}
+
+func NewQueryService(logger *zap.Logger, ds *DorisStorage) *QueryService {
+	return &QueryService{
+		logger: logger,
+		dr:     ds.dorisReader,
+		reader: ds.access,
+	}
+}
+
+func (qs *QueryService) Register(s *grpc.Server) {
+	api_v3.RegisterQueryServiceServer(s, qs)
+}
+
+func (qs *QueryService) GetTrace(request *api_v3.GetTraceRequest, stream api_v3.QueryService_GetTraceServer) error {
+	traceID, err := model.TraceIDFromString(request.GetTraceId())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "malformed trace ID: %s", err)
+	}
+
+	query := shared.GetTraceParameters{TraceID: traceID}
+	if request.GetStartTime() != nil {
+		query.StartTime = *request.GetStartTime()
+	}
+	if request.GetEndTime() != nil {
+		query.EndTime = *request.GetEndTime()
+	}
+
+	traces, err := qs.dr.getOTLPTrace(stream.Context(), query, qs.reader.deniedSpans)
+	if errors.Is(err, spanstore.ErrTraceNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+	return qs.send(stream, traces)
+}
+
+// FindTraces sends the matching traces one by one.
+func (qs *QueryService) FindTraces(request *api_v3.FindTracesRequest, stream api_v3.QueryService_FindTracesServer) error {
+	query, err := traceQueryFromAPIv3(request.GetQuery())
+	if err != nil {
+		return err
+	}
+
+	return qs.dr.streamOTLPTraces(stream.Context(), query, qs.reader.deniedSpans, func(traces ptrace.Traces) error {
+		return qs.send(stream, traces)
+	})
+}
+
+func (qs *QueryService) GetServices(ctx context.Context, _ *api_v3.GetServicesRequest) (*api_v3.GetServicesResponse, error) {
+	services, err := qs.reader.GetServices(LoggerWithContext(ctx, qs.logger))
+	if err != nil {
+		return nil, err
+	}
+	return &api_v3.GetServicesResponse{Services: services}, nil
+}
+
+func (qs *QueryService) GetOperations(ctx context.Context, request *api_v3.GetOperationsRequest) (*api_v3.GetOperationsResponse, error) {
+	operations, err := qs.reader.GetOperations(LoggerWithContext(ctx, qs.logger), spanstore.OperationQueryParameters{
+		ServiceName: request.GetService(),
+		SpanKind:    request.GetSpanKind(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api_v3.GetOperationsResponse{
+		Operations: make([]*api_v3.Operation, 0, len(operations)),
+	}
+	for _, operation := range operations {
+		response.Operations = append(response.Operations, &api_v3.Operation{
+			Name:     operation.Name,
+			SpanKind: operation.SpanKind,
+		})
+	}
+	return response, nil
+}
+
+// tracesSender is the stream of GetTrace and FindTraces.
+type tracesSender interface {
+	Send(*api_v3.TracesData) error
+}
+
+func (qs *QueryService) send(stream tracesSender, traces ptrace.Traces) error {
+	tracesData := api_v3.TracesData(traces)
+	err := stream.Send(&tracesData)
+	if err != nil {
+		qs.logger.Error("Failed to send traces", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// traceQueryFromAPIv3 converts the query, the start time range is required like in jaeger-query.
+// The attributes are the tags of the search, including "_sort" and "_cursor".
+func traceQueryFromAPIv3(query *api_v3.TraceQueryParameters) (*spanstore.TraceQueryParameters, error) {
+	if query == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing query")
+	}
+	if query.GetStartTimeMin() == nil || query.GetStartTimeMax() == nil {
+		return nil, status.Error(codes.InvalidArgument, "start time min and max are required parameters")
+	}
+
+	tags := query.GetAttributes()
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	result := &spanstore.TraceQueryParameters{
+		ServiceName:   query.GetServiceName(),
+		OperationName: query.GetOperationName(),
+		Tags:          tags,
+		NumTraces:     int(query.GetNumTraces()),
+	}
+	if result.NumTraces <= 0 {
+		// an unset limit would match no traces
+		result.NumTraces = defaultHTTPQueryLimit
+	}
+
+	var errs []error
+	var err error
+	result.StartTimeMin, err = types.TimestampFromProto(query.GetStartTimeMin())
+	errs = append(errs, err)
+	result.StartTimeMax, err = types.TimestampFromProto(query.GetStartTimeMax())
+	errs = append(errs, err)
+	if query.GetDurationMin() != nil {
+		result.DurationMin, err = types.DurationFromProto(query.GetDurationMin())
+		errs = append(errs, err)
+	}
+	if query.GetDurationMax() != nil {
+		result.DurationMax, err = types.DurationFromProto(query.GetDurationMax())
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return result, nil
+}
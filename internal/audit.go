@@ -0,0 +1,413 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/proto-gen/storage_v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/cmd/query/app/api_v3"
+)
+
+// auditedMethodPrefixes select the methods served by GRPCHandler and QueryService.
+var auditedMethodPrefixes = []string{"/jaeger.storage.v1.", "/jaeger.api_v3."}
+
+func isAuditedMethod(method string) bool {
+	for _, prefix := range auditedMethodPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditEvent is a line of the audit log, and a row of the audit table.
+type AuditEvent struct {
+	Time         string            `json:"time"`
+	Identity     string            `json:"identity,omitempty"` // set if access control is enabled
+	Tenant       string            `json:"tenant,omitempty"`
+	Peer         string            `json:"peer,omitempty"`
+	Method       string            `json:"method"`          // gRPC method, or HTTP method and path
+	Query        string            `json:"query,omitempty"` // query string of HTTP requests
+	TraceID      string            `json:"trace_id,omitempty"`
+	Service      string            `json:"service,omitempty"`
+	Operation    string            `json:"operation,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	StartTimeMin string            `json:"start_time_min,omitempty"`
+	StartTimeMax string            `json:"start_time_max,omitempty"`
+	DurationMin  string            `json:"duration_min,omitempty"`
+	DurationMax  string            `json:"duration_max,omitempty"`
+	NumTraces    int32             `json:"num_traces,omitempty"`
+	Results      int               `json:"results"` // services, operations, trace IDs or dependency links
+	Traces       int               `json:"traces"`
+	Spans        int               `json:"spans"`
+	LatencyMs    float64           `json:"latency_ms"`
+	Code         string            `json:"code"` // gRPC code, or HTTP status code
+	Error        string            `json:"error,omitempty"`
+}
+
+// AuditLog records who read which traces. It is separate from the operational log.
+type AuditLog struct {
+	logger        *zap.Logger
+	enabled       bool
+	location      *time.Location
+	tenancyHeader string // set if tenancy is enabled
+
+	mu         sync.Mutex
+	out        io.WriteCloser
+	streamLoad *streamLoader
+}
+
+func NewAuditLog(logger *zap.Logger, cfg *Config) (*AuditLog, error) {
+	auditCfg := cfg.Service.Audit
+	a := &AuditLog{
+		logger:   logger,
+		enabled:  auditCfg.Enabled,
+		location: cfg.Doris.Location,
+	}
+	if !a.enabled {
+		return a, nil
+	}
+	if cfg.Service.Tenancy.Enabled {
+		a.tenancyHeader = cfg.Service.Tenancy.Header
+		if a.tenancyHeader == "" {
+			a.tenancyHeader = defaultTenancyHeader
+		}
+	}
+
+	switch auditCfg.Output {
+	case "":
+	case "stdout":
+		a.out = nopWriteCloser{os.Stdout}
+	default:
+		f, err := openRotatingFile(auditCfg.Output, int64(auditCfg.MaxSizeMB)*1024*1024, auditCfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		a.out = f
+	}
+
+	if auditCfg.StreamLoad.Enabled {
+		a.streamLoad = newStreamLoader(logger, auditCfg.StreamLoad, cfg.Doris)
+	}
+
+	return a, nil
+}
+
+func (a *AuditLog) Record(event *AuditEvent) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		a.logger.Warn("failed to marshal audit event", zap.Error(err))
+		return
+	}
+	b = append(b, '\n')
+
+	if a.out != nil {
+		a.mu.Lock()
+		_, err = a.out.Write(b)
+		a.mu.Unlock()
+		if err != nil {
+			a.logger.Warn("failed to write audit event", zap.Error(err))
+		}
+	}
+	if a.streamLoad != nil {
+		a.streamLoad.Add(b)
+	}
+}
+
+// Close flushes the pending events.
+func (a *AuditLog) Close() error {
+	if a.streamLoad != nil {
+		a.streamLoad.Close()
+	}
+	if a.out != nil {
+		return a.out.Close()
+	}
+	return nil
+}
+
+func (a *AuditLog) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !a.enabled || !isAuditedMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		event := a.newEvent(ctx, info.FullMethod)
+		start := time.Now()
+		res, err := handler(auditEventWithContext(ctx, event), req)
+		setAuditRequest(event, req, a.location)
+		setAuditResponse(event, res)
+		a.finish(event, start, err)
+		return res, err
+	}
+}
+
+func (a *AuditLog) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !a.enabled || !isAuditedMethod(info.FullMethod) {
+			return handler(srv, stream)
+		}
+
+		event := a.newEvent(stream.Context(), info.FullMethod)
+		auditStream := &auditServerStream{
+			ServerStream: stream,
+			ctx:          auditEventWithContext(stream.Context(), event),
+			event:        event,
+			location:     a.location,
+		}
+		start := time.Now()
+		err := handler(srv, auditStream)
+		a.finish(auditStream.event, start, err)
+		return err
+	}
+}
+
+// HTTPHandler records the requests of the HTTP APIs. It must wrap the tenancy and access control
+// handlers, so that rejected requests are recorded as well.
+func (a *AuditLog) HTTPHandler(h http.Handler) http.Handler {
+	if !a.enabled {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		event := &AuditEvent{
+			Method:    r.Method + " " + r.URL.Path,
+			Query:     r.URL.RawQuery,
+			Peer:      r.RemoteAddr,
+			TraceID:   httpAuditTraceID(r.URL.Path, query),
+			Service:   firstNonEmpty(query.Get("service"), query.Get("serviceName")),
+			Operation: firstNonEmpty(query.Get("operation"), query.Get("spanName")),
+		}
+		if a.tenancyHeader != "" {
+			event.Tenant = r.Header.Get(a.tenancyHeader)
+		}
+
+		sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		h.ServeHTTP(sw, r.WithContext(auditEventWithContext(r.Context(), event)))
+		event.Time = formatAuditTime(start, a.location)
+		event.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+		event.Code = strconv.Itoa(sw.status)
+		a.Record(event)
+	})
+}
+
+// httpAuditTraceID returns the trace IDs of the trace endpoints of the jaeger and Zipkin APIs.
+func httpAuditTraceID(path string, query url.Values) string {
+	for _, prefix := range []string{"/api/traces/", "/api/v2/trace/"} {
+		if traceID, ok := strings.CutPrefix(path, prefix); ok {
+			traceID, _, _ = strings.Cut(traceID, "/")
+			return traceID
+		}
+	}
+	return strings.Join(query["traceID"], ",")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// newEvent starts the event of a request. The interceptor runs before the tenancy and access
+// control interceptors, so that rejected requests are recorded as well: the tenant is read from
+// the request metadata, and the identity is set by AccessControl through the context.
+func (a *AuditLog) newEvent(ctx context.Context, method string) *AuditEvent {
+	event := &AuditEvent{
+		Method: method,
+		Tenant: tenancy.GetTenant(ctx),
+	}
+	if event.Tenant == "" && a.tenancyHeader != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if tenants := md.Get(a.tenancyHeader); len(tenants) > 0 {
+			event.Tenant = tenants[0]
+		}
+	}
+	if permissions := permissionsFromContext(ctx); permissions != nil {
+		event.Identity = permissions.identity
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		event.Peer = p.Addr.String()
+	}
+	return event
+}
+
+type auditEventKey struct{}
+
+func auditEventWithContext(ctx context.Context, event *AuditEvent) context.Context {
+	return context.WithValue(ctx, auditEventKey{}, event)
+}
+
+// setAuditIdentity records the identity of the caller in the audit event of ctx, if any.
+func setAuditIdentity(ctx context.Context, identity string) {
+	if event, ok := ctx.Value(auditEventKey{}).(*AuditEvent); ok {
+		event.Identity = identity
+	}
+}
+
+func (a *AuditLog) finish(event *AuditEvent, start time.Time, err error) {
+	event.Time = formatAuditTime(start, a.location)
+	event.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	event.Code = status.Code(err).String()
+	if err != nil {
+		event.Error = err.Error()
+	}
+	a.Record(event)
+}
+
+// auditServerStream collects the request and the result counts of a streaming call.
+type auditServerStream struct {
+	grpc.ServerStream
+	ctx         context.Context
+	event       *AuditEvent
+	location    *time.Location
+	lastTraceID model.TraceID
+}
+
+func (ss *auditServerStream) Context() context.Context {
+	return ss.ctx
+}
+
+func (ss *auditServerStream) RecvMsg(m any) error {
+	err := ss.ServerStream.RecvMsg(m)
+	if err == nil {
+		setAuditRequest(ss.event, m, ss.location)
+	}
+	return err
+}
+
+func (ss *auditServerStream) SendMsg(m any) error {
+	switch r := m.(type) {
+	case *storage_v1.SpansResponseChunk:
+		ss.event.Spans += len(r.Spans)
+		for i := range r.Spans {
+			// the spans of a trace are sent contiguously
+			if r.Spans[i].TraceID != ss.lastTraceID || ss.event.Traces == 0 {
+				ss.event.Traces++
+				ss.lastTraceID = r.Spans[i].TraceID
+			}
+		}
+	case *api_v3.TracesData:
+		// QueryService sends a trace per message
+		ss.event.Traces++
+		ss.event.Spans += r.ToTraces().SpanCount()
+	}
+	return ss.ServerStream.SendMsg(m)
+}
+
+func setAuditRequest(event *AuditEvent, req any, location *time.Location) {
+	switch r := req.(type) {
+	case *storage_v1.GetTraceRequest:
+		event.TraceID = r.TraceID.String()
+	case *storage_v1.GetOperationsRequest:
+		event.Service = r.Service
+	case *storage_v1.FindTracesRequest:
+		setAuditQuery(event, r.Query, location)
+	case *storage_v1.FindTraceIDsRequest:
+		setAuditQuery(event, r.Query, location)
+	case *storage_v1.GetDependenciesRequest:
+		event.StartTimeMin = formatAuditTime(r.StartTime, location)
+		event.StartTimeMax = formatAuditTime(r.EndTime, location)
+	case *api_v3.GetTraceRequest:
+		event.TraceID = r.TraceId
+	case *api_v3.GetOperationsRequest:
+		event.Service = r.Service
+	case *api_v3.FindTracesRequest:
+		query, err := traceQueryFromAPIv3(r.Query)
+		if err == nil {
+			setAuditQuery(event, &storage_v1.TraceQueryParameters{
+				ServiceName:   query.ServiceName,
+				OperationName: query.OperationName,
+				Tags:          query.Tags,
+				StartTimeMin:  query.StartTimeMin,
+				StartTimeMax:  query.StartTimeMax,
+				DurationMin:   query.DurationMin,
+				DurationMax:   query.DurationMax,
+				NumTraces:     int32(query.NumTraces),
+			}, location)
+		}
+	}
+}
+
+func setAuditQuery(event *AuditEvent, query *storage_v1.TraceQueryParameters, location *time.Location) {
+	if query == nil {
+		return
+	}
+	event.Service = query.ServiceName
+	event.Operation = query.OperationName
+	event.Tags = query.Tags
+	event.StartTimeMin = formatAuditTime(query.StartTimeMin, location)
+	event.StartTimeMax = formatAuditTime(query.StartTimeMax, location)
+	if query.DurationMin > 0 {
+		event.DurationMin = query.DurationMin.String()
+	}
+	if query.DurationMax > 0 {
+		event.DurationMax = query.DurationMax.String()
+	}
+	event.NumTraces = query.NumTraces
+}
+
+func setAuditResponse(event *AuditEvent, res any) {
+	switch r := res.(type) {
+	case *storage_v1.GetServicesResponse:
+		event.Results = len(r.Services)
+	case *storage_v1.GetOperationsResponse:
+		event.Results = len(r.Operations)
+	case *storage_v1.FindTraceIDsResponse:
+		event.Results = len(r.TraceIDs)
+	case *storage_v1.GetDependenciesResponse:
+		event.Results = len(r.Dependencies)
+	case *api_v3.GetServicesResponse:
+		event.Results = len(r.Services)
+	case *api_v3.GetOperationsResponse:
+		event.Results = len(r.Operations)
+	}
+}
+
+// formatAuditTime formats times like the span timestamps, so that they can be loaded into doris.
+func formatAuditTime(t time.Time, location *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(location).Format(timeFormat)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,282 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+// rotatingFile appends to path, and renames it to path.1, path.2, ... when it exceeds maxSize.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) rotate() error {
+	err := f.file.Close()
+	if err != nil {
+		return err
+	}
+
+	if f.maxBackups == 0 {
+		err = os.Remove(f.path)
+	} else {
+		for i := f.maxBackups - 1; i > 0; i-- {
+			err = os.Rename(f.backupPath(i), f.backupPath(i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		err = os.Rename(f.path, f.backupPath(1))
+	}
+	if err != nil {
+		return err
+	}
+
+	return f.open()
+}
+
+func (f *rotatingFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", f.path, i)
+}
+
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
+
+// streamLoader loads JSON lines into a doris table with Stream Load, in batches of batchSize
+// lines or every flush interval. A batch which fails to load is retried at the next flush with the
+// same label, so that doris loads it at most once.
+type streamLoader struct {
+	logger    *zap.Logger
+	client    *http.Client
+	url       string
+	username  string
+	password  string
+	batchSize int
+	labelID   string // unique per process
+
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	lines int
+	seq   int // of the last batch
+
+	pending []streamLoadBatch // only accessed by the background loop
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+type streamLoadBatch struct {
+	label string
+	body  []byte
+	lines int
+}
+
+// maxPendingStreamLoadBatches bounds the batches kept for retries while doris is unavailable.
+const maxPendingStreamLoadBatches = 100
+
+func newStreamLoader(logger *zap.Logger, cfg *AuditStreamLoadConfig, doris *DorisConfig) *streamLoader {
+	sl := &streamLoader{
+		logger:    logger,
+		url:       fmt.Sprintf("%s/api/%s/%s/_stream_load", cfg.Endpoint, doris.Database, cfg.Table),
+		username:  doris.Username,
+		password:  doris.Password,
+		batchSize: cfg.BatchSize,
+		labelID:   newStreamLoadLabelID(),
+		flushCh:   make(chan struct{}, 1),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+	sl.client = &http.Client{
+		Timeout: time.Minute,
+		// the frontend redirects to a backend, which needs the credentials as well
+		CheckRedirect: func(req *http.Request, _ []*http.Request) error {
+			req.SetBasicAuth(sl.username, sl.password)
+			return nil
+		},
+	}
+
+	go sl.run(cfg.FlushInterval)
+	return sl
+}
+
+func (sl *streamLoader) Add(line []byte) {
+	sl.mu.Lock()
+	sl.buf.Write(line)
+	sl.lines++
+	full := sl.lines >= sl.batchSize
+	sl.mu.Unlock()
+
+	if full {
+		select {
+		case sl.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (sl *streamLoader) run(interval time.Duration) {
+	defer close(sl.doneCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sl.stopCh:
+			sl.flush()
+			for _, batch := range sl.pending {
+				sl.logger.Error("lost audit events after failed stream loads", zap.String("label", batch.label), zap.Int("events", batch.lines))
+			}
+			return
+		case <-ticker.C:
+			sl.flush()
+		case <-sl.flushCh:
+			sl.flush()
+		}
+	}
+}
+
+// Close stops the background loop after a final flush.
+func (sl *streamLoader) Close() {
+	close(sl.stopCh)
+	<-sl.doneCh
+}
+
+func (sl *streamLoader) flush() {
+	sl.mu.Lock()
+	if sl.lines > 0 {
+		sl.seq++
+		sl.pending = append(sl.pending, streamLoadBatch{
+			label: fmt.Sprintf("jaeger_audit_%s_%d", sl.labelID, sl.seq),
+			body:  bytes.Clone(sl.buf.Bytes()),
+			lines: sl.lines,
+		})
+		sl.buf.Reset()
+		sl.lines = 0
+	}
+	sl.mu.Unlock()
+
+	if len(sl.pending) > maxPendingStreamLoadBatches {
+		dropped := sl.pending[:len(sl.pending)-maxPendingStreamLoadBatches]
+		for _, batch := range dropped {
+			sl.logger.Error("dropped audit events after failed stream loads", zap.String("label", batch.label), zap.Int("events", batch.lines))
+		}
+		sl.pending = append([]streamLoadBatch(nil), sl.pending[len(dropped):]...)
+	}
+
+	// load in order, and keep the remaining batches after a failure
+	for len(sl.pending) > 0 {
+		batch := sl.pending[0]
+		err := sl.load(batch.label, batch.body)
+		if err != nil {
+			sl.logger.Error("failed to stream load audit events, retrying at the next flush",
+				zap.String("label", batch.label), zap.Int("events", batch.lines), zap.Int("pending_batches", len(sl.pending)), zap.Error(err))
+			return
+		}
+		sl.pending = sl.pending[1:]
+	}
+}
+
+func newStreamLoadLabelID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type streamLoadResponse struct {
+	Status  string `json:"Status"`
+	Message string `json:"Message"`
+}
+
+func (sl *streamLoader) load(label string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPut, sl.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(sl.username, sl.password)
+	req.Header.Set("Expect", "100-continue")
+	req.Header.Set("label", label)
+	req.Header.Set("format", "json")
+	req.Header.Set("read_json_by_line", "true")
+
+	res, err := sl.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("stream load returned %s: %s", res.Status, b)
+	}
+
+	var result streamLoadResponse
+	err = json.Unmarshal(b, &result)
+	if err != nil {
+		return fmt.Errorf("malformed stream load response: %w", err)
+	}
+	// a batch with an existing label was loaded by a previous attempt
+	if result.Status != "Success" && result.Status != "Publish Timeout" && result.Status != "Label Already Exists" {
+		return fmt.Errorf("stream load failed with status %s: %s", result.Status, result.Message)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/proto-gen/storage_v1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/cmd/query/app/api_v3"
+)
+
+func TestAuditLogUnaryInterceptor(t *testing.T) {
+	var out bytes.Buffer
+	a := &AuditLog{logger: zap.NewNop(), enabled: true, location: time.UTC, out: nopWriteCloser{&out}}
+
+	ctx := permissionsWithContext(context.Background(), &servicePermissions{identity: "alice"})
+	req := &storage_v1.FindTraceIDsRequest{Query: &storage_v1.TraceQueryParameters{
+		ServiceName:  "checkout",
+		StartTimeMin: time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+		DurationMin:  time.Second,
+		NumTraces:    20,
+	}}
+	info := &grpc.UnaryServerInfo{FullMethod: "/jaeger.storage.v1.SpanReaderPlugin/FindTraceIDs"}
+	_, err := a.UnaryServerInterceptor()(ctx, req, info, func(context.Context, any) (any, error) {
+		return &storage_v1.FindTraceIDsResponse{TraceIDs: make([]model.TraceID, 2)}, nil
+	})
+	require.NoError(t, err)
+
+	var event AuditEvent
+	require.NoError(t, json.Unmarshal(out.Bytes(), &event))
+	require.Equal(t, "alice", event.Identity)
+	require.Equal(t, info.FullMethod, event.Method)
+	require.Equal(t, "checkout", event.Service)
+	require.Equal(t, "2024-01-01 01:01:01", event.StartTimeMin)
+	require.Equal(t, "1s", event.DurationMin)
+	require.Equal(t, int32(20), event.NumTraces)
+	require.Equal(t, 2, event.Results)
+	require.Equal(t, "OK", event.Code)
+}
+
+func TestAuditLogBeforeTenancyAndAccessControl(t *testing.T) {
+	var out bytes.Buffer
+	a := &AuditLog{logger: zap.NewNop(), enabled: true, location: time.UTC, tenancyHeader: "x-tenant", out: nopWriteCloser{&out}}
+	tm := tenancy.NewManager(&tenancy.Options{Enabled: true, Header: "x-tenant", Tenants: []string{"acme"}})
+	ac, err := NewAccessControl(zap.NewNop(), &AccessControlConfig{
+		Enabled:        true,
+		IdentitySource: IdentitySourceHeader,
+		IdentityHeader: "x-user",
+	})
+	require.NoError(t, err)
+
+	req := &storage_v1.GetServicesRequest{}
+	info := &grpc.UnaryServerInfo{FullMethod: "/jaeger.storage.v1.SpanReaderPlugin/GetServices"}
+	call := func(tenant string) AuditEvent {
+		out.Reset()
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", tenant, "x-user", "alice"))
+		_, _ = a.UnaryServerInterceptor()(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+			return tenancy.NewGuardingUnaryInterceptor(tm)(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return ac.UnaryServerInterceptor()(ctx, req, info, func(context.Context, any) (any, error) {
+					return &storage_v1.GetServicesResponse{Services: []string{"checkout"}}, nil
+				})
+			})
+		})
+
+		var event AuditEvent
+		require.NoError(t, json.Unmarshal(out.Bytes(), &event))
+		return event
+	}
+
+	event := call("acme")
+	require.Equal(t, "acme", event.Tenant)
+	require.Equal(t, "alice", event.Identity)
+	require.Equal(t, codes.OK.String(), event.Code)
+	require.Equal(t, 1, event.Results)
+
+	event = call("unknown")
+	require.Equal(t, "unknown", event.Tenant)
+	require.Equal(t, codes.PermissionDenied.String(), event.Code)
+	require.NotEmpty(t, event.Error)
+}
+
+func TestAuditLogHTTPHandler(t *testing.T) {
+	var out bytes.Buffer
+	a := &AuditLog{logger: zap.NewNop(), enabled: true, location: time.UTC, tenancyHeader: "x-tenant", out: nopWriteCloser{&out}}
+	tm := tenancy.NewManager(&tenancy.Options{Enabled: true, Header: "x-tenant", Tenants: []string{"acme"}})
+	ac, err := NewAccessControl(zap.NewNop(), &AccessControlConfig{
+		Enabled:        true,
+		IdentitySource: IdentitySourceHeader,
+		IdentityHeader: "x-user",
+	})
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/traces/{traceID}", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "trace not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /api/traces", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	})
+	handler := a.HTTPHandler(tenancy.ExtractTenantHTTPHandler(tm, ac.HTTPHandler(mux)))
+
+	call := func(target string, tenant string) AuditEvent {
+		out.Reset()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("x-tenant", tenant)
+		req.Header.Set("x-user", "alice")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		var event AuditEvent
+		require.NoError(t, json.Unmarshal(out.Bytes(), &event))
+		return event
+	}
+
+	event := call("/api/traces?service=checkout&operation=pay&limit=20", "acme")
+	require.Equal(t, "GET /api/traces", event.Method)
+	require.Equal(t, "service=checkout&operation=pay&limit=20", event.Query)
+	require.Equal(t, "checkout", event.Service)
+	require.Equal(t, "pay", event.Operation)
+	require.Equal(t, "acme", event.Tenant)
+	require.Equal(t, "alice", event.Identity)
+	require.Equal(t, "200", event.Code)
+
+	event = call("/api/traces/0102030405", "acme")
+	require.Equal(t, "0102030405", event.TraceID)
+	require.Equal(t, "404", event.Code)
+
+	event = call("/api/traces/0102030405", "unknown")
+	require.Equal(t, "unknown", event.Tenant)
+	require.Empty(t, event.Identity)
+	require.Equal(t, "401", event.Code)
+}
+
+func TestAuditLogAPIv3(t *testing.T) {
+	var out bytes.Buffer
+	a := &AuditLog{logger: zap.NewNop(), enabled: true, location: time.UTC, out: nopWriteCloser{&out}}
+
+	req := &api_v3.GetOperationsRequest{Service: "checkout"}
+	info := &grpc.UnaryServerInfo{FullMethod: "/jaeger.api_v3.QueryService/GetOperations"}
+	_, err := a.UnaryServerInterceptor()(context.Background(), req, info, func(context.Context, any) (any, error) {
+		return &api_v3.GetOperationsResponse{Operations: make([]*api_v3.Operation, 3)}, nil
+	})
+	require.NoError(t, err)
+
+	var event AuditEvent
+	require.NoError(t, json.Unmarshal(out.Bytes(), &event))
+	require.Equal(t, info.FullMethod, event.Method)
+	require.Equal(t, "checkout", event.Service)
+	require.Equal(t, 3, event.Results)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := openRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		_, err = f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	for file, want := range map[string]string{path: "dddddddd\n", path + ".1": "cccccccc\n", path + ".2": "bbbbbbbb\n"} {
+		b, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Equal(t, want, string(b))
+	}
+	require.NoFileExists(t, path+".3")
+}
+
+func TestStreamLoader(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/otel/audit/_stream_load", r.URL.Path)
+		require.Equal(t, "json", r.Header.Get("format"))
+		username, password, _ := r.BasicAuth()
+		require.Equal(t, "admin:secret", username+":"+password)
+		body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"Status": "Success"}`))
+	}))
+	defer server.Close()
+
+	sl := newStreamLoader(zap.NewNop(), &AuditStreamLoadConfig{
+		Endpoint:      server.URL,
+		Table:         "audit",
+		BatchSize:     100,
+		FlushInterval: time.Hour,
+	}, &DorisConfig{Database: "otel", Username: "admin", Password: "secret"})
+	sl.Add([]byte("{\"method\":\"a\"}\n"))
+	sl.Add([]byte("{\"method\":\"b\"}\n"))
+	sl.Close()
+
+	require.Equal(t, "{\"method\":\"a\"}\n{\"method\":\"b\"}\n", string(body))
+}
+
+func TestStreamLoaderRetry(t *testing.T) {
+	var labels []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		labels = append(labels, r.Header.Get("label"))
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(labels) == 1 {
+			_, _ = w.Write([]byte(`{"Status": "Fail", "Message": "too many tablet versions"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Status": "Success"}`))
+	}))
+	defer server.Close()
+
+	sl := newStreamLoader(zap.NewNop(), &AuditStreamLoadConfig{
+		Endpoint:      server.URL,
+		Table:         "audit",
+		BatchSize:     100,
+		FlushInterval: time.Hour,
+	}, &DorisConfig{Database: "otel"})
+	sl.Add([]byte("{\"method\":\"a\"}\n"))
+	sl.flushCh <- struct{}{}
+	require.Eventually(t, func() bool {
+		sl.mu.Lock()
+		defer sl.mu.Unlock()
+		return sl.seq == 1
+	}, time.Second, time.Millisecond)
+	sl.Add([]byte("{\"method\":\"b\"}\n"))
+	sl.Close()
+
+	// the failed batch is retried with the same label before the next one
+	require.Len(t, labels, 3)
+	require.NotEmpty(t, labels[0])
+	require.Equal(t, labels[0], labels[1])
+	require.NotEqual(t, labels[1], labels[2])
+	require.Equal(t, []string{"{\"method\":\"a\"}\n", "{\"method\":\"a\"}\n", "{\"method\":\"b\"}\n"}, bodies)
+}
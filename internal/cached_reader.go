@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"go.uber.org/zap"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/plugin/storage/grpc/shared"
+)
+
+var (
+	_ spanstore.Reader             = (*cachedReader)(nil)
+	_ shared.TraceParametersReader = (*cachedReader)(nil)
+)
+
+// cachedReader caches the results of GetServices and GetOperations, all other calls
+// are passed through to the underlying reader.
+type cachedReader struct {
+	spanstore.Reader
+
+	dr         *dorisReader
+	logger     *zap.Logger
+	services   *ttlCache[struct{}, []string]
+	operations *ttlCache[spanstore.OperationQueryParameters, []spanstore.Operation]
+}
+
+func newCachedReader(logger *zap.Logger, reader *dorisReader, ttl time.Duration) *cachedReader {
+	return &cachedReader{
+		Reader: reader,
+		dr:     reader,
+		logger: logger,
+		services: newTTLCache(ttl, func(ctx context.Context, _ struct{}) ([]string, error) {
+			return reader.GetServices(ctx)
+		}),
+		operations: newTTLCache(ttl, func(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+			return reader.GetOperations(ctx, query)
+		}),
+	}
+}
+
+func (cr *cachedReader) GetServices(ctx context.Context) ([]string, error) {
+	return cr.services.Get(ctx, struct{}{})
+}
+
+func (cr *cachedReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
+	return cr.operations.Get(ctx, query)
+}
+
+func (cr *cachedReader) GetTraceWithParameters(ctx context.Context, query shared.GetTraceParameters) (*model.Trace, error) {
+	return cr.dr.GetTraceWithParameters(ctx, query)
+}
+
+// Refresh reloads every cached entry, it keeps the entries which fail to reload.
+func (cr *cachedReader) Refresh(ctx context.Context) error {
+	return errors.Join(cr.services.Refresh(ctx), cr.operations.Refresh(ctx))
+}
+
+// runRefresh refreshes the cache every interval until ctx is done.
+func (cr *cachedReader) runRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := cr.Refresh(ctx)
+			if err != nil && ctx.Err() == nil {
+				cr.logger.Warn("failed to refresh services cache", zap.Error(err))
+			}
+		}
+	}
+}
+
+type ttlCacheEntry[V any] struct {
+	value      V
+	loadedAt   time.Time
+	accessedAt time.Time
+}
+
+// ttlCacheCall is a load in progress, shared by the callers missing the same key.
+type ttlCacheCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// maxTTLCacheEntries bounds the entries of a cache, since the keys of GetOperations are chosen by callers.
+const maxTTLCacheEntries = 10000
+
+// ttlCache is a minimal read-through cache: entries expire after ttl, and entries
+// that have not been read for ttl are dropped instead of refreshed. Concurrent misses
+// of a key share a single load. When the cache holds maxEntries entries, the entries
+// not read for ttl, or else the least recently read entry, are dropped to make room.
+type ttlCache[K comparable, V any] struct {
+	ttl        time.Duration
+	maxEntries int
+	load       func(ctx context.Context, key K) (V, error)
+
+	mu       sync.Mutex
+	entries  map[K]*ttlCacheEntry[V]
+	inflight map[K]*ttlCacheCall[V]
+}
+
+func newTTLCache[K comparable, V any](ttl time.Duration, load func(ctx context.Context, key K) (V, error)) *ttlCache[K, V] {
+	return &ttlCache[K, V]{
+		ttl:        ttl,
+		maxEntries: maxTTLCacheEntries,
+		load:       load,
+		entries:    make(map[K]*ttlCacheEntry[V]),
+		inflight:   make(map[K]*ttlCacheCall[V]),
+	}
+}
+
+func (c *ttlCache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		entry.accessedAt = now
+		if now.Sub(entry.loadedAt) < c.ttl {
+			c.mu.Unlock()
+			return entry.value, nil
+		}
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+	call := &ttlCacheCall[V]{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.value, call.err = c.load(ctx, key)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.err == nil {
+		if _, ok := c.entries[key]; !ok {
+			c.evict(now)
+		}
+		c.entries[key] = &ttlCacheEntry[V]{
+			value:      call.value,
+			loadedAt:   now,
+			accessedAt: now,
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.value, call.err
+}
+
+// evict makes room for an entry if the cache is full, c.mu must be held.
+func (c *ttlCache[K, V]) evict(now time.Time) {
+	if len(c.entries) < c.maxEntries {
+		return
+	}
+
+	var oldestKey K
+	var oldest *ttlCacheEntry[V]
+	for key, entry := range c.entries {
+		if now.Sub(entry.accessedAt) >= c.ttl {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == nil || entry.accessedAt.Before(oldest.accessedAt) {
+			oldestKey, oldest = key, entry
+		}
+	}
+	if len(c.entries) >= c.maxEntries && oldest != nil {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// Refresh reloads the entries read within ttl, and drops the others. Entries which fail to
+// reload keep their value, the errors are returned after all entries have been tried.
+func (c *ttlCache[K, V]) Refresh(ctx context.Context) error {
+	now := time.Now()
+
+	c.mu.Lock()
+	keys := make([]K, 0, len(c.entries))
+	for key, entry := range c.entries {
+		if now.Sub(entry.accessedAt) >= c.ttl {
+			delete(c.entries, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	c.mu.Unlock()
+
+	var errs error
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return errors.Join(errs, ctx.Err())
+		}
+
+		value, err := c.load(ctx, key)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		c.mu.Lock()
+		if entry, ok := c.entries[key]; ok {
+			entry.value = value
+			entry.loadedAt = now
+		}
+		c.mu.Unlock()
+	}
+
+	return errs
+}
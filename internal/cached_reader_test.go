@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLCache(t *testing.T) {
+	loads := 0
+	cache := newTTLCache(time.Hour, func(_ context.Context, key string) (int, error) {
+		loads++
+		return len(key) + loads, nil
+	})
+
+	v, err := cache.Get(context.Background(), "abc")
+	require.NoError(t, err)
+	require.Equal(t, 4, v)
+
+	v, err = cache.Get(context.Background(), "abc")
+	require.NoError(t, err)
+	require.Equal(t, 4, v)
+	require.Equal(t, 1, loads)
+
+	require.NoError(t, cache.Refresh(context.Background()))
+	require.Equal(t, 2, loads)
+
+	v, err = cache.Get(context.Background(), "abc")
+	require.NoError(t, err)
+	require.Equal(t, 5, v)
+
+	cache.entries["abc"].accessedAt = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, cache.Refresh(context.Background()))
+	require.Equal(t, 2, loads)
+	require.Empty(t, cache.entries)
+}
+
+func TestTTLCacheMaxEntries(t *testing.T) {
+	cache := newTTLCache(time.Hour, func(_ context.Context, key string) (string, error) {
+		return key, nil
+	})
+	cache.maxEntries = 2
+
+	for _, key := range []string{"a", "b"} {
+		_, err := cache.Get(context.Background(), key)
+		require.NoError(t, err)
+	}
+	cache.entries["a"].accessedAt = time.Now().Add(-time.Minute)
+
+	// the least recently read entry makes room
+	_, err := cache.Get(context.Background(), "c")
+	require.NoError(t, err)
+	require.Len(t, cache.entries, 2)
+	require.NotContains(t, cache.entries, "a")
+
+	// entries not read for ttl are dropped first
+	cache.entries["b"].accessedAt = time.Now().Add(-2 * time.Hour)
+	cache.entries["c"].accessedAt = time.Now().Add(-2 * time.Hour)
+	_, err = cache.Get(context.Background(), "d")
+	require.NoError(t, err)
+	require.Len(t, cache.entries, 1)
+	require.Contains(t, cache.entries, "d")
+}
+
+func TestTTLCacheRefreshErrors(t *testing.T) {
+	fail := false
+	cache := newTTLCache(time.Hour, func(_ context.Context, key string) (string, error) {
+		if fail && key == "a" {
+			return "", errors.New("failed to load a")
+		}
+		return key + "!", nil
+	})
+
+	for _, key := range []string{"a", "b"} {
+		_, err := cache.Get(context.Background(), key)
+		require.NoError(t, err)
+	}
+	cache.entries["b"].value = "stale"
+
+	fail = true
+	require.ErrorContains(t, cache.Refresh(context.Background()), "failed to load a")
+	require.Equal(t, "a!", cache.entries["a"].value)
+	require.Equal(t, "b!", cache.entries["b"].value)
+}
+
+func TestTTLCacheConcurrentMisses(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	cache := newTTLCache(time.Hour, func(_ context.Context, key string) (string, error) {
+		loads.Add(1)
+		<-release
+		return key, nil
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := cache.Get(context.Background(), "a")
+			require.NoError(t, err)
+			require.Equal(t, "a", v)
+		}()
+	}
+	require.Eventually(t, func() bool { return loads.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), loads.Load())
+}
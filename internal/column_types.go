@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// The types of the attribute columns, which decide how attributes are accessed in predicates.
+const (
+	ColumnTypeMap     = "map"     // MAP<STRING, STRING>, the default: col['key']
+	ColumnTypeJSON    = "json"    // JSON, JSONB or a string holding JSON: JSON_EXTRACT_STRING(col, '$."key"')
+	ColumnTypeVariant = "variant" // VARIANT, which splits dotted keys into sub-column paths: col['http']['method']
+)
+
+// detectColumnTypes reads the types of the columns of the span table, so that the attributes
+// columns are accessed and decoded according to their type.
+func detectColumnTypes(ctx context.Context, db *sql.DB, cfg *Config) error {
+	schema := cfg.Doris.SchemaMapping
+
+	columnTypes := make(map[string]string)
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		columnTypes[record["Field"]] = columnTypeFromDoris(record["Type"])
+		return nil
+	}
+
+	err := executeQuery(ctx, db, cfg, queryDescribeTable(cfg.Doris.TableFullName()), f)
+	if err != nil {
+		return err
+	}
+	schema.columnTypes = columnTypes
+
+	logger := LoggerFromContext(ctx)
+	for _, column := range []string{schema.SpanAttributes, schema.ResourceAttributes, schema.Events, schema.Links} {
+		logger.Debug("detected type of column", zap.String("column", column), zap.String("type", schema.columnType(column)))
+	}
+	return nil
+}
+
+func columnTypeFromDoris(typ string) string {
+	typ = strings.ToUpper(typ)
+	switch {
+	case strings.HasPrefix(typ, "VARIANT"):
+		return ColumnTypeVariant
+	case strings.HasPrefix(typ, "JSON"), strings.HasPrefix(typ, "STRING"), strings.HasPrefix(typ, "TEXT"), strings.HasPrefix(typ, "VARCHAR"):
+		return ColumnTypeJSON
+	default:
+		return ColumnTypeMap
+	}
+}
+
+// columnType returns the detected type of column, or the type of the schema profile if it has not
+// been detected, or ColumnTypeMap.
+func (s *SchemaMapping) columnType(column string) string {
+	if typ, ok := s.columnTypes[column]; ok {
+		return typ
+	}
+	if typ, ok := s.defaultColumnTypes[column]; ok {
+		return typ
+	}
+	return ColumnTypeMap
+}
+
+// attributeExpr returns the value of the attribute key of the column, prefixed with the table
+// alias prefix, as a string or, if numeric is set, as a double.
+func (s *SchemaMapping) attributeExpr(prefix string, column string, key string, numeric bool) string {
+	var expr string
+	switch s.columnType(column) {
+	case ColumnTypeJSON:
+		path := `$."` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key) + `"`
+		expr = fmt.Sprintf(`JSON_EXTRACT_STRING(%s%s, '%s')`, prefix, column, escapeStringLiteral(path))
+	case ColumnTypeVariant:
+		expr = prefix + column
+		for _, segment := range strings.Split(key, ".") {
+			expr += fmt.Sprintf(`['%s']`, escapeStringLiteral(segment))
+		}
+		if !numeric {
+			// sub-columns keep the type of their values
+			return fmt.Sprintf(`CAST(%s AS STRING)`, expr)
+		}
+	default:
+		expr = fmt.Sprintf(`%s%s['%s']`, prefix, column, escapeStringLiteral(key))
+	}
+
+	if numeric {
+		return fmt.Sprintf(`CAST(%s AS DOUBLE)`, expr)
+	}
+	return expr
+}
+
+// unmarshalAttributes decodes the attributes of column.
+func (s *SchemaMapping) unmarshalAttributes(column string, data string) (map[string]any, error) {
+	attributes := make(map[string]any)
+	err := unmarshalUseNumber(data, &attributes)
+	if err != nil {
+		return nil, err
+	}
+	return s.flattenAttributes(column, attributes), nil
+}
+
+// flattenAttributes joins the keys of the nested objects VARIANT columns return for dotted keys,
+// and decodes the values of the binary attributes.
+func (s *SchemaMapping) flattenAttributes(column string, attributes map[string]any) map[string]any {
+	if s.columnType(column) == ColumnTypeVariant {
+		flattened := make(map[string]any, len(attributes))
+		flattenInto(flattened, "", attributes)
+		attributes = flattened
+	}
+	s.decodeBinaryAttributes(attributes)
+	return attributes
+}
+
+// decodeBinaryAttributes replaces the base64 strings of the keys matching binaryAttributes with
+// their bytes, values which are not valid base64 are kept as strings.
+func (s *SchemaMapping) decodeBinaryAttributes(attributes map[string]any) {
+	if len(s.binaryAttributes) == 0 {
+		return
+	}
+	for k, v := range attributes {
+		str, ok := v.(string)
+		if !ok || !s.isBinaryAttribute(k) {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(str)
+		if err == nil {
+			attributes[k] = b
+		}
+	}
+}
+
+func (s *SchemaMapping) isBinaryAttribute(key string) bool {
+	for _, pattern := range s.binaryAttributes {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func flattenInto(dst map[string]any, prefix string, attributes map[string]any) {
+	for k, v := range attributes {
+		if nested, ok := v.(map[string]any); ok && len(nested) > 0 {
+			flattenInto(dst, prefix+k+".", nested)
+			continue
+		}
+		dst[prefix+k] = v
+	}
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestColumnTypeFromDoris(t *testing.T) {
+	for typ, want := range map[string]string{
+		"MAP<TEXT,TEXT>":                    ColumnTypeMap,
+		"map<varchar(65533),text>":          ColumnTypeMap,
+		"JSON":                              ColumnTypeJSON,
+		"JSONB":                             ColumnTypeJSON,
+		"TEXT":                              ColumnTypeJSON,
+		"VARCHAR(65533)":                    ColumnTypeJSON,
+		"VARIANT":                           ColumnTypeVariant,
+		"variant<'http.method':text>":       ColumnTypeVariant,
+		"ARRAY<STRUCT<name:TEXT>>":          ColumnTypeMap,
+		"STRUCT<timestamp:DATETIME(6)>":     ColumnTypeMap,
+		"MAP<STRING,STRING> NOT NULL":       ColumnTypeMap,
+		"JSON COMMENT 'span attributes'":    ColumnTypeJSON,
+		"VARIANT COMMENT 'span attributes'": ColumnTypeVariant,
+	} {
+		require.Equal(t, want, columnTypeFromDoris(typ), typ)
+	}
+}
+
+func TestAttributeExpr(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	require.Equal(t, `s0.span_attributes['http.method']`, schema.attributeExpr("s0.", "span_attributes", "http.method", false))
+	require.Equal(t, `CAST(span_attributes['http.status_code'] AS DOUBLE)`, schema.attributeExpr("", "span_attributes", "http.status_code", true))
+
+	schema.columnTypes = map[string]string{"span_attributes": ColumnTypeJSON, "resource_attributes": ColumnTypeVariant}
+	require.Equal(t, `JSON_EXTRACT_STRING(span_attributes, '$.\"http.method\"')`, schema.attributeExpr("", "span_attributes", "http.method", false))
+	require.Equal(t, `JSON_EXTRACT_STRING(span_attributes, '$.\"it\'s \\\"quoted\\\"\"')`, schema.attributeExpr("", "span_attributes", `it's "quoted"`, false))
+	require.Equal(t, `CAST(JSON_EXTRACT_STRING(span_attributes, '$.\"http.status_code\"') AS DOUBLE)`, schema.attributeExpr("", "span_attributes", "http.status_code", true))
+	require.Equal(t, `CAST(s1.resource_attributes['host']['name'] AS STRING)`, schema.attributeExpr("s1.", "resource_attributes", "host.name", false))
+	require.Equal(t, `CAST(resource_attributes['process']['pid'] AS DOUBLE)`, schema.attributeExpr("", "resource_attributes", "process.pid", true))
+
+	query := queryFindTraceIDs(schema, "otel.traces", &spanstore.TraceQueryParameters{
+		Tags:      map[string]string{"error": "true"},
+		NumTraces: 10,
+	}, time.UTC, nil, FindTracesSortNewest, nil, nil)
+	require.Contains(t, query, `((JSON_EXTRACT_STRING(span_attributes, '$.\"error.msg\"') IS NOT NULL) OR (status_code == "STATUS_CODE_ERROR"))`)
+}
+
+func TestFlattenAttributes(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	attributes := map[string]any{"http": map[string]any{"method": "GET", "status_code": json.Number("200")}, "empty": map[string]any{}}
+	require.Equal(t, attributes, schema.flattenAttributes("span_attributes", attributes))
+
+	schema.columnTypes = map[string]string{"span_attributes": ColumnTypeVariant}
+	require.Equal(t, map[string]any{"http.method": "GET", "http.status_code": json.Number("200"), "empty": map[string]any{}},
+		schema.flattenAttributes("span_attributes", attributes))
+}
+
+func TestDecodeBinaryAttributes(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+	schema.binaryAttributes = []string{"payload", "grpc.*"}
+
+	attributes, err := schema.unmarshalAttributes("span_attributes", `{"payload":"AQI=","grpc.key":"AAE=","other":"AQI=","invalid":"!","grpc.number":1}`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"payload":     []byte{1, 2},
+		"grpc.key":    []byte{0, 1},
+		"other":       "AQI=",
+		"invalid":     "!",
+		"grpc.number": json.Number("1"),
+	}, attributes)
+	require.Equal(t, model.Binary("payload", []byte{1, 2}), kvToKeyValue("payload", attributes["payload"]))
+
+	// values which are not valid base64 stay strings
+	schema.binaryAttributes = []string{"*"}
+	attributes = schema.flattenAttributes("span_attributes", map[string]any{"invalid": "!"})
+	require.Equal(t, map[string]any{"invalid": "!"}, attributes)
+}
+
+func TestRecordToSpanVariant(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+	schema.columnTypes = map[string]string{"span_attributes": ColumnTypeVariant, "resource_attributes": ColumnTypeVariant, "events": ColumnTypeVariant}
+	cfg := &Config{Doris: &DorisConfig{SchemaMapping: schema, Location: time.UTC}}
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	span, err := recordToSpan(ctx, cfg, map[string]string{
+		"service_name":        "checkout",
+		"timestamp":           "2024-01-01 01:01:01.000001",
+		"trace_id":            "01020301000000000000000000000000",
+		"span_id":             "0102030100000000",
+		"span_name":           "GET",
+		"span_kind":           SpanKindServer,
+		"duration":            "1000",
+		"span_attributes":     `{"http":{"method":"GET","status_code":200}}`,
+		"events":              `[{"timestamp":"2024-01-01 01:01:01.000002","name":"retry","attributes":{"retry":{"count":1}}}]`,
+		"status_message":      "",
+		"status_code":         StatusCodeUnset,
+		"resource_attributes": `{"host":{"name":"localhost"}}`,
+	})
+	require.NoError(t, err)
+
+	tags := model.KeyValues(span.Tags)
+	for _, kv := range []model.KeyValue{model.String("http.method", "GET"), model.Int64("http.status_code", 200)} {
+		tag, ok := tags.FindByKey(kv.Key)
+		require.True(t, ok, kv.Key)
+		require.Equal(t, kv, tag)
+	}
+	require.Contains(t, span.Process.Tags, model.String("host.name", "localhost"))
+	require.Len(t, span.Logs, 1)
+	require.Contains(t, span.Logs[0].Fields, model.Int64("retry.count", 1))
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -90,8 +91,10 @@ func executeQuery(ctx context.Context, db *sql.DB, cfg *Config, query string, f
 }
 
 type otelLink struct {
-	TraceID string `json:"trace_id"`
-	SpanID  string `json:"span_id"`
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	TraceState string         `json:"trace_state"`
+	Attributes map[string]any `json:"attributes"`
 }
 
 type otelEvent struct {
@@ -112,7 +115,7 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 	if !ok {
 		return nil, fmt.Errorf("invalid trace_id")
 	}
-	traceID, err := model.TraceIDFromString(traceIDString)
+	traceID, err := model.TraceIDFromString(schema.decodeID(traceIDString))
 	if err != nil {
 		return nil, err
 	}
@@ -123,7 +126,7 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 	if !ok {
 		return nil, fmt.Errorf("invalid span_id")
 	}
-	spanID, err := model.SpanIDFromString(spanIDString)
+	spanID, err := model.SpanIDFromString(schema.decodeID(spanIDString))
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +142,7 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 	// References
 	references := []model.SpanRef{}
 
-	parentSpanIDString := record[schema.ParentSpanID]
+	parentSpanIDString := schema.decodeID(record[schema.ParentSpanID])
 	if parentSpanIDString != "" {
 		parentSpanID, err := model.SpanIDFromString(parentSpanIDString)
 		if err != nil {
@@ -152,10 +155,13 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 		})
 	}
 
+	// model.SpanRef cannot carry attributes, links with attributes or a trace state
+	// are additionally surfaced as span logs once the start time is known
+	var linksWithAttributes []*otelLink
+
 	referencesFollowsFromString := record[schema.Links]
 	if referencesFollowsFromString != "" {
-		referencesFollowsFrom := []*otelLink{}
-		err = json.Unmarshal([]byte(referencesFollowsFromString), &referencesFollowsFrom)
+		referencesFollowsFrom, err := schema.unmarshalLinks(referencesFollowsFromString, parentSpanIDString)
 		if err != nil {
 			logger.Warn("failed to unmarshal links", zap.Error(err))
 		} else {
@@ -175,6 +181,9 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 					SpanID:  spanID,
 					RefType: model.FollowsFrom,
 				})
+				if len(ref.Attributes) > 0 || ref.TraceState != "" {
+					linksWithAttributes = append(linksWithAttributes, ref)
+				}
 			}
 		}
 	}
@@ -210,8 +219,7 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 
 	tagsString := record[schema.SpanAttributes]
 	if tagsString != "" {
-		attributes := make(map[string]any)
-		err = json.Unmarshal([]byte(tagsString), &attributes)
+		attributes, err := schema.unmarshalAttributes(schema.SpanAttributes, tagsString)
 		if err != nil {
 			logger.Warn("failed to unmarshal span_attributes", zap.Error(err))
 		} else {
@@ -231,7 +239,7 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 	if !ok {
 		logger.Warn("invalid span_kind")
 	} else {
-		spanKind, ok := otelToJeagerSpanKind[spanKind]
+		spanKind, ok := otelToJeagerSpanKind[schema.otelSpanKind(spanKind)]
 		if !ok {
 			logger.Warn("invalid span_kind")
 		} else {
@@ -252,20 +260,33 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 	if !ok {
 		logger.Warn("invalid status_code")
 	} else {
+		statusCode = schema.otelStatusCode(statusCode)
 		tags = append(tags, model.String(SpanTagKeyStatusCode, statusCode))
 		if statusCode == StatusCodeError {
 			tags = append(tags, model.Bool(SpanTagKeyError, true))
 		}
 	}
 
+	// Tags.Scope
+	if scopeName := record[schema.ScopeName]; scopeName != "" {
+		tags = append(tags, model.String(SpanTagKeyScopeName, scopeName))
+	}
+	if scopeVersion := record[schema.ScopeVersion]; scopeVersion != "" {
+		tags = append(tags, model.String(SpanTagKeyScopeVersion, scopeVersion))
+	}
+
+	// Tags.TraceState
+	if traceState := record[schema.TraceState]; traceState != "" {
+		tags = append(tags, model.String(SpanTagKeyW3CTraceState, traceState))
+	}
+
 	span.Tags = tags
 
 	// Logs
 	var logs []model.Log
 	logsString := record[schema.Events]
 	if logsString != "" {
-		events := []*otelEvent{}
-		err = json.Unmarshal([]byte(logsString), &events)
+		events, err := schema.unmarshalEvents(logsString, location)
 		if err != nil {
 			logger.Warn("failed to unmarshal events", zap.Error(err))
 		} else {
@@ -276,9 +297,13 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 					logger.Warn("failed to parse timestamp of event", zap.Error(err))
 					continue
 				}
-				fields := make([]model.KeyValue, 1, len(event.Attributes)+1)
-				fields[0] = model.String(SpanLogFieldKeyEvent, event.Name)
-				for k, v := range event.Attributes {
+				attributes := schema.flattenAttributes(schema.Events, event.Attributes)
+				fields := make([]model.KeyValue, 0, len(attributes)+1)
+				if event.Name != "" || schema.eventsFormat != NestedFormatJaeger {
+					// jaeger logs without an event field stay without one
+					fields = append(fields, model.String(SpanLogFieldKeyEvent, event.Name))
+				}
+				for k, v := range attributes {
 					fields = append(fields, kvToKeyValue(k, v))
 				}
 				logs = append(logs, model.Log{
@@ -288,6 +313,26 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 			}
 		}
 	}
+
+	// Logs.Links
+	for _, link := range linksWithAttributes {
+		fields := make([]model.KeyValue, 0, len(link.Attributes)+4)
+		fields = append(fields,
+			model.String(SpanLogFieldKeyEvent, SpanLogEventLink),
+			model.String(SpanLogFieldKeyLinkTraceID, link.TraceID),
+			model.String(SpanLogFieldKeyLinkSpanID, link.SpanID),
+		)
+		if link.TraceState != "" {
+			fields = append(fields, model.String(SpanTagKeyW3CTraceState, link.TraceState))
+		}
+		for k, v := range schema.flattenAttributes(schema.Links, link.Attributes) {
+			fields = append(fields, kvToKeyValue(k, v))
+		}
+		logs = append(logs, model.Log{
+			Timestamp: span.StartTime,
+			Fields:    fields,
+		})
+	}
 	span.Logs = logs
 
 	// Process
@@ -299,18 +344,25 @@ func recordToSpan(ctx context.Context, cfg *Config, record map[string]string) (*
 	var processTags []model.KeyValue
 	processTagsString := record[schema.ResourceAttributes]
 	if processTagsString != "" {
-		attributes := make(map[string]any)
-		err = json.Unmarshal([]byte(processTagsString), &attributes)
+		attributes, err := schema.unmarshalAttributes(schema.ResourceAttributes, processTagsString)
 		if err != nil {
 			logger.Warn("failed to unmarshal resource_attributes", zap.Error(err))
 		} else {
-			processTags = make([]model.KeyValue, 0, len(attributes))
+			processTags = make([]model.KeyValue, 0, len(attributes)+1)
 			for k, v := range attributes {
 				processTags = append(processTags, kvToKeyValue(k, v))
 			}
 		}
 	}
 
+	// Process.Tags.ServiceInstanceID
+	serviceInstanceID := record[schema.ServiceInstanceID]
+	if serviceInstanceID != "" {
+		if _, ok := model.KeyValues(processTags).FindByKey(ProcessTagKeyServiceInstanceID); !ok {
+			processTags = append(processTags, model.String(ProcessTagKeyServiceInstanceID, serviceInstanceID))
+		}
+	}
+
 	span.Process = &model.Process{
 		ServiceName: serviceName,
 		Tags:        processTags,
@@ -352,16 +404,47 @@ func recordToDependencyLink(_ context.Context, cfg *Config, record map[string]st
 	return dependencyLink, nil
 }
 
+// unmarshalUseNumber decodes attribute JSON keeping numbers as json.Number,
+// so that integers are not turned into float64.
+func unmarshalUseNumber(data string, v any) error {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// kvToKeyValue converts an attribute value decoded from JSON. The doris exporter writes the
+// attributes with encoding/json, so bytes values are stored as base64 strings, which are only
+// decoded into bytes for the keys of doris.binary_attributes. Empty values are stored as null
+// and returned as empty strings, like jaeger translates empty OTLP values.
 func kvToKeyValue(k string, v any) model.KeyValue {
 	switch vv := v.(type) {
+	case nil:
+		return model.String(k, "")
 	case bool:
 		return model.Bool(k, vv)
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return model.Int64(k, i)
+		}
+		if f, err := vv.Float64(); err == nil {
+			return model.Float64(k, f)
+		}
+		return model.String(k, vv.String())
 	case float64:
 		return model.Float64(k, vv)
 	case int64:
 		return model.Int64(k, vv)
 	case string:
 		return model.String(k, vv)
+	case []byte:
+		return model.Binary(k, vv)
+	case []any, map[string]any:
+		// arrays and maps are represented as JSON, map keys are sorted by the encoder
+		b, err := json.Marshal(vv)
+		if err != nil {
+			return model.String(k, fmt.Sprint(vv))
+		}
+		return model.String(k, string(b))
 	default:
 		return model.String(k, fmt.Sprint(vv))
 	}
@@ -388,16 +471,32 @@ const (
 	SpanTagKeyStatusDescription = "otel.status_description"
 	SpanTagKeyStatusCode        = "otel.status_code"
 	SpanTagKeyError             = "error"
+	SpanTagKeyScopeName         = "otel.scope.name"
+	SpanTagKeyScopeVersion      = "otel.scope.version"
+	SpanTagKeyW3CTraceState     = "w3c.tracestate"
+
+	SpanLogFieldKeyEvent       = "event"
+	SpanLogFieldKeyLinkTraceID = "link.trace_id"
+	SpanLogFieldKeyLinkSpanID  = "link.span_id"
+	SpanLogEventLink           = "link"
+
+	ProcessTagKeyServiceInstanceID = "service.instance.id"
 
-	SpanLogFieldKeyEvent = "event"
+	// TagKeySort is a search tag which selects the sort of FindTraces, e.g. "_sort=longest"
+	TagKeySort = "_sort"
+	// TagKeyCursor is a search tag which continues a search after the given cursor, see FindTraceIDsPage
+	TagKeyCursor = "_cursor"
 
 	// TODO reference
-	SpanKindInternal = "SPAN_KIND_INTERNAL"
-	SpanKindServer   = "SPAN_KIND_SERVER"
-	SpanKindClient   = "SPAN_KIND_CLIENT"
-	SpanKindProducer = "SPAN_KIND_PRODUCER"
-	SpanKindConsumer = "SPAN_KIND_CONSUMER"
+	SpanKindUnspecified = "SPAN_KIND_UNSPECIFIED"
+	SpanKindInternal    = "SPAN_KIND_INTERNAL"
+	SpanKindServer      = "SPAN_KIND_SERVER"
+	SpanKindClient      = "SPAN_KIND_CLIENT"
+	SpanKindProducer    = "SPAN_KIND_PRODUCER"
+	SpanKindConsumer    = "SPAN_KIND_CONSUMER"
 
 	// TODO reference
+	StatusCodeUnset = "STATUS_CODE_UNSET"
+	StatusCodeOk    = "STATUS_CODE_OK"
 	StatusCodeError = "STATUS_CODE_ERROR"
 )
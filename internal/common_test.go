@@ -1,10 +1,13 @@
 package internal
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/jaegertracing/jaeger/model"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestTraceIDToString(t *testing.T) {
@@ -15,3 +18,77 @@ func TestTraceIDToString(t *testing.T) {
 
 	require.Equal(t, "00000000000000000000000000000000", traceIDToString(traceID))
 }
+
+func TestKvToKeyValue(t *testing.T) {
+	attributes := make(map[string]any)
+	err := unmarshalUseNumber(`{"int":200,"float":1.5,"exp":1e3,"bool":true,"str":"s","arr":[1,"a"],"map":{"b":2,"a":1},"null":null,"bytes":"AQI="}`, &attributes)
+	require.NoError(t, err)
+
+	require.Equal(t, model.Int64("int", 200), kvToKeyValue("int", attributes["int"]))
+	require.Equal(t, model.Float64("float", 1.5), kvToKeyValue("float", attributes["float"]))
+	require.Equal(t, model.Float64("exp", 1000), kvToKeyValue("exp", attributes["exp"]))
+	require.Equal(t, model.Bool("bool", true), kvToKeyValue("bool", attributes["bool"]))
+	require.Equal(t, model.String("str", "s"), kvToKeyValue("str", attributes["str"]))
+	require.Equal(t, model.String("arr", `[1,"a"]`), kvToKeyValue("arr", attributes["arr"]))
+	require.Equal(t, model.String("map", `{"a":1,"b":2}`), kvToKeyValue("map", attributes["map"]))
+	require.Equal(t, model.String("null", ""), kvToKeyValue("null", attributes["null"]))
+	require.Equal(t, model.String("bytes", "AQI="), kvToKeyValue("bytes", attributes["bytes"]))
+}
+
+func TestRecordToSpan(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+	cfg := &Config{Doris: &DorisConfig{SchemaMapping: schema, Location: time.UTC}}
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	record := map[string]string{
+		"service_name":        "test-service",
+		"service_instance_id": "instance-1",
+		"timestamp":           "2024-01-01 01:01:01.000001",
+		"trace_id":            "01020301000000000000000000000000",
+		"span_id":             "0102030100000000",
+		"trace_state":         "rojo=00f067aa0ba902b7",
+		"span_name":           "test-operation",
+		"span_kind":           SpanKindServer,
+		"duration":            "1000",
+		"span_attributes":     `{"http.status_code":200}`,
+		"links":               `[{"trace_id":"01020301000000000000000000000001","span_id":"0102030100000001","trace_state":"","attributes":{"retry":1}}]`,
+		"status_message":      "",
+		"status_code":         "STATUS_CODE_OK",
+		"resource_attributes": `{"host.name":"localhost"}`,
+		"scope_name":          "test-scope",
+		"scope_version":       "1.0.0",
+	}
+
+	span, err := recordToSpan(ctx, cfg, record)
+	require.NoError(t, err)
+
+	tags := model.KeyValues(span.Tags)
+	for _, kv := range []model.KeyValue{
+		model.Int64("http.status_code", 200),
+		model.String(SpanTagKeyScopeName, "test-scope"),
+		model.String(SpanTagKeyScopeVersion, "1.0.0"),
+		model.String(SpanTagKeyW3CTraceState, "rojo=00f067aa0ba902b7"),
+	} {
+		tag, ok := tags.FindByKey(kv.Key)
+		require.True(t, ok, kv.Key)
+		require.Equal(t, kv, tag)
+	}
+
+	require.Len(t, span.References, 1)
+	require.Equal(t, model.FollowsFrom, span.References[0].RefType)
+
+	require.Len(t, span.Logs, 1)
+	require.Equal(t, span.StartTime, span.Logs[0].Timestamp)
+	require.Equal(t, model.KeyValues{
+		model.String(SpanLogFieldKeyEvent, SpanLogEventLink),
+		model.String(SpanLogFieldKeyLinkTraceID, "01020301000000000000000000000001"),
+		model.String(SpanLogFieldKeyLinkSpanID, "0102030100000001"),
+		model.Int64("retry", 1),
+	}, model.KeyValues(span.Logs[0].Fields))
+
+	require.Equal(t, "test-service", span.Process.ServiceName)
+	instanceID, ok := model.KeyValues(span.Process.Tags).FindByKey(ProcessTagKeyServiceInstanceID)
+	require.True(t, ok)
+	require.Equal(t, "instance-1", instanceID.VStr)
+}
@@ -1,9 +1,14 @@
 package internal
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"os"
+	"path"
 	"regexp"
+	"slices"
 	"time"
 
 	"github.com/spf13/viper"
@@ -15,23 +20,126 @@ type Config struct {
 }
 
 type ServiceConfig struct {
-	IP                string `yaml:"ip" mapstructure:"ip"`
-	Port              int32  `yaml:"port" mapstructure:"port"`
-	LogLevel          string `yaml:"log_level" mapstructure:"log_level"`
-	TimeoutSecond     int64  `yaml:"timeout" mapstructure:"timeout"`
-	GRPCSpanBatchSize int32  `yaml:"grpc_stream_span_batch_size" mapstructure:"grpc_stream_span_batch_size"`
+	IP                  string `yaml:"ip" mapstructure:"ip"`
+	Port                int32  `yaml:"port" mapstructure:"port"`
+	LogLevel            string `yaml:"log_level" mapstructure:"log_level"`
+	TimeoutSecond       int64  `yaml:"timeout" mapstructure:"timeout"`
+	GRPCSpanBatchSize   int32  `yaml:"grpc_stream_span_batch_size" mapstructure:"grpc_stream_span_batch_size"`
+	AdminPort           int32  `yaml:"admin_port" mapstructure:"admin_port"`                         // pprof and maintenance endpoints
+	HTTPPort            int32  `yaml:"http_port" mapstructure:"http_port"`                           // HTTP query APIs, 0 disables them
+	StreamSpans         bool   `yaml:"stream_spans" mapstructure:"stream_spans"`                     // send spans while they are read instead of buffering whole traces
+	StreamBufferSize    int32  `yaml:"stream_buffer_size" mapstructure:"stream_buffer_size"`         // spans buffered before they are sent, defaults to grpc_stream_span_batch_size
+	GRPCMaxMessageBytes int32  `yaml:"grpc_max_message_bytes" mapstructure:"grpc_max_message_bytes"` // estimated maximum size of a chunk of spans
+	APIv3               bool   `yaml:"api_v3" mapstructure:"api_v3"`                                 // serve the api_v3 query service with OTLP traces on the gRPC port
+	ZipkinAPI           bool   `yaml:"zipkin_api" mapstructure:"zipkin_api"`                         // serve the Zipkin API v2 read endpoints on the HTTP port
+	SPM                 bool   `yaml:"spm" mapstructure:"spm"`                                       // serve the metrics of the Monitor tab computed from the spans
+	AnalyticsAPI        bool   `yaml:"analytics_api" mapstructure:"analytics_api"`                   // serve the span analytics service on the gRPC port
+
+	Tenancy       *TenancyConfig       `yaml:"tenancy" mapstructure:"tenancy"`
+	TLS           *TLSConfig           `yaml:"tls" mapstructure:"tls"`
+	AccessControl *AccessControlConfig `yaml:"access_control" mapstructure:"access_control"`
+	Audit         *AuditConfig         `yaml:"audit" mapstructure:"audit"`
+}
+
+// AuditConfig records every request of the storage gRPC API as a JSON line to Output,
+// separately from the operational log.
+type AuditConfig struct {
+	Enabled    bool                   `yaml:"enabled" mapstructure:"enabled"`
+	Output     string                 `yaml:"output" mapstructure:"output"`           // stdout or a file path, empty disables the file output
+	MaxSizeMB  int                    `yaml:"max_size_mb" mapstructure:"max_size_mb"` // the file is rotated when it exceeds the size
+	MaxBackups int                    `yaml:"max_backups" mapstructure:"max_backups"` // rotated files which are kept
+	StreamLoad *AuditStreamLoadConfig `yaml:"stream_load" mapstructure:"stream_load"`
+}
+
+// AuditStreamLoadConfig also loads the audit events into a table of doris.database with Stream Load.
+type AuditStreamLoadConfig struct {
+	Enabled       bool          `yaml:"enabled" mapstructure:"enabled"`
+	Endpoint      string        `yaml:"endpoint" mapstructure:"endpoint"` // HTTP address of a frontend, e.g. http://doris:8030
+	Table         string        `yaml:"table" mapstructure:"table"`
+	BatchSize     int           `yaml:"batch_size" mapstructure:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval" mapstructure:"flush_interval"`
+}
+
+// TLSConfig enables TLS on the gRPC and HTTP query servers, and requires client
+// certificates signed by ClientCAFile if it is set.
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file" mapstructure:"cert_file"`
+	KeyFile      string `yaml:"key_file" mapstructure:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file" mapstructure:"client_ca_file"`
+}
+
+const (
+	IdentitySourceHeader = "header" // value of a request header, set by a trusted proxy
+	IdentitySourceMTLS   = "mtls"   // common name of the subject of the client certificate
+	IdentitySourceJWT    = "jwt"    // claim of the bearer token forwarded by jaeger-query
+
+	AccessDeniedSpansStrip  = "strip"  // spans of disallowed services are removed from traces
+	AccessDeniedSpansRedact = "redact" // spans of disallowed services keep only their structure
+)
+
+// AccessControlConfig restricts callers to the services matched by the rules of their identity.
+type AccessControlConfig struct {
+	Enabled         bool                 `yaml:"enabled" mapstructure:"enabled"`
+	IdentitySource  string               `yaml:"identity_source" mapstructure:"identity_source"`
+	IdentityHeader  string               `yaml:"identity_header" mapstructure:"identity_header"` // defaults to x-user
+	JWTClaim        string               `yaml:"jwt_claim" mapstructure:"jwt_claim"`             // defaults to sub
+	JWTKeyFile      string               `yaml:"jwt_key_file" mapstructure:"jwt_key_file"`       // PEM public key of RS*, ES* and EdDSA tokens
+	JWTSecret       string               `yaml:"jwt_secret" mapstructure:"jwt_secret"`           // secret of HS* tokens
+	Rules           []*AccessControlRule `yaml:"rules" mapstructure:"rules"`
+	DefaultServices []string             `yaml:"default_services" mapstructure:"default_services"` // services of callers without a matching rule
+	DeniedSpans     string               `yaml:"denied_spans" mapstructure:"denied_spans"`         // strip (default) or redact
+}
+
+// AccessControlRule allows the callers matching Identities to read Services, both are
+// shell patterns, e.g. "team-a-*".
+type AccessControlRule struct {
+	Identities []string `yaml:"identities" mapstructure:"identities"`
+	Services   []string `yaml:"services" mapstructure:"services"`
+}
+
+// TenancyConfig reads the tenant of each request from the header jaeger-query sends
+// with --multi-tenancy.enabled.
+type TenancyConfig struct {
+	Enabled bool     `yaml:"enabled" mapstructure:"enabled"`
+	Header  string   `yaml:"header" mapstructure:"header"`   // defaults to x-tenant
+	Tenants []string `yaml:"tenants" mapstructure:"tenants"` // allowed tenants, empty allows all
 }
 
 type DorisConfig struct {
-	Endpoint           string              `yaml:"endpoint" mapstructure:"endpoint"`
-	Username           string              `yaml:"username" mapstructure:"username"`
-	Password           string              `yaml:"password" mapstructure:"password"`
-	Database           string              `yaml:"database" mapstructure:"database"`
-	Table              string              `yaml:"table" mapstructure:"table"`
-	SchemaMapping      *SchemaMapping      `yaml:"schema_mapping" mapstructure:"schema_mapping"`
-	GraphTable         string              `yaml:"graph_table" mapstructure:"graph_table"`
-	GraphSchemaMapping *GraphSchemaMapping `yaml:"graph_schema_mapping" mapstructure:"graph_schema_mapping"`
-	TimeZone           string              `yaml:"timezone" mapstructure:"timezone"` // doris does not handle time zones and needs to be handled manually
+	Endpoint           string                `yaml:"endpoint" mapstructure:"endpoint"`
+	Username           string                `yaml:"username" mapstructure:"username"`
+	Password           string                `yaml:"password" mapstructure:"password"`
+	Database           string                `yaml:"database" mapstructure:"database"`
+	Table              string                `yaml:"table" mapstructure:"table"`
+	SchemaProfile      string                `yaml:"schema_profile" mapstructure:"schema_profile"`           // layout of the span table, defaults to otel-doris-exporter-v1
+	SchemaProfileFile  string                `yaml:"schema_profile_file" mapstructure:"schema_profile_file"` // YAML file of the custom schema profile
+	SchemaMapping      *SchemaMapping        `yaml:"schema_mapping" mapstructure:"schema_mapping"`
+	GraphTable         string                `yaml:"graph_table" mapstructure:"graph_table"`
+	GraphSchemaMapping *GraphSchemaMapping   `yaml:"graph_schema_mapping" mapstructure:"graph_schema_mapping"`
+	TimeZone           string                `yaml:"timezone" mapstructure:"timezone"` // doris does not handle time zones and needs to be handled manually
+	FullTextSearch     *FullTextSearchConfig `yaml:"full_text_search" mapstructure:"full_text_search"`
+	FindTraces         *FindTracesConfig     `yaml:"find_traces" mapstructure:"find_traces"`
+	Services           *ServicesConfig       `yaml:"services" mapstructure:"services"`
+	MaxSpansPerTrace   int                   `yaml:"max_spans_per_trace" mapstructure:"max_spans_per_trace"` // 0 means unlimited
+	BinaryAttributes   []string              `yaml:"binary_attributes" mapstructure:"binary_attributes"`     // attribute keys, shell patterns, whose values are bytes stored as base64 strings
+	Sanitize           *SanitizeConfig       `yaml:"sanitize" mapstructure:"sanitize"`
+	Redaction          *RedactionConfig      `yaml:"redaction" mapstructure:"redaction"`
+
+	// OperationsTable, if set, serves GetServices and GetOperations instead of grouping the span table.
+	OperationsTable            string                   `yaml:"operations_table" mapstructure:"operations_table"`
+	OperationsSchemaMapping    *OperationsSchemaMapping `yaml:"operations_schema_mapping" mapstructure:"operations_schema_mapping"`
+	OperationsMaterializedView *MaterializedViewConfig  `yaml:"operations_materialized_view" mapstructure:"operations_materialized_view"`
+
+	// TraceIndexTable, if set, is used to look up the time range of a trace in GetTrace,
+	// so that doris only scans the matching partitions of the span table.
+	TraceIndexTable         string                   `yaml:"trace_index_table" mapstructure:"trace_index_table"`
+	TraceIndexSchemaMapping *TraceIndexSchemaMapping `yaml:"trace_index_schema_mapping" mapstructure:"trace_index_schema_mapping"`
+
+	// LogsTable, if set, serves the log records of traces, e.g. the otel_logs table of the otlp doris exporter.
+	// With MergeLogs, GetTrace also adds the log records of each span to its logs.
+	LogsTable         string             `yaml:"logs_table" mapstructure:"logs_table"`
+	LogsSchemaMapping *LogsSchemaMapping `yaml:"logs_schema_mapping" mapstructure:"logs_schema_mapping"`
+	MergeLogs         bool               `yaml:"merge_logs" mapstructure:"merge_logs"`
 
 	Location *time.Location `yaml:"-"`
 }
@@ -39,10 +147,10 @@ type DorisConfig struct {
 type SchemaMapping struct {
 	ServiceName        string `yaml:"service_name" mapstructure:"service_name"`               // otlp doris exporter: service_name			jaeger: Span.Process.ServiceName
 	Timestamp          string `yaml:"timestamp" mapstructure:"timestamp"`                     // otlp doris exporter: timestamp				jaeger: Span.StartTime
-	ServiceInstanceID  string `yaml:"service_instance_id" mapstructure:"service_instance_id"` // otlp doris exporter: service_instance_id	jaeger: -
+	ServiceInstanceID  string `yaml:"service_instance_id" mapstructure:"service_instance_id"` // otlp doris exporter: service_instance_id	jaeger: Span.Process.Tags["service.instance.id"]
 	TraceID            string `yaml:"trace_id" mapstructure:"trace_id"`                       // otlp doris exporter: trace_id				jaeger: Span.TraceID
 	SpanID             string `yaml:"span_id" mapstructure:"span_id"`                         // otlp doris exporter: span_id				jaeger: Span.SpanID
-	TraceState         string `yaml:"trace_state" mapstructure:"trace_state"`                 // otlp doris exporter: trace_state			jaeger: -
+	TraceState         string `yaml:"trace_state" mapstructure:"trace_state"`                 // otlp doris exporter: trace_state			jaeger: Span.Tags["w3c.tracestate"]
 	ParentSpanID       string `yaml:"parent_span_id" mapstructure:"parent_span_id"`           // otlp doris exporter: parent_span_id		jaeger: Span.References[SpanRef.RefType == ChildOf].SpanID
 	SpanName           string `yaml:"span_name" mapstructure:"span_name"`                     // otlp doris exporter: span_name				jaeger: Span.OperationName
 	SpanKind           string `yaml:"span_kind" mapstructure:"span_kind"`                     // otlp doris exporter: span_kind				jaeger: Span.Tags[SpanKind]
@@ -54,8 +162,19 @@ type SchemaMapping struct {
 	StatusMessage      string `yaml:"status_message" mapstructure:"status_message"`           // otlp doris exporter: status_message		jaeger: Span.Tags["otel.status_description"]
 	StatusCode         string `yaml:"status_code" mapstructure:"status_code"`                 // otlp doris exporter: status_code			jaager: Span.Tags["otel.status_code"]
 	ResourceAttributes string `yaml:"resource_attributes" mapstructure:"resource_attributes"` // otlp doris exporter: resource_attributes	jaeger: Span.Process.Tags
-	ScopeName          string `yaml:"scope_name" mapstructure:"scope_name"`                   // otlp doris exporter: scope_name			jaeger: -
-	ScopeVersion       string `yaml:"scope_version" mapstructure:"scope_version"`             // otlp doris exporter: scope_version			jaeger: -
+	ScopeName          string `yaml:"scope_name" mapstructure:"scope_name"`                   // otlp doris exporter: scope_name			jaeger: Span.Tags["otel.scope.name"]
+	ScopeVersion       string `yaml:"scope_version" mapstructure:"scope_version"`             // otlp doris exporter: scope_version			jaeger: Span.Tags["otel.scope.version"]
+
+	columnTypes        map[string]string // detected column types, see detectColumnTypes
+	defaultColumnTypes map[string]string // column types of the schema profile, used if they are not detected
+	spanKinds          map[string]string // stored values of the OTel span kinds, see SchemaProfile
+	otelSpanKinds      map[string]string
+	statusCodes        map[string]string // stored values of the OTel status codes, see SchemaProfile
+	otelStatusCodes    map[string]string
+	binaryAttributes   []string // patterns of the keys of bytes attributes, see DorisConfig.BinaryAttributes
+	idEncoding         string   // encoding of the ID columns, see SchemaProfile
+	eventsFormat       string
+	linksFormat        string
 }
 
 func (s *SchemaMapping) FillDefaultValues() {
@@ -152,15 +271,201 @@ func (s *GraphSchemaMapping) FillDefaultValues() {
 	}
 }
 
+type OperationsSchemaMapping struct {
+	ServiceName string `yaml:"service_name" mapstructure:"service_name"` // jaeger: Span.Process.ServiceName
+	SpanName    string `yaml:"span_name" mapstructure:"span_name"`       // jaeger: Operation.Name
+	SpanKind    string `yaml:"span_kind" mapstructure:"span_kind"`       // jaeger: Operation.SpanKind
+	LastSeen    string `yaml:"last_seen" mapstructure:"last_seen"`       // jaeger: -
+}
+
+func (s *OperationsSchemaMapping) FillDefaultValues() {
+	if s.ServiceName == "" {
+		s.ServiceName = "service_name"
+	}
+	if s.SpanName == "" {
+		s.SpanName = "span_name"
+	}
+	if s.SpanKind == "" {
+		s.SpanKind = "span_kind"
+	}
+	if s.LastSeen == "" {
+		s.LastSeen = "last_seen"
+	}
+}
+
+type TraceIndexSchemaMapping struct {
+	TraceID   string `yaml:"trace_id" mapstructure:"trace_id"`     // jaeger: Span.TraceID
+	StartTime string `yaml:"start_time" mapstructure:"start_time"` // jaeger: min(Span.StartTime)
+	EndTime   string `yaml:"end_time" mapstructure:"end_time"`     // jaeger: max(Span.StartTime)
+}
+
+func (s *TraceIndexSchemaMapping) FillDefaultValues() {
+	if s.TraceID == "" {
+		s.TraceID = "trace_id"
+	}
+	if s.StartTime == "" {
+		s.StartTime = "start_time"
+	}
+	if s.EndTime == "" {
+		s.EndTime = "end_time"
+	}
+}
+
+type LogsSchemaMapping struct {
+	ServiceName   string `yaml:"service_name" mapstructure:"service_name"`     // otlp doris exporter: service_name		jaeger: Span.Process.ServiceName
+	Timestamp     string `yaml:"timestamp" mapstructure:"timestamp"`           // otlp doris exporter: timestamp			jaeger: Log.Timestamp
+	TraceID       string `yaml:"trace_id" mapstructure:"trace_id"`             // otlp doris exporter: trace_id			jaeger: Span.TraceID
+	SpanID        string `yaml:"span_id" mapstructure:"span_id"`               // otlp doris exporter: span_id			jaeger: Span.SpanID
+	SeverityText  string `yaml:"severity_text" mapstructure:"severity_text"`   // otlp doris exporter: severity_text		jaeger: Log.Fields["level"]
+	Body          string `yaml:"body" mapstructure:"body"`                     // otlp doris exporter: body				jaeger: Log.Fields["message"]
+	LogAttributes string `yaml:"log_attributes" mapstructure:"log_attributes"` // otlp doris exporter: log_attributes	jaeger: Log.Fields
+}
+
+func (s *LogsSchemaMapping) FillDefaultValues() {
+	if s.ServiceName == "" {
+		s.ServiceName = "service_name"
+	}
+	if s.Timestamp == "" {
+		s.Timestamp = "timestamp"
+	}
+	if s.TraceID == "" {
+		s.TraceID = "trace_id"
+	}
+	if s.SpanID == "" {
+		s.SpanID = "span_id"
+	}
+	if s.SeverityText == "" {
+		s.SeverityText = "severity_text"
+	}
+	if s.Body == "" {
+		s.Body = "body"
+	}
+	if s.LogAttributes == "" {
+		s.LogAttributes = "log_attributes"
+	}
+}
+
+// MaterializedViewConfig lets the service create the operations table as a doris async
+// materialized view over the span table, doris then keeps it up to date.
+type MaterializedViewConfig struct {
+	Create          bool              `yaml:"create" mapstructure:"create"`
+	RefreshInterval time.Duration     `yaml:"refresh_interval" mapstructure:"refresh_interval"`
+	Properties      map[string]string `yaml:"properties" mapstructure:"properties"` // e.g. replication_num
+}
+
+const (
+	FindTracesModeTwoPhase = "two_phase" // query the trace ids, then the spans of these traces
+	FindTracesModeJoin     = "join"      // join the spans with the trace id query in a single statement
+)
+
+const (
+	FindTracesSortNewest     = "newest"      // latest start time first
+	FindTracesSortOldest     = "oldest"      // earliest start time first
+	FindTracesSortLongest    = "longest"     // longest span duration first
+	FindTracesSortMostSpans  = "most_spans"  // most matching spans first
+	FindTracesSortMostErrors = "most_errors" // most matching error spans first
+)
+
+type FindTracesConfig struct {
+	Mode string `yaml:"mode" mapstructure:"mode"`
+	// Sort is the default order of the found traces, it can be overridden per search with the "_sort" tag.
+	Sort string `yaml:"sort" mapstructure:"sort"`
+	// TimeSlack widens the search time range when fetching the spans of the found traces,
+	// since spans of a trace may start before or after the searched range. 0 fetches the spans
+	// regardless of their time, it is the default of the two_phase mode, and 1h of the join mode.
+	TimeSlack time.Duration `yaml:"time_slack" mapstructure:"time_slack"`
+}
+
+// FullTextSearchConfig enables grep-style trace search: a tag with the key TagKey is
+// translated into MATCH_ANY / MATCH_PHRASE predicates against Columns, which should
+// have inverted indexes in doris.
+type FullTextSearchConfig struct {
+	Enabled bool     `yaml:"enabled" mapstructure:"enabled"`
+	TagKey  string   `yaml:"tag_key" mapstructure:"tag_key"`
+	Columns []string `yaml:"columns" mapstructure:"columns"`
+}
+
+// SanitizeConfig limits the size of the spans returned to jaeger-query. Spans which have been
+// cut get the tag MarkerTagKey.
+type SanitizeConfig struct {
+	MaxValueLength int           `yaml:"max_value_length" mapstructure:"max_value_length"`   // string and binary values of tags and log fields, -1 means unlimited
+	KeyOverrides   []KeyOverride `yaml:"key_overrides" mapstructure:"key_overrides"`         // overrides max_value_length per key, e.g. 8192 for db.statement
+	MaxTagsPerSpan int           `yaml:"max_tags_per_span" mapstructure:"max_tags_per_span"` // 0 means unlimited
+	MaxLogsPerSpan int           `yaml:"max_logs_per_span" mapstructure:"max_logs_per_span"` // 0 means unlimited
+	MarkerTagKey   string        `yaml:"marker_tag_key" mapstructure:"marker_tag_key"`
+
+	keyMaxValueLength map[string]int // KeyOverrides by key
+}
+
+// KeyOverride is a list entry rather than a map key, since viper splits dotted map keys.
+type KeyOverride struct {
+	Key            string `yaml:"key" mapstructure:"key"`
+	MaxValueLength int    `yaml:"max_value_length" mapstructure:"max_value_length"`
+}
+
+// RedactionConfig removes and masks sensitive data of spans before they leave the service.
+type RedactionConfig struct {
+	Rules    []*RedactionRule `yaml:"rules" mapstructure:"rules"`
+	HashSalt string           `yaml:"hash_salt" mapstructure:"hash_salt"` // prepended to values before hashing
+}
+
+// RedactionRule applies to the span tags, log fields and process tags of the spans of Services
+// and Tenants. Keys ending with * match by prefix.
+type RedactionRule struct {
+	Services  []string         `yaml:"services" mapstructure:"services"`     // empty matches all services
+	Tenants   []string         `yaml:"tenants" mapstructure:"tenants"`       // empty matches all tenants
+	DenyKeys  []string         `yaml:"deny_keys" mapstructure:"deny_keys"`   // removed
+	AllowKeys []string         `yaml:"allow_keys" mapstructure:"allow_keys"` // if set, all other keys are removed
+	HashKeys  []string         `yaml:"hash_keys" mapstructure:"hash_keys"`   // values replaced by their salted SHA-256
+	Masks     []*RedactionMask `yaml:"masks" mapstructure:"masks"`           // applied to all string values
+}
+
+// RedactionMask replaces the matches of Pattern in string values.
+type RedactionMask struct {
+	Pattern     string `yaml:"pattern" mapstructure:"pattern"`
+	Replacement string `yaml:"replacement" mapstructure:"replacement"` // defaults to ****
+}
+
+// ServicesConfig controls the queries behind GetServices and GetOperations, which the
+// Jaeger UI calls on every page load.
+type ServicesConfig struct {
+	Lookback        time.Duration `yaml:"lookback" mapstructure:"lookback"`                 // only spans newer than now-lookback are considered, 0 means unbounded
+	CacheTTL        time.Duration `yaml:"cache_ttl" mapstructure:"cache_ttl"`               // 0 disables the cache
+	RefreshInterval time.Duration `yaml:"refresh_interval" mapstructure:"refresh_interval"` // 0 disables the background refresh
+}
+
 const (
 	defaultServiceIP       = "localhost"
 	defaultServicePort     = 17271
 	defaultServiceLogLevel = "info"
+	defaultAdminPort       = 9090
 	defaultSpanBatchSize   = 1000
+	defaultMaxMessageBytes = 4 * 1024 * 1024 // default maximum receive message size of gRPC clients
 
 	defaultDorisDatabase   = "otel"
 	defaultDorisTable      = "otel_traces"
 	defaultDorisGraphTable = "otel_traces_graph"
+
+	defaultFullTextSearchTagKey = "_text"
+
+	defaultSanitizeMaxValueLength = 1024
+	defaultSanitizeMarkerTagKey   = "jaeger_doris.truncated"
+
+	defaultRedactionMaskReplacement = "****"
+
+	defaultIdentityHeader = "x-user"
+	defaultTenancyHeader  = "x-tenant" // default of tenancy.NewManager
+
+	defaultAuditMaxSizeMB     = 100
+	defaultAuditMaxBackups    = 5
+	defaultAuditTable         = "jaeger_audit_log"
+	defaultAuditBatchSize     = 1000
+	defaultAuditFlushInterval = 10 * time.Second
+	defaultJWTClaim           = "sub"
+
+	defaultMaterializedViewRefreshInterval = 10 * time.Minute
+
+	defaultFindTracesJoinTimeSlack = time.Hour
 )
 
 func (c *Config) Init(configPath string) error {
@@ -177,10 +482,36 @@ func (c *Config) Init(configPath string) error {
 		return err
 	}
 
+	c.InitDefaults()
+	return nil
+}
+
+// InitDefaults creates the sub-configs which are not set, so that Validate can fill in their defaults.
+func (c *Config) InitDefaults() {
 	if c.Service == nil {
 		c.Service = &ServiceConfig{}
 	}
 
+	if c.Service.Tenancy == nil {
+		c.Service.Tenancy = &TenancyConfig{}
+	}
+
+	if c.Service.TLS == nil {
+		c.Service.TLS = &TLSConfig{}
+	}
+
+	if c.Service.AccessControl == nil {
+		c.Service.AccessControl = &AccessControlConfig{}
+	}
+
+	if c.Service.Audit == nil {
+		c.Service.Audit = &AuditConfig{}
+	}
+
+	if c.Service.Audit.StreamLoad == nil {
+		c.Service.Audit.StreamLoad = &AuditStreamLoadConfig{}
+	}
+
 	if c.Doris == nil {
 		c.Doris = &DorisConfig{}
 	}
@@ -193,7 +524,41 @@ func (c *Config) Init(configPath string) error {
 		c.Doris.GraphSchemaMapping = &GraphSchemaMapping{}
 	}
 
-	return nil
+	if c.Doris.FullTextSearch == nil {
+		c.Doris.FullTextSearch = &FullTextSearchConfig{}
+	}
+
+	if c.Doris.Sanitize == nil {
+		c.Doris.Sanitize = &SanitizeConfig{}
+	}
+
+	if c.Doris.Redaction == nil {
+		c.Doris.Redaction = &RedactionConfig{}
+	}
+
+	if c.Doris.FindTraces == nil {
+		c.Doris.FindTraces = &FindTracesConfig{}
+	}
+
+	if c.Doris.Services == nil {
+		c.Doris.Services = &ServicesConfig{}
+	}
+
+	if c.Doris.OperationsSchemaMapping == nil {
+		c.Doris.OperationsSchemaMapping = &OperationsSchemaMapping{}
+	}
+
+	if c.Doris.OperationsMaterializedView == nil {
+		c.Doris.OperationsMaterializedView = &MaterializedViewConfig{}
+	}
+
+	if c.Doris.TraceIndexSchemaMapping == nil {
+		c.Doris.TraceIndexSchemaMapping = &TraceIndexSchemaMapping{}
+	}
+
+	if c.Doris.LogsSchemaMapping == nil {
+		c.Doris.LogsSchemaMapping = &LogsSchemaMapping{}
+	}
 }
 
 func (c *Config) Validate() error {
@@ -206,6 +571,10 @@ func (c *Config) Validate() error {
 		c.Service.Port = defaultServicePort
 	}
 
+	if c.Service.AdminPort == 0 {
+		c.Service.AdminPort = defaultAdminPort
+	}
+
 	if c.Service.LogLevel == "" {
 		c.Service.LogLevel = defaultServiceLogLevel
 	}
@@ -214,10 +583,43 @@ func (c *Config) Validate() error {
 		c.Service.GRPCSpanBatchSize = defaultSpanBatchSize
 	}
 
+	if c.Service.GRPCMaxMessageBytes == 0 {
+		c.Service.GRPCMaxMessageBytes = defaultMaxMessageBytes
+	}
+	if c.Service.GRPCMaxMessageBytes < 0 {
+		err = errors.Join(err, errors.New("service.grpc_max_message_bytes must be greater than or equal to 0"))
+	}
+
+	if c.Service.StreamBufferSize == 0 {
+		c.Service.StreamBufferSize = c.Service.GRPCSpanBatchSize
+	}
+
+	if c.Service.ZipkinAPI && c.Service.HTTPPort == 0 {
+		err = errors.Join(err, errors.New("service.zipkin_api requires service.http_port"))
+	}
+
 	if c.Service.TimeoutSecond < 0 {
 		err = errors.Join(err, errors.New("service.timeout must be greater than or equal to 0"))
 	}
 
+	if (c.Service.TLS.CertFile == "") != (c.Service.TLS.KeyFile == "") {
+		err = errors.Join(err, errors.New("service.tls.cert_file and service.tls.key_file must be set together"))
+	}
+	if c.Service.TLS.ClientCAFile != "" && c.Service.TLS.CertFile == "" {
+		err = errors.Join(err, errors.New("service.tls.client_ca_file requires service.tls.cert_file"))
+	}
+
+	err = errors.Join(err, c.Service.AccessControl.validate(c.Service.TLS))
+	err = errors.Join(err, c.Service.Audit.validate())
+
+	if c.Doris.Services.Lookback < 0 || c.Doris.Services.CacheTTL < 0 || c.Doris.Services.RefreshInterval < 0 {
+		err = errors.Join(err, errors.New("doris.services durations must be greater than or equal to 0"))
+	}
+
+	if c.Doris.MaxSpansPerTrace < 0 {
+		err = errors.Join(err, errors.New("doris.max_spans_per_trace must be greater than or equal to 0"))
+	}
+
 	if c.Doris.Endpoint == "" {
 		err = errors.Join(err, errors.New("doris.endpoint must be specified"))
 	}
@@ -233,13 +635,107 @@ func (c *Config) Validate() error {
 	if c.Doris.Table == "" {
 		c.Doris.Table = defaultDorisTable
 	}
-	c.Doris.SchemaMapping.FillDefaultValues()
+	if c.Doris.SchemaProfile == "" {
+		c.Doris.SchemaProfile = SchemaProfileOTelDorisExporterV1
+	}
+	profile, profileErr := loadSchemaProfile(c.Doris.SchemaProfile, c.Doris.SchemaProfileFile)
+	if profileErr != nil {
+		err = errors.Join(err, profileErr)
+		profile = builtinSchemaProfiles[SchemaProfileOTelDorisExporterV1]
+	}
+	c.Doris.SchemaMapping.applySchemaProfile(profile)
+	for _, pattern := range c.Doris.BinaryAttributes {
+		if _, errM := path.Match(pattern, ""); errM != nil {
+			err = errors.Join(err, fmt.Errorf("doris.binary_attributes has an invalid pattern %q", pattern))
+		}
+	}
+	c.Doris.SchemaMapping.binaryAttributes = c.Doris.BinaryAttributes
 
 	if c.Doris.GraphTable == "" {
 		c.Doris.GraphTable = defaultDorisGraphTable
 	}
 	c.Doris.GraphSchemaMapping.FillDefaultValues()
 
+	c.Doris.OperationsSchemaMapping.FillDefaultValues()
+	if c.Doris.OperationsMaterializedView.RefreshInterval == 0 {
+		c.Doris.OperationsMaterializedView.RefreshInterval = defaultMaterializedViewRefreshInterval
+	}
+	if c.Doris.OperationsMaterializedView.Create && c.Doris.OperationsTable == "" {
+		err = errors.Join(err, errors.New("doris.operations_table must be specified to create the materialized view"))
+	}
+
+	c.Doris.TraceIndexSchemaMapping.FillDefaultValues()
+
+	c.Doris.LogsSchemaMapping.FillDefaultValues()
+	if c.Doris.MergeLogs && c.Doris.LogsTable == "" {
+		err = errors.Join(err, errors.New("doris.logs_table must be specified to merge logs"))
+	}
+
+	switch c.Doris.FindTraces.Mode {
+	case "":
+		c.Doris.FindTraces.Mode = FindTracesModeTwoPhase
+	case FindTracesModeTwoPhase, FindTracesModeJoin:
+	default:
+		err = errors.Join(err, fmt.Errorf("doris.find_traces.mode must be one of %s, %s", FindTracesModeTwoPhase, FindTracesModeJoin))
+	}
+	switch c.Doris.FindTraces.Sort {
+	case "":
+		c.Doris.FindTraces.Sort = FindTracesSortNewest
+	case FindTracesSortNewest, FindTracesSortOldest, FindTracesSortLongest, FindTracesSortMostSpans, FindTracesSortMostErrors:
+	default:
+		err = errors.Join(err, fmt.Errorf("doris.find_traces.sort must be one of %s, %s, %s, %s, %s",
+			FindTracesSortNewest, FindTracesSortOldest, FindTracesSortLongest, FindTracesSortMostSpans, FindTracesSortMostErrors))
+	}
+	// the join mode needs a bound to prune the partitions of the joined span table
+	if c.Doris.FindTraces.TimeSlack == 0 && c.Doris.FindTraces.Mode == FindTracesModeJoin {
+		c.Doris.FindTraces.TimeSlack = defaultFindTracesJoinTimeSlack
+	}
+	if c.Doris.FindTraces.TimeSlack < 0 {
+		err = errors.Join(err, errors.New("doris.find_traces.time_slack must be greater than or equal to 0"))
+	}
+
+	if c.Doris.Sanitize.MaxValueLength == 0 {
+		c.Doris.Sanitize.MaxValueLength = defaultSanitizeMaxValueLength
+	}
+	if c.Doris.Sanitize.MaxValueLength < -1 {
+		err = errors.Join(err, errors.New("doris.sanitize.max_value_length must be greater than 0, or -1 for unlimited"))
+	}
+	c.Doris.Sanitize.keyMaxValueLength = make(map[string]int, len(c.Doris.Sanitize.KeyOverrides))
+	for _, override := range c.Doris.Sanitize.KeyOverrides {
+		if override.MaxValueLength == 0 || override.MaxValueLength < -1 {
+			err = errors.Join(err, fmt.Errorf("doris.sanitize.key_overrides max_value_length of %s must be greater than 0, or -1 for unlimited", override.Key))
+		}
+		c.Doris.Sanitize.keyMaxValueLength[override.Key] = override.MaxValueLength
+	}
+	if c.Doris.Sanitize.MaxTagsPerSpan < 0 {
+		err = errors.Join(err, errors.New("doris.sanitize.max_tags_per_span must be greater than or equal to 0"))
+	}
+	if c.Doris.Sanitize.MaxLogsPerSpan < 0 {
+		err = errors.Join(err, errors.New("doris.sanitize.max_logs_per_span must be greater than or equal to 0"))
+	}
+	if c.Doris.Sanitize.MarkerTagKey == "" {
+		c.Doris.Sanitize.MarkerTagKey = defaultSanitizeMarkerTagKey
+	}
+
+	for i, rule := range c.Doris.Redaction.Rules {
+		for _, mask := range rule.Masks {
+			_, errC := regexp.Compile(mask.Pattern)
+			if errC != nil {
+				err = errors.Join(err, fmt.Errorf("doris.redaction.rules[%d] has an invalid mask pattern: %w", i, errC))
+			}
+			if mask.Replacement == "" {
+				mask.Replacement = defaultRedactionMaskReplacement
+			}
+		}
+	}
+
+	if c.Doris.FullTextSearch.TagKey == "" {
+		c.Doris.FullTextSearch.TagKey = defaultFullTextSearchTagKey
+	}
+	if len(c.Doris.FullTextSearch.Columns) == 0 {
+		c.Doris.FullTextSearch.Columns = []string{c.Doris.SchemaMapping.SpanAttributes, c.Doris.SchemaMapping.StatusMessage}
+	}
+
 	if c.Doris.TimeZone == "" {
 		c.Doris.Location = time.Local
 	} else {
@@ -259,14 +755,158 @@ func (c *Config) Validate() error {
 	if !re.MatchString(c.Doris.Table) {
 		err = errors.Join(err, errors.New("doris.table_name must be alphanumeric and underscore"))
 	}
+	if c.Doris.OperationsTable != "" && !re.MatchString(c.Doris.OperationsTable) {
+		err = errors.Join(err, errors.New("doris.operations_table must be alphanumeric and underscore"))
+	}
+	if c.Doris.TraceIndexTable != "" && !re.MatchString(c.Doris.TraceIndexTable) {
+		err = errors.Join(err, errors.New("doris.trace_index_table must be alphanumeric and underscore"))
+	}
+	if c.Doris.LogsTable != "" && !re.MatchString(c.Doris.LogsTable) {
+		err = errors.Join(err, errors.New("doris.logs_table must be alphanumeric and underscore"))
+	}
+	for _, column := range []string{
+		c.Doris.OperationsSchemaMapping.ServiceName,
+		c.Doris.OperationsSchemaMapping.SpanName,
+		c.Doris.OperationsSchemaMapping.SpanKind,
+		c.Doris.OperationsSchemaMapping.LastSeen,
+	} {
+		if !re.MatchString(column) {
+			err = errors.Join(err, errors.New("doris.operations_schema_mapping columns must be alphanumeric and underscore"))
+			break
+		}
+	}
+	for _, column := range c.Doris.FullTextSearch.Columns {
+		if !re.MatchString(column) {
+			err = errors.Join(err, errors.New("doris.full_text_search.columns must be alphanumeric and underscore"))
+			break
+		}
+	}
 
 	return err
 }
 
+func (c *AccessControlConfig) validate(tlsConfig *TLSConfig) error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var err error
+	switch c.IdentitySource {
+	case IdentitySourceHeader:
+		if c.IdentityHeader == "" {
+			c.IdentityHeader = defaultIdentityHeader
+		}
+	case IdentitySourceMTLS:
+		if tlsConfig.ClientCAFile == "" {
+			err = errors.Join(err, errors.New("service.access_control.identity_source mtls requires service.tls.client_ca_file"))
+		}
+	case IdentitySourceJWT:
+		if c.JWTClaim == "" {
+			c.JWTClaim = defaultJWTClaim
+		}
+		if (c.JWTKeyFile == "") == (c.JWTSecret == "") {
+			err = errors.Join(err, errors.New("service.access_control requires either jwt_key_file or jwt_secret"))
+		}
+	default:
+		err = errors.Join(err, fmt.Errorf("service.access_control.identity_source must be one of %s, %s, %s",
+			IdentitySourceHeader, IdentitySourceMTLS, IdentitySourceJWT))
+	}
+
+	switch c.DeniedSpans {
+	case "":
+		c.DeniedSpans = AccessDeniedSpansStrip
+	case AccessDeniedSpansStrip, AccessDeniedSpansRedact:
+	default:
+		err = errors.Join(err, fmt.Errorf("service.access_control.denied_spans must be one of %s, %s",
+			AccessDeniedSpansStrip, AccessDeniedSpansRedact))
+	}
+
+	patterns := slices.Clone(c.DefaultServices)
+	for _, rule := range c.Rules {
+		patterns = append(patterns, rule.Identities...)
+		patterns = append(patterns, rule.Services...)
+	}
+	for _, pattern := range patterns {
+		if _, errM := path.Match(pattern, ""); errM != nil {
+			err = errors.Join(err, fmt.Errorf("service.access_control has an invalid pattern %q", pattern))
+		}
+	}
+
+	return err
+}
+
+func (c *AuditConfig) validate() error {
+	if c.MaxSizeMB == 0 {
+		c.MaxSizeMB = defaultAuditMaxSizeMB
+	}
+	if c.MaxBackups == 0 {
+		c.MaxBackups = defaultAuditMaxBackups
+	}
+	if c.StreamLoad.Table == "" {
+		c.StreamLoad.Table = defaultAuditTable
+	}
+	if c.StreamLoad.BatchSize == 0 {
+		c.StreamLoad.BatchSize = defaultAuditBatchSize
+	}
+	if c.StreamLoad.FlushInterval == 0 {
+		c.StreamLoad.FlushInterval = defaultAuditFlushInterval
+	}
+
+	var err error
+	if c.MaxSizeMB < 0 || c.MaxBackups < 0 || c.StreamLoad.BatchSize < 0 || c.StreamLoad.FlushInterval < 0 {
+		err = errors.Join(err, errors.New("service.audit sizes and intervals must be greater than or equal to 0"))
+	}
+	if c.Enabled && c.StreamLoad.Enabled && c.StreamLoad.Endpoint == "" {
+		err = errors.Join(err, errors.New("service.audit.stream_load.endpoint is required"))
+	}
+	if !regexp.MustCompile(`^[a-zA-Z0-9_]+$`).MatchString(c.StreamLoad.Table) {
+		err = errors.Join(err, errors.New("invalid service.audit.stream_load.table"))
+	}
+	return err
+}
+
 func (c *ServiceConfig) Address() string {
 	return fmt.Sprintf("%s:%d", c.IP, c.Port)
 }
 
+func (c *ServiceConfig) HTTPAddress() string {
+	return fmt.Sprintf("%s:%d", c.IP, c.HTTPPort)
+}
+
+func (c *ServiceConfig) AdminAddress() string {
+	return fmt.Sprintf("%s:%d", c.IP, c.AdminPort)
+}
+
+// ServerTLSConfig loads the certificates, it returns nil if TLS is disabled.
+func (c *TLSConfig) ServerTLSConfig() (*tls.Config, error) {
+	if c.CertFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.ClientCAFile != "" {
+		b, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in %s", c.ClientCAFile)
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
 func (c *DorisConfig) DSN() string {
 	return fmt.Sprintf("%s:%s@tcp(%s)/%s", c.Username, c.Password, c.Endpoint, c.Database)
 }
@@ -278,3 +918,15 @@ func (c *DorisConfig) TableFullName() string {
 func (c *DorisConfig) GraphTableFullName() string {
 	return fmt.Sprintf("%s.%s", c.Database, c.GraphTable)
 }
+
+func (c *DorisConfig) TraceIndexTableFullName() string {
+	return fmt.Sprintf("%s.%s", c.Database, c.TraceIndexTable)
+}
+
+func (c *DorisConfig) OperationsTableFullName() string {
+	return fmt.Sprintf("%s.%s", c.Database, c.OperationsTable)
+}
+
+func (c *DorisConfig) LogsTableFullName() string {
+	return fmt.Sprintf("%s.%s", c.Database, c.LogsTable)
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -27,4 +28,64 @@ func TestConfig_InitAndValidate(t *testing.T) {
 
 	require.Equal(t, "trace_time", cfg.Doris.SchemaMapping.Timestamp)
 	require.Equal(t, "trace_graph_time", cfg.Doris.GraphSchemaMapping.Timestamp)
+
+	require.False(t, cfg.Doris.FullTextSearch.Enabled)
+	require.Equal(t, "_text", cfg.Doris.FullTextSearch.TagKey)
+	require.Equal(t, []string{"span_attributes", "status_message"}, cfg.Doris.FullTextSearch.Columns)
+
+	require.Equal(t, 1024, cfg.Doris.Sanitize.MaxValueLength)
+	require.Equal(t, "jaeger_doris.truncated", cfg.Doris.Sanitize.MarkerTagKey)
+	require.Equal(t, map[string]int{"db.statement": 8192}, cfg.Doris.Sanitize.keyMaxValueLength)
+}
+
+func TestConfig_ValidateOperationsSchemaMapping(t *testing.T) {
+	cfg := &Config{}
+	require.NoError(t, cfg.Init(configPath))
+
+	cfg.Doris.OperationsSchemaMapping.SpanName = "span_name; DROP TABLE traces"
+	require.ErrorContains(t, cfg.Validate(), "doris.operations_schema_mapping columns must be alphanumeric and underscore")
+}
+
+func TestConfig_ValidateLogsTable(t *testing.T) {
+	cfg := &Config{}
+	require.NoError(t, cfg.Init(configPath))
+	cfg.Doris.LogsTable = "otel_logs"
+	require.NoError(t, cfg.Validate())
+
+	cfg = &Config{}
+	require.NoError(t, cfg.Init(configPath))
+	cfg.Doris.LogsTable = "logs; DROP TABLE traces"
+	require.ErrorContains(t, cfg.Validate(), "doris.logs_table must be alphanumeric and underscore")
+}
+
+func TestConfig_ValidateFindTracesTimeSlack(t *testing.T) {
+	cfg := &Config{}
+	require.NoError(t, cfg.Init(configPath))
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, FindTracesModeTwoPhase, cfg.Doris.FindTraces.Mode)
+	require.Zero(t, cfg.Doris.FindTraces.TimeSlack)
+
+	cfg = &Config{}
+	require.NoError(t, cfg.Init(configPath))
+	cfg.Doris.FindTraces.Mode = FindTracesModeJoin
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, time.Hour, cfg.Doris.FindTraces.TimeSlack)
+
+	cfg = &Config{}
+	require.NoError(t, cfg.Init(configPath))
+	cfg.Doris.FindTraces.TimeSlack = -time.Minute
+	require.ErrorContains(t, cfg.Validate(), "doris.find_traces.time_slack must be greater than or equal to 0")
+}
+
+func TestConfig_ValidateBinaryAttributes(t *testing.T) {
+	cfg := &Config{}
+	require.NoError(t, cfg.Init(configPath))
+	cfg.Doris.BinaryAttributes = []string{"payload", "grpc.*"}
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, []string{"payload", "grpc.*"}, cfg.Doris.SchemaMapping.binaryAttributes)
+
+	cfg = &Config{}
+	require.NoError(t, cfg.Init(configPath))
+	cfg.Doris.BinaryAttributes = []string{"["}
+	require.ErrorContains(t, cfg.Validate(), `doris.binary_attributes has an invalid pattern "["`)
 }
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// traceCursor points at the last trace of a page of FindTraceIDs results,
+// the next page starts strictly after it.
+type traceCursor struct {
+	StartTime time.Time // minimum timestamp of the matching spans of the trace
+	TraceID   string
+}
+
+// String returns the opaque representation of the cursor passed by clients.
+func (c *traceCursor) String() string {
+	s := fmt.Sprintf("%d:%s", c.StartTime.UnixMicro(), c.TraceID)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func parseTraceCursor(s string) (*traceCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	micros, traceIDString, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+
+	startTime, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	// the trace id is embedded in SQL, make sure that it is a well-formed one
+	traceID, err := model.TraceIDFromString(traceIDString)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &traceCursor{
+		StartTime: time.UnixMicro(startTime),
+		TraceID:   traceIDToString(traceID),
+	}, nil
+}
@@ -3,10 +3,14 @@ package internal
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstore_v2 "github.com/jaegertracing/jaeger/storage_v2/spanstore"
 	"go.uber.org/zap"
 
 	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/plugin/storage/grpc/shared"
@@ -17,9 +21,14 @@ type DorisStorage struct {
 
 	db               *sql.DB
 	cfg              *Config
+	dorisReader      *dorisReader
 	reader           spanstore.Reader
 	writer           spanstore.Writer
 	dependencyReader dependencystore.Reader
+
+	cache       *cachedReader
+	access      *accessControlledReader
+	stopRefresh context.CancelFunc
 }
 
 func NewDorisStorage(ctx context.Context, cfg *Config) (*DorisStorage, error) {
@@ -37,10 +46,35 @@ func NewDorisStorage(ctx context.Context, cfg *Config) (*DorisStorage, error) {
 		return nil, err
 	}
 
+	err = detectColumnTypes(ctx, db, cfg)
+	if err != nil {
+		logger.Warn("failed to detect column types, attributes are accessed as maps", zap.Error(err))
+	}
+
+	if cfg.Doris.FullTextSearch.Enabled {
+		err = checkInvertedIndexes(ctx, db, cfg)
+		if err != nil {
+			logger.Warn("failed to check inverted indexes", zap.Error(err))
+		}
+	}
+
+	if cfg.Doris.OperationsMaterializedView.Create {
+		err = createOperationsMaterializedView(ctx, db, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create operations materialized view: %w", err)
+		}
+	}
+
+	redactor, err := newRedactor(cfg.Doris.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
 	reader := &dorisReader{
-		logger: logger.With(zap.String("doris", "reader")),
-		db:     db,
-		cfg:    cfg,
+		logger:   logger.With(zap.String("doris", "reader")),
+		db:       db,
+		cfg:      cfg,
+		redactor: redactor,
 	}
 
 	writer := &dorisWriterNoop{
@@ -52,14 +86,34 @@ func NewDorisStorage(ctx context.Context, cfg *Config) (*DorisStorage, error) {
 		dr:     reader,
 	}
 
-	return &DorisStorage{
+	ds := &DorisStorage{
 		logger:           logger,
 		db:               db,
 		cfg:              cfg,
+		dorisReader:      reader,
 		reader:           reader,
 		writer:           writer,
 		dependencyReader: dependencyReader,
-	}, nil
+		stopRefresh:      func() {},
+	}
+
+	if cfg.Doris.Services.CacheTTL > 0 {
+		ds.cache = newCachedReader(logger.With(zap.String("doris", "cache")), reader, cfg.Doris.Services.CacheTTL)
+		ds.reader = ds.cache
+
+		if cfg.Doris.Services.RefreshInterval > 0 {
+			var refreshCtx context.Context
+			refreshCtx, ds.stopRefresh = context.WithCancel(LoggerWithContext(context.Background(), logger))
+			go ds.cache.runRefresh(refreshCtx, cfg.Doris.Services.RefreshInterval)
+		}
+	}
+
+	// the cache is shared by all callers, the permissions are applied on top of it
+	ds.access = newAccessControlledReader(ds.reader, reader, dependencyReader, cfg.Service.AccessControl.DeniedSpans)
+	ds.reader = ds.access
+	ds.dependencyReader = ds.access
+
+	return ds, nil
 }
 
 var (
@@ -74,10 +128,81 @@ func (ds *DorisStorage) SpanWriter() spanstore.Writer {
 	return ds.writer
 }
 
+// MetricsReader computes the metrics of the Monitor tab of the Jaeger UI from the span table.
+func (ds *DorisStorage) MetricsReader() metricsstore.Reader {
+	return &dorisMetricsReader{
+		logger: ds.logger.With(zap.String("doris", "metrics-reader")),
+		dr:     ds.dorisReader,
+	}
+}
+
+// TraceReader returns the OTLP reader of the jaeger v2 storage API.
+func (ds *DorisStorage) TraceReader() spanstore_v2.Reader {
+	return &traceReader{reader: ds.access}
+}
+
+// TraceWriter returns the OTLP writer of the jaeger v2 storage API, which rejects writes like SpanWriter.
+func (ds *DorisStorage) TraceWriter() spanstore_v2.Writer {
+	return ds.writer.(spanstore_v2.Writer)
+}
+
 func (ds *DorisStorage) DependencyReader() dependencystore.Reader {
 	return ds.dependencyReader
 }
 
+// RefreshCache reloads the cached services and operations, it is a no-op if the cache is disabled.
+func (ds *DorisStorage) RefreshCache(ctx context.Context) error {
+	if ds.cache == nil {
+		return nil
+	}
+	return ds.cache.Refresh(ctx)
+}
+
 func (ds *DorisStorage) Close() error {
+	ds.stopRefresh()
 	return ds.db.Close()
 }
+
+func createOperationsMaterializedView(ctx context.Context, db *sql.DB, cfg *Config) error {
+	query := queryCreateOperationsMaterializedView(
+		cfg.Doris.SchemaMapping,
+		cfg.Doris.OperationsSchemaMapping,
+		cfg.Doris.OperationsTableFullName(),
+		cfg.Doris.TableFullName(),
+		cfg.Doris.OperationsMaterializedView,
+	)
+
+	LoggerFromContext(ctx).Debug("creating operations materialized view", zap.String("query", query))
+	_, err := db.ExecContext(ctx, query)
+	return err
+}
+
+// checkInvertedIndexes warns about full-text search columns without an inverted index,
+// since MATCH_* predicates on them are either rejected or very slow.
+func checkInvertedIndexes(ctx context.Context, db *sql.DB, cfg *Config) error {
+	logger := LoggerFromContext(ctx)
+
+	indexed := make(map[string]bool)
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		if strings.EqualFold(record["Index_type"], "INVERTED") {
+			indexed[record["Column_name"]] = true
+		}
+		return nil
+	}
+
+	err := executeQuery(ctx, db, cfg, queryShowIndex(cfg.Doris.TableFullName()), f)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range cfg.Doris.FullTextSearch.Columns {
+		if !indexed[column] {
+			logger.Warn("full-text search column has no inverted index",
+				zap.String("table", cfg.Doris.TableFullName()),
+				zap.String("column", column),
+			)
+		}
+	}
+
+	return nil
+}
@@ -8,31 +8,61 @@ import (
 	"time"
 
 	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
 	"github.com/jaegertracing/jaeger/storage/dependencystore"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/plugin/storage/grpc/shared"
 )
 
 var (
 	_ spanstore.Reader       = (*dorisReader)(nil)
 	_ dependencystore.Reader = (*dorisDependencyReader)(nil)
+
+	_ shared.TraceParametersReader = (*dorisReader)(nil)
+	_ shared.StreamingSpanReader   = (*dorisReader)(nil)
 )
 
 type dorisReader struct {
-	logger *zap.Logger
-	db     *sql.DB
-	cfg    *Config
+	logger   *zap.Logger
+	db       *sql.DB
+	cfg      *Config
+	redactor *redactor
+}
+
+// spanFromRecord converts a record to a span, adds its log records from logs, and redacts it before
+// it is returned to users.
+func (dr *dorisReader) spanFromRecord(ctx context.Context, cfg *Config, record map[string]string, logs map[model.SpanID][]model.Log) (*model.Span, error) {
+	span, err := recordToSpan(ctx, cfg, record)
+	if err != nil {
+		return nil, err
+	}
+	mergeLogs(span, logs)
+	return dr.redactor.Redact(ctx, span), nil
 }
 
 func (dr *dorisReader) GetTrace(ctx context.Context, traceID model.TraceID) (*model.Trace, error) {
-	schema := dr.cfg.Doris.SchemaMapping
+	return dr.GetTraceWithParameters(ctx, shared.GetTraceParameters{TraceID: traceID})
+}
 
+// GetTraceWithParameters limits the scanned time range of the span table to the hints
+// of the query, or to the range found in the trace index table if there are no hints.
+func (dr *dorisReader) GetTraceWithParameters(ctx context.Context, query shared.GetTraceParameters) (*model.Trace, error) {
 	trace := &model.Trace{
 		Spans: make([]*model.Span, 0),
 	}
 
+	logs, err := dr.getSpanLogs(ctx, &query)
+	if err != nil {
+		// the spans are still useful without their log records
+		dr.logger.Warn("Failed to read the log records of the trace", zap.Error(err))
+	}
+
 	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
-		span, err := recordToSpan(ctx, cfg, record)
+		span, err := dr.spanFromRecord(ctx, cfg, record, logs)
 		if err != nil {
 			dr.logger.Warn("Failed to convert record to span", zap.Error(err))
 		} else {
@@ -42,7 +72,7 @@ func (dr *dorisReader) GetTrace(ctx context.Context, traceID model.TraceID) (*mo
 		return nil
 	}
 
-	err := executeQuery(ctx, dr.db, dr.cfg, queryGetTrace(schema, dr.cfg.Doris.TableFullName(), traceIDToString(traceID)), f)
+	err = executeQuery(ctx, dr.db, dr.cfg, dr.getTraceQuery(ctx, query), f)
 	if err != nil {
 		return nil, err
 	}
@@ -51,23 +81,123 @@ func (dr *dorisReader) GetTrace(ctx context.Context, traceID model.TraceID) (*mo
 		return nil, spanstore.ErrTraceNotFound
 	}
 
-	return sanitizeTrace(trace), nil
+	trace.Spans = truncateSpans(trace.Spans, dr.cfg.Doris.MaxSpansPerTrace)
+
+	return sanitizeTrace(dr.cfg.Doris.Sanitize, trace), nil
+}
+
+// StreamTrace passes the spans of the trace to fn in batches while they are read.
+func (dr *dorisReader) StreamTrace(ctx context.Context, query shared.GetTraceParameters, fn func(spans []*model.Span) error) error {
+	stream := newSpanStream(int(dr.cfg.Service.StreamBufferSize), dr.cfg.Doris.MaxSpansPerTrace, dr.cfg.Doris.Sanitize, fn)
+
+	logs, err := dr.getSpanLogs(ctx, &query)
+	if err != nil {
+		// the spans are still useful without their log records
+		dr.logger.Warn("Failed to read the log records of the trace", zap.Error(err))
+	}
+
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		span, err := dr.spanFromRecord(ctx, cfg, record, logs)
+		if err != nil {
+			dr.logger.Warn("Failed to convert record to span", zap.Error(err))
+			return nil
+		}
+		return stream.Add(span)
+	}
+
+	err = executeQuery(ctx, dr.db, dr.cfg, dr.getTraceQuery(ctx, query), f)
+	if err != nil {
+		return err
+	}
+
+	count, err := stream.Close()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return spanstore.ErrTraceNotFound
+	}
+
+	return nil
+}
+
+func (dr *dorisReader) getTraceQuery(ctx context.Context, query shared.GetTraceParameters) string {
+	schema := dr.cfg.Doris.SchemaMapping
+	traceID := traceIDToString(query.TraceID)
+
+	startTime, endTime := query.StartTime, query.EndTime
+	if startTime.IsZero() && endTime.IsZero() && dr.cfg.Doris.TraceIndexTable != "" {
+		var err error
+		startTime, endTime, err = dr.getTraceTimeRange(ctx, traceID)
+		if err != nil {
+			dr.logger.Warn("Failed to look up the time range of the trace", zap.Error(err))
+		}
+	}
+
+	// one more span than the maximum tells whether the trace has been truncated
+	limit := 0
+	if dr.cfg.Doris.MaxSpansPerTrace > 0 {
+		limit = dr.cfg.Doris.MaxSpansPerTrace + 1
+	}
+
+	return queryGetTrace(schema, dr.cfg.Doris.TableFullName(), traceID, startTime, endTime, dr.cfg.Doris.Location, limit)
+}
+
+// getTraceTimeRange returns zero times if the trace is not in the trace index table.
+func (dr *dorisReader) getTraceTimeRange(ctx context.Context, traceID string) (time.Time, time.Time, error) {
+	indexSchema := dr.cfg.Doris.TraceIndexSchemaMapping
+
+	var startTime, endTime time.Time
+
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		startTimeString, ok := record[indexSchema.StartTime]
+		if !ok {
+			return nil
+		}
+		endTimeString, ok := record[indexSchema.EndTime]
+		if !ok {
+			return nil
+		}
+
+		var err error
+		startTime, err = time.ParseInLocation(timeFormat, startTimeString, cfg.Doris.Location)
+		if err != nil {
+			return err
+		}
+		endTime, err = time.ParseInLocation(timeFormat, endTimeString, cfg.Doris.Location)
+		return err
+	}
+
+	err := executeQuery(ctx, dr.db, dr.cfg, queryGetTraceTimeRange(indexSchema, dr.cfg.Doris.TraceIndexTableFullName(), traceID), f)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return startTime, endTime, nil
 }
 
 func (dr *dorisReader) GetServices(ctx context.Context) ([]string, error) {
 	schema := dr.cfg.Doris.SchemaMapping
+	opsSchema := dr.cfg.Doris.OperationsSchemaMapping
+
+	serviceNameColumn := schema.ServiceName
+	query := queryGetServices(schema, dr.cfg.Doris.TableFullName(), time.Now(), dr.cfg.Doris.Services.Lookback, dr.cfg.Doris.Location)
+	if dr.cfg.Doris.OperationsTable != "" {
+		serviceNameColumn = opsSchema.ServiceName
+		query = queryGetServicesFromOperationsTable(opsSchema, dr.cfg.Doris.OperationsTableFullName(), time.Now(), dr.cfg.Doris.Services.Lookback, dr.cfg.Doris.Location)
+	}
 
 	services := make([]string, 0)
 
 	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
-		serviceName := record[schema.ServiceName]
+		serviceName := record[serviceNameColumn]
 		if serviceName != "" {
 			services = append(services, serviceName)
 		}
 		return nil
 	}
 
-	err := executeQuery(ctx, dr.db, dr.cfg, queryGetServices(schema, dr.cfg.Doris.TableFullName()), f)
+	err := executeQuery(ctx, dr.db, dr.cfg, query, f)
 	if err != nil {
 		return nil, err
 	}
@@ -77,22 +207,30 @@ func (dr *dorisReader) GetServices(ctx context.Context) ([]string, error) {
 
 func (dr *dorisReader) GetOperations(ctx context.Context, query spanstore.OperationQueryParameters) ([]spanstore.Operation, error) {
 	schema := dr.cfg.Doris.SchemaMapping
+	opsSchema := dr.cfg.Doris.OperationsSchemaMapping
+
+	spanNameColumn, spanKindColumn := schema.SpanName, schema.SpanKind
+	sqlQuery := queryGetOperations(schema, dr.cfg.Doris.TableFullName(), query, time.Now(), dr.cfg.Doris.Services.Lookback, dr.cfg.Doris.Location)
+	if dr.cfg.Doris.OperationsTable != "" {
+		spanNameColumn, spanKindColumn = opsSchema.SpanName, opsSchema.SpanKind
+		sqlQuery = queryGetOperationsFromOperationsTable(schema, opsSchema, dr.cfg.Doris.OperationsTableFullName(), query, time.Now(), dr.cfg.Doris.Services.Lookback, dr.cfg.Doris.Location)
+	}
 
 	operations := make([]spanstore.Operation, 0)
 
 	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
-		operationName := record[schema.SpanName]
-		spanKind := record[schema.SpanKind]
+		operationName := record[spanNameColumn]
+		spanKind := record[spanKindColumn]
 		if operationName != "" {
 			operations = append(operations, spanstore.Operation{
 				Name:     operationName,
-				SpanKind: otelToJeagerSpanKind[spanKind],
+				SpanKind: otelToJeagerSpanKind[schema.otelSpanKind(spanKind)],
 			})
 		}
 		return nil
 	}
 
-	err := executeQuery(ctx, dr.db, dr.cfg, queryGetOperations(schema, dr.cfg.Doris.TableFullName(), query), f)
+	err := executeQuery(ctx, dr.db, dr.cfg, sqlQuery, f)
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +239,72 @@ func (dr *dorisReader) GetOperations(ctx context.Context, query spanstore.Operat
 }
 
 func (dr *dorisReader) FindTraces(ctx context.Context, query *spanstore.TraceQueryParameters) ([]*model.Trace, error) {
+	spansQuery, traceIDs, err := dr.findTracesQuery(ctx, query, false)
+	if err != nil {
+		return nil, err
+	}
+	if spansQuery == "" {
+		return make([]*model.Trace, 0), nil
+	}
+
+	return dr.findTraces(ctx, spansQuery, traceIDs)
+}
+
+// StreamTraces passes the spans of the matching traces to fn in batches while they are read,
+// the spans of each trace are passed contiguously.
+func (dr *dorisReader) StreamTraces(ctx context.Context, query *spanstore.TraceQueryParameters, fn func(spans []*model.Span) error) error {
+	spansQuery, _, err := dr.findTracesQuery(ctx, query, true)
+	if err != nil || spansQuery == "" {
+		return err
+	}
+
+	stream := newSpanStream(int(dr.cfg.Service.StreamBufferSize), dr.cfg.Doris.MaxSpansPerTrace, dr.cfg.Doris.Sanitize, fn)
+
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		span, err := dr.spanFromRecord(ctx, cfg, record, nil)
+		if err != nil {
+			dr.logger.Warn("Failed to convert record to span", zap.Error(err))
+			return nil
+		}
+		return stream.Add(span)
+	}
+
+	err = executeQuery(ctx, dr.db, dr.cfg, spansQuery, f)
+	if err != nil {
+		return err
+	}
+
+	_, err = stream.Close()
+	return err
+}
+
+// findTracesQuery returns the query of the spans of the matching traces, and the IDs of the
+// traces in the two-phase mode. The query is empty if no traces match. If ordered is set, the
+// spans are ordered by trace like the trace IDs.
+func (dr *dorisReader) findTracesQuery(ctx context.Context, query *spanstore.TraceQueryParameters, ordered bool) (string, []string, error) {
 	schema := dr.cfg.Doris.SchemaMapping
+	sort := dr.findTracesSort(query)
+	cursor, err := findTracesCursor(query, sort)
+	if err != nil {
+		return "", nil, err
+	}
+	traceIDsQuery := queryFindTraceIDs(schema, dr.cfg.Doris.TableFullName(), query, dr.cfg.Doris.Location, dr.cfg.Doris.FullTextSearch, sort, cursor, dr.redactor.attributeGuard(tenancy.GetTenant(ctx)))
+
+	// spans of a matching trace may lie outside of the searched time range, they are fetched
+	// regardless of their time without a slack
+	var startTimeMin, startTimeMax time.Time
+	if timeSlack := dr.cfg.Doris.FindTraces.TimeSlack; timeSlack > 0 {
+		if !query.StartTimeMin.IsZero() {
+			startTimeMin = query.StartTimeMin.Add(-timeSlack)
+		}
+		if !query.StartTimeMax.IsZero() {
+			startTimeMax = query.StartTimeMax.Add(timeSlack)
+		}
+	}
+
+	if dr.cfg.Doris.FindTraces.Mode == FindTracesModeJoin {
+		return queryFindTracesJoin(schema, dr.cfg.Doris.TableFullName(), traceIDsQuery, sort, startTimeMin, startTimeMax, dr.cfg.Doris.Location), nil, nil
+	}
 
 	traceIDs := make([]string, 0)
 
@@ -110,47 +313,97 @@ func (dr *dorisReader) FindTraces(ctx context.Context, query *spanstore.TraceQue
 		if !ok || traceID == "" {
 			return fmt.Errorf("invalid trace_id")
 		}
-		traceIDs = append(traceIDs, traceID)
+		traceIDs = append(traceIDs, schema.decodeID(traceID))
 		return nil
 	}
 
-	err := executeQuery(ctx, dr.db, dr.cfg, queryFindTraceIDs(schema, dr.cfg.Doris.TableFullName(), query, dr.cfg.Doris.Location), f)
+	err = executeQuery(ctx, dr.db, dr.cfg, traceIDsQuery, f)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
-	traces := make([]*model.Trace, 0, len(traceIDs))
 	if len(traceIDs) == 0 {
-		return traces, nil
+		return "", traceIDs, nil
 	}
 
-	traceMap := make(map[string]*model.Trace)
-	for _, traceID := range traceIDs {
-		traceMap[traceID] = &model.Trace{
-			Spans: make([]*model.Span, 0),
-		}
+	spansQuery := queryFindTraces(schema, dr.cfg.Doris.TableFullName(), traceIDs, startTimeMin, startTimeMax, dr.cfg.Doris.Location)
+	if ordered {
+		spansQuery += orderByTraceIDs(schema, traceIDs)
 	}
 
-	f = func(ctx context.Context, cfg *Config, record map[string]string) error {
-		span, err := recordToSpan(ctx, cfg, record)
+	return spansQuery, traceIDs, nil
+}
+
+// findTracesSort returns the sort selected by the "_sort" tag, or the configured one.
+func (dr *dorisReader) findTracesSort(query *spanstore.TraceQueryParameters) string {
+	switch sort := query.Tags[TagKeySort]; sort {
+	case FindTracesSortNewest, FindTracesSortOldest, FindTracesSortLongest, FindTracesSortMostSpans, FindTracesSortMostErrors:
+		return sort
+	case "":
+	default:
+		dr.logger.Warn("Invalid sort, using the default", zap.String("sort", sort))
+	}
+	return dr.cfg.Doris.FindTraces.Sort
+}
+
+// findTracesCursor returns the cursor of the "_cursor" tag, if any.
+func findTracesCursor(query *spanstore.TraceQueryParameters, sort string) (*traceCursor, error) {
+	cursorString, ok := query.Tags[TagKeyCursor]
+	if !ok {
+		return nil, nil
+	}
+	if sort != FindTracesSortNewest && sort != FindTracesSortOldest {
+		return nil, status.Errorf(codes.InvalidArgument, "cursor requires the sort %s or %s", FindTracesSortNewest, FindTracesSortOldest)
+	}
+	cursor, err := parseTraceCursor(cursorString)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return cursor, nil
+}
+
+// findTraces groups the spans returned by query into traces. The traces are returned in
+// the order of traceIDs, or in the order of their first span if traceIDs is nil.
+func (dr *dorisReader) findTraces(ctx context.Context, query string, traceIDs []string) ([]*model.Trace, error) {
+	schema := dr.cfg.Doris.SchemaMapping
+
+	traceMap := make(map[string]*model.Trace)
+	order := traceIDs
+
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		span, err := dr.spanFromRecord(ctx, cfg, record, nil)
 		if err != nil {
 			dr.logger.Warn("Failed to convert record to span", zap.Error(err))
-		} else {
-			traceIDString := record[schema.TraceID]
-			traceMap[traceIDString].Spans = append(traceMap[traceIDString].Spans, span)
+			return nil
+		}
+
+		traceIDString := schema.decodeID(record[schema.TraceID])
+		trace, ok := traceMap[traceIDString]
+		if !ok {
+			trace = &model.Trace{
+				Spans: make([]*model.Span, 0),
+			}
+			traceMap[traceIDString] = trace
+			if traceIDs == nil {
+				order = append(order, traceIDString)
+			}
 		}
+		trace.Spans = append(trace.Spans, span)
 
 		return nil
 	}
 
-	err = executeQuery(ctx, dr.db, dr.cfg, queryFindTraces(schema, dr.cfg.Doris.TableFullName(), traceIDs), f)
+	err := executeQuery(ctx, dr.db, dr.cfg, query, f)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, trace := range traceMap {
-		if len(trace.Spans) > 0 {
-			traces = append(traces, sanitizeTrace(trace))
+	traces := make([]*model.Trace, 0, len(traceMap))
+	for _, traceID := range order {
+		trace, ok := traceMap[traceID]
+		if ok && len(trace.Spans) > 0 {
+			trace.Spans = truncateSpans(trace.Spans, dr.cfg.Doris.MaxSpansPerTrace)
+			traces = append(traces, sanitizeTrace(dr.cfg.Doris.Sanitize, trace))
 		}
 	}
 
@@ -158,25 +411,53 @@ func (dr *dorisReader) FindTraces(ctx context.Context, query *spanstore.TraceQue
 }
 
 func (dr *dorisReader) FindTraceIDs(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, error) {
+	traceIDs, _, err := dr.FindTraceIDsPage(ctx, query)
+	return traceIDs, err
+}
+
+// FindTraceIDsPage is FindTraceIDs which also returns the cursor of the last trace. Passing
+// it with the "_cursor" tag returns the next page, it is empty if there are no more traces.
+func (dr *dorisReader) FindTraceIDsPage(ctx context.Context, query *spanstore.TraceQueryParameters) ([]model.TraceID, string, error) {
 	schema := dr.cfg.Doris.SchemaMapping
+	sort := dr.findTracesSort(query)
+	cursor, err := findTracesCursor(query, sort)
+	if err != nil {
+		return nil, "", err
+	}
 
 	traceIDs := make([]model.TraceID, 0)
+	var last *traceCursor
 
 	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
-		traceID, err := model.TraceIDFromString(record[schema.TraceID])
+		traceIDString := schema.decodeID(record[schema.TraceID])
+		traceID, err := model.TraceIDFromString(traceIDString)
 		if err != nil {
 			return err
 		}
 		traceIDs = append(traceIDs, traceID)
+
+		startTime, err := time.ParseInLocation(timeFormat, record["t"], cfg.Doris.Location)
+		if err != nil {
+			return err
+		}
+		last = &traceCursor{
+			StartTime: startTime,
+			TraceID:   traceIDString,
+		}
 		return nil
 	}
 
-	err := executeQuery(ctx, dr.db, dr.cfg, queryFindTraceIDs(schema, dr.cfg.Doris.TableFullName(), query, dr.cfg.Doris.Location), f)
+	err = executeQuery(ctx, dr.db, dr.cfg, queryFindTraceIDs(schema, dr.cfg.Doris.TableFullName(), query, dr.cfg.Doris.Location, dr.cfg.Doris.FullTextSearch, sort, cursor, dr.redactor.attributeGuard(tenancy.GetTenant(ctx))), f)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	next := ""
+	if last != nil && len(traceIDs) == query.NumTraces && (sort == FindTracesSortNewest || sort == FindTracesSortOldest) {
+		next = last.String()
 	}
 
-	return traceIDs, nil
+	return traceIDs, next, nil
 }
 
 type dorisDependencyReader struct {
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/plugin/storage/grpc/shared"
+)
+
+// fakeQueryFunc returns the columns and rows of a query, or its error.
+type fakeQueryFunc func(query string) ([]string, [][]driver.Value, error)
+
+// newFakeDB returns a database answering queries with f.
+func newFakeDB(t *testing.T, f fakeQueryFunc) *sql.DB {
+	db := sql.OpenDB(fakeConnector{f})
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+type fakeConnector struct {
+	f fakeQueryFunc
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn(c), nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	f fakeQueryFunc
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	columns, rows, err := c.f(query)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestGetTraceMergeLogsFailure(t *testing.T) {
+	cfg := &Config{}
+	require.NoError(t, cfg.Init(configPath))
+	cfg.Doris.LogsTable = "logs"
+	cfg.Doris.MergeLogs = true
+	require.NoError(t, cfg.Validate())
+
+	db := newFakeDB(t, func(query string) ([]string, [][]driver.Value, error) {
+		if strings.Contains(query, cfg.Doris.LogsTableFullName()) {
+			return nil, nil, errors.New("logs table unavailable")
+		}
+		schema := cfg.Doris.SchemaMapping
+		columns := []string{schema.ServiceName, schema.Timestamp, schema.TraceID, schema.SpanID, schema.SpanName, schema.Duration}
+		return columns, [][]driver.Value{{
+			[]byte("test-service"),
+			[]byte("2024-01-01 01:01:01.000001"),
+			[]byte("01020301000000000000000000000000"),
+			[]byte("0102030100000000"),
+			[]byte("test-operation"),
+			[]byte("1000"),
+		}}, nil
+	})
+	dr := &dorisReader{logger: zap.NewNop(), db: db, cfg: cfg}
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+	query := shared.GetTraceParameters{TraceID: model.NewTraceID(0x0102030100000000, 0)}
+
+	trace, err := dr.GetTraceWithParameters(ctx, query)
+	require.NoError(t, err)
+	require.Len(t, trace.Spans, 1)
+	require.Equal(t, "test-operation", trace.Spans[0].OperationName)
+	require.Empty(t, trace.Spans[0].Logs)
+
+	var spans []*model.Span
+	err = dr.StreamTrace(ctx, query, func(batch []*model.Span) error {
+		spans = append(spans, batch...)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+}
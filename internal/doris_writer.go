@@ -6,11 +6,14 @@ import (
 
 	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstore_v2 "github.com/jaegertracing/jaeger/storage_v2/spanstore"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.uber.org/zap"
 )
 
 var (
-	_ spanstore.Writer = (*dorisWriterNoop)(nil)
+	_ spanstore.Writer    = (*dorisWriterNoop)(nil)
+	_ spanstore_v2.Writer = (*dorisWriterNoop)(nil)
 )
 
 type dorisWriterNoop struct {
@@ -21,3 +24,8 @@ func (dw *dorisWriterNoop) WriteSpan(ctx context.Context, span *model.Span) erro
 	dw.logger.Debug("no-op WriteSpan called")
 	return errors.New("WriteSpan is not implemented in this context")
 }
+
+func (dw *dorisWriterNoop) WriteTraces(ctx context.Context, td ptrace.Traces) error {
+	dw.logger.Debug("no-op WriteTraces called")
+	return errors.New("WriteTraces is not implemented in this context")
+}
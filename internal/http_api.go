@@ -0,0 +1,265 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/jaegertracing/jaeger/storage/dependencystore"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultHTTPQueryLimit = 20
+
+// HTTPHandler serves the HTTP query APIs on top of the doris reader.
+type HTTPHandler struct {
+	logger           *zap.Logger
+	reader           *accessControlledReader
+	dependencyReader dependencystore.Reader
+	metricsReader    metricsstore.Reader // nil unless service.spm is enabled
+	zipkin           bool
+	logs             bool // doris.logs_table is set
+}
+
+func NewHTTPHandler(logger *zap.Logger, ds *DorisStorage) *HTTPHandler {
+	h := &HTTPHandler{
+		logger:           logger,
+		reader:           ds.access,
+		dependencyReader: ds.dependencyReader,
+		zipkin:           ds.cfg.Service.ZipkinAPI,
+		logs:             ds.cfg.Doris.LogsTable != "",
+	}
+	if ds.cfg.Service.SPM {
+		h.metricsReader = ds.MetricsReader()
+	}
+	return h
+}
+
+func (h *HTTPHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/trace-ids", h.findTraceIDs)
+	h.registerJaegerRoutes(mux)
+	h.registerTraceQLRoutes(mux)
+	h.registerAnalyticsRoutes(mux)
+	if h.logs {
+		h.registerLogsRoutes(mux)
+	}
+	if h.metricsReader != nil {
+		h.registerMetricsRoutes(mux)
+	}
+	if h.zipkin {
+		h.registerZipkinRoutes(mux)
+	}
+}
+
+func (h *HTTPHandler) context(r *http.Request) context.Context {
+	return LoggerWithContext(r.Context(), h.logger)
+}
+
+type traceIDsResponse struct {
+	Data   []string `json:"data"`
+	Cursor string   `json:"cursor,omitempty"`
+}
+
+// findTraceIDs pages through the IDs of matching traces, the response contains
+// the cursor of the next page which is passed back with the "cursor" parameter.
+func (h *HTTPHandler) findTraceIDs(w http.ResponseWriter, r *http.Request) {
+	query, err := parseTraceQuery(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if cursor := r.FormValue("cursor"); cursor != "" {
+		query.Tags[TagKeyCursor] = cursor
+	}
+
+	traceIDs, cursor, err := h.reader.FindTraceIDsPage(h.context(r), query)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := &traceIDsResponse{
+		Data:   make([]string, 0, len(traceIDs)),
+		Cursor: cursor,
+	}
+	for _, traceID := range traceIDs {
+		response.Data = append(response.Data, traceID.String())
+	}
+	h.writeJSON(w, response)
+}
+
+// parseTraceQuery parses the search parameters of the Jaeger UI /api/traces endpoint:
+// service, operation, tags (JSON object), tag (key:value), start and end (unix microseconds),
+// minDuration and maxDuration (e.g. 1.2s) and limit.
+func parseTraceQuery(r *http.Request) (*spanstore.TraceQueryParameters, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return nil, newBadRequestError("malformed query: %s", err)
+	}
+
+	query := &spanstore.TraceQueryParameters{
+		ServiceName:   r.FormValue("service"),
+		OperationName: r.FormValue("operation"),
+		Tags:          make(map[string]string),
+		NumTraces:     defaultHTTPQueryLimit,
+	}
+
+	if tags := r.FormValue("tags"); tags != "" {
+		err = json.Unmarshal([]byte(tags), &query.Tags)
+		if err != nil {
+			return nil, newBadRequestError("malformed tags parameter: %s", err)
+		}
+	}
+	for _, tag := range r.Form["tag"] {
+		k, v, ok := strings.Cut(tag, ":")
+		if !ok {
+			return nil, newBadRequestError("malformed tag parameter: %s", tag)
+		}
+		query.Tags[k] = v
+	}
+
+	query.StartTimeMin, err = parseUnixMicros(r, "start")
+	if err != nil {
+		return nil, err
+	}
+	query.StartTimeMax, err = parseUnixMicros(r, "end")
+	if err != nil {
+		return nil, err
+	}
+
+	query.DurationMin, err = parseDuration(r, "minDuration")
+	if err != nil {
+		return nil, err
+	}
+	query.DurationMax, err = parseDuration(r, "maxDuration")
+	if err != nil {
+		return nil, err
+	}
+
+	if limit := r.FormValue("limit"); limit != "" {
+		query.NumTraces, err = strconv.Atoi(limit)
+		if err != nil || query.NumTraces <= 0 {
+			return nil, newBadRequestError("malformed limit parameter: %s", limit)
+		}
+	}
+
+	return query, nil
+}
+
+func parseUnixMicros(r *http.Request, name string) (time.Time, error) {
+	return parseUnixMicrosValue(name, r.FormValue(name))
+}
+
+func parseUnixMicrosValue(name string, v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	micros, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, newBadRequestError("malformed %s parameter: %s", name, v)
+	}
+	return time.UnixMicro(micros), nil
+}
+
+func parseUnixSeconds(r *http.Request, name string, defaultValue time.Time) (time.Time, error) {
+	v := r.FormValue(name)
+	if v == "" {
+		return defaultValue, nil
+	}
+	seconds, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, newBadRequestError("malformed %s parameter: %s", name, v)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+func parseUnixMillis(r *http.Request, name string, defaultValue time.Time) (time.Time, error) {
+	v := r.FormValue(name)
+	if v == "" {
+		return defaultValue, nil
+	}
+	millis, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, newBadRequestError("malformed %s parameter: %s", name, v)
+	}
+	return time.UnixMilli(millis), nil
+}
+
+func parseMillis(r *http.Request, name string, defaultValue time.Duration) (time.Duration, error) {
+	v := r.FormValue(name)
+	if v == "" {
+		return defaultValue, nil
+	}
+	millis, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || millis < 0 {
+		return 0, newBadRequestError("malformed %s parameter: %s", name, v)
+	}
+	return time.Duration(millis) * time.Millisecond, nil
+}
+
+func parseDuration(r *http.Request, name string) (time.Duration, error) {
+	v := r.FormValue(name)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, newBadRequestError("malformed %s parameter: %s", name, v)
+	}
+	return d, nil
+}
+
+type badRequestError struct {
+	msg string
+}
+
+func (e *badRequestError) Error() string {
+	return e.msg
+}
+
+func newBadRequestError(format string, args ...any) error {
+	return &badRequestError{msg: fmt.Sprintf(format, args...)}
+}
+
+func (h *HTTPHandler) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		h.logger.Warn("failed to write response", zap.Error(err))
+	}
+}
+
+func (h *HTTPHandler) writeError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), h.errorStatusCode(err))
+}
+
+// errorStatusCode maps err to the HTTP status code, and logs internal errors.
+func (h *HTTPHandler) errorStatusCode(err error) int {
+	code := http.StatusInternalServerError
+
+	var badRequest *badRequestError
+	switch {
+	case errors.As(err, &badRequest):
+		code = http.StatusBadRequest
+	case errors.Is(err, spanstore.ErrTraceNotFound):
+		code = http.StatusNotFound
+	case status.Code(err) == codes.InvalidArgument:
+		code = http.StatusBadRequest
+	case status.Code(err) == codes.PermissionDenied:
+		code = http.StatusForbidden
+	}
+
+	if code == http.StatusInternalServerError {
+		h.logger.Error("HTTP request failed", zap.Error(err))
+	}
+	return code
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultAnalyticsLookback = time.Hour
+
+// registerAnalyticsRoutes registers the endpoints that aggregate the spans of a service.
+func (h *HTTPHandler) registerAnalyticsRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/analytics/slow-operations", h.getSlowOperations)
+	mux.HandleFunc("GET /api/analytics/error-operations", h.getErrorOperations)
+	mux.HandleFunc("GET /api/analytics/duration-histogram", h.getDurationHistogram)
+}
+
+func (h *HTTPHandler) getSlowOperations(w http.ResponseWriter, r *http.Request) {
+	h.getOperationStats(w, r, false)
+}
+
+func (h *HTTPHandler) getErrorOperations(w http.ResponseWriter, r *http.Request) {
+	h.getOperationStats(w, r, true)
+}
+
+func (h *HTTPHandler) getOperationStats(w http.ResponseWriter, r *http.Request, errors bool) {
+	query, err := parseAnalyticsQuery(r)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	stats, err := h.reader.GetOperationStats(h.context(r), query, errors)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	h.writeJSON(w, &structuredResponse{Data: stats, Total: len(stats), Limit: query.Limit})
+}
+
+func (h *HTTPHandler) getDurationHistogram(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAnalyticsQuery(r)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	if query.Operation == "" {
+		h.writeStructuredError(w, newBadRequestError("parameter 'operation' is required"))
+		return
+	}
+	buckets, err := h.reader.GetDurationHistogram(h.context(r), query)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	h.writeJSON(w, &structuredResponse{Data: buckets, Total: len(buckets)})
+}
+
+// parseAnalyticsQuery parses service, operation, spanKind (e.g. server), start and end in unix
+// microseconds like /api/traces (the last hour by default) and limit.
+func parseAnalyticsQuery(r *http.Request) (*analyticsQuery, error) {
+	return parseAnalyticsParameters(r.FormValue)
+}
+
+// parseAnalyticsParameters parses the parameters of parseAnalyticsQuery, returned by get.
+func parseAnalyticsParameters(get func(name string) string) (*analyticsQuery, error) {
+	query := &analyticsQuery{
+		ServiceName: get("service"),
+		Operation:   get("operation"),
+		SpanKind:    get("spanKind"),
+		Limit:       defaultHTTPQueryLimit,
+	}
+	if query.ServiceName == "" {
+		return nil, newBadRequestError("parameter 'service' is required")
+	}
+	if _, ok := jeagerToOtelSpanKind[query.SpanKind]; query.SpanKind != "" && !ok {
+		return nil, newBadRequestError("unsupported span kind: '%s'", query.SpanKind)
+	}
+
+	var err error
+	query.StartTimeMax, err = parseUnixMicrosValue("end", get("end"))
+	if err != nil {
+		return nil, err
+	}
+	if query.StartTimeMax.IsZero() {
+		query.StartTimeMax = time.Now()
+	}
+	query.StartTimeMin, err = parseUnixMicrosValue("start", get("start"))
+	if err != nil {
+		return nil, err
+	}
+	if query.StartTimeMin.IsZero() {
+		query.StartTimeMin = query.StartTimeMax.Add(-defaultAnalyticsLookback)
+	}
+
+	if v := get("limit"); v != "" {
+		query.Limit, err = strconv.Atoi(v)
+		if err != nil || query.Limit <= 0 {
+			return nil, newBadRequestError("malformed limit parameter: %s", v)
+		}
+	}
+	return query, nil
+}
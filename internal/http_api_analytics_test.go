@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseAnalyticsQuery(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/analytics/slow-operations?service=checkout&spanKind=server&end=1704070861000000&limit=5", nil)
+	query, err := parseAnalyticsQuery(r)
+	require.NoError(t, err)
+	require.Equal(t, "checkout", query.ServiceName)
+	require.Equal(t, "server", query.SpanKind)
+	require.Equal(t, time.UnixMicro(1704070861000000), query.StartTimeMax)
+	require.Equal(t, query.StartTimeMax.Add(-defaultAnalyticsLookback), query.StartTimeMin)
+	require.Equal(t, 5, query.Limit)
+
+	h := &HTTPHandler{logger: zap.NewNop()}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	for _, url := range []string{
+		"/api/analytics/slow-operations",
+		"/api/analytics/error-operations?service=checkout&spanKind=queue",
+		"/api/analytics/error-operations?service=checkout&limit=0",
+		"/api/analytics/duration-histogram?service=checkout",
+		"/api/analytics/duration-histogram?service=checkout&operation=GET&start=yesterday",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestNewDurationBucket(t *testing.T) {
+	require.Equal(t, &durationBucket{Min: 0, Max: 2, TraceIDs: []string{}}, newDurationBucket(0))
+	require.Equal(t, &durationBucket{Min: 1024, Max: 2048, TraceIDs: []string{}}, newDurationBucket(10))
+}
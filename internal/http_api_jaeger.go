@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/model/adjuster"
+	uiconv "github.com/jaegertracing/jaeger/model/converter/json"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"go.uber.org/zap"
+)
+
+const defaultDependenciesLookback = 24 * time.Hour
+
+// traceAdjuster applies the adjustments jaeger-query makes before it returns traces.
+var traceAdjuster = adjuster.Sequence(
+	adjuster.SpanIDDeduper(),
+	adjuster.ClockSkew(0),
+	adjuster.IPTagAdjuster(),
+	adjuster.OTelTagAdjuster(),
+	adjuster.SortLogFields(),
+	adjuster.SpanReferences(),
+	adjuster.ParentReference(),
+)
+
+// structuredResponse is the envelope of the responses of the jaeger-query HTTP API.
+type structuredResponse struct {
+	Data   any               `json:"data"`
+	Total  int               `json:"total"`
+	Limit  int               `json:"limit"`
+	Offset int               `json:"offset"`
+	Errors []structuredError `json:"errors"`
+}
+
+type structuredError struct {
+	Code    int        `json:"code,omitempty"`
+	Msg     string     `json:"msg"`
+	TraceID ui.TraceID `json:"traceID,omitempty"`
+}
+
+// registerJaegerRoutes registers the endpoints of the jaeger-query HTTP API used by the Jaeger UI
+// and Grafana's Jaeger data source.
+func (h *HTTPHandler) registerJaegerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/services", h.getServices)
+	mux.HandleFunc("GET /api/services/{service}/operations", h.getOperationNames)
+	mux.HandleFunc("GET /api/operations", h.getOperations)
+	mux.HandleFunc("GET /api/traces", h.searchTraces)
+	mux.HandleFunc("GET /api/traces/{traceID}", h.getTrace)
+	mux.HandleFunc("GET /api/dependencies", h.getDependencies)
+}
+
+func (h *HTTPHandler) getServices(w http.ResponseWriter, r *http.Request) {
+	services, err := h.reader.GetServices(h.context(r))
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	h.writeJSON(w, &structuredResponse{Data: services, Total: len(services)})
+}
+
+// getOperationNames serves the legacy endpoint which returns the names of the operations only.
+func (h *HTTPHandler) getOperationNames(w http.ResponseWriter, r *http.Request) {
+	operations, err := h.reader.GetOperations(h.context(r), spanstore.OperationQueryParameters{
+		ServiceName: r.PathValue("service"),
+	})
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+
+	names := make([]string, 0, len(operations))
+	seen := make(map[string]struct{}, len(operations))
+	for _, operation := range operations {
+		if _, ok := seen[operation.Name]; ok {
+			continue
+		}
+		seen[operation.Name] = struct{}{}
+		names = append(names, operation.Name)
+	}
+	h.writeJSON(w, &structuredResponse{Data: names, Total: len(names)})
+}
+
+func (h *HTTPHandler) getOperations(w http.ResponseWriter, r *http.Request) {
+	service := r.FormValue("service")
+	if service == "" {
+		h.writeStructuredError(w, newBadRequestError("parameter 'service' is required"))
+		return
+	}
+
+	operations, err := h.reader.GetOperations(h.context(r), spanstore.OperationQueryParameters{
+		ServiceName: service,
+		SpanKind:    r.FormValue("spanKind"),
+	})
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+
+	data := make([]ui.Operation, 0, len(operations))
+	for _, operation := range operations {
+		data = append(data, ui.Operation{Name: operation.Name, SpanKind: operation.SpanKind})
+	}
+	h.writeJSON(w, &structuredResponse{Data: data, Total: len(data)})
+}
+
+// searchTraces returns the traces listed by the traceID parameters, or the traces matching the search.
+func (h *HTTPHandler) searchTraces(w http.ResponseWriter, r *http.Request) {
+	query, err := parseTraceQuery(r)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+
+	ctx := h.context(r)
+	response := &structuredResponse{}
+	var traces []*model.Trace
+
+	if len(r.Form["traceID"]) > 0 {
+		for _, id := range r.Form["traceID"] {
+			traceID, err := model.TraceIDFromString(id)
+			if err != nil {
+				h.writeStructuredError(w, newBadRequestError("cannot parse traceID param: %s", err))
+				return
+			}
+			trace, err := h.reader.GetTrace(ctx, traceID)
+			if errors.Is(err, spanstore.ErrTraceNotFound) {
+				response.Errors = append(response.Errors, structuredError{
+					Code:    http.StatusNotFound,
+					Msg:     err.Error(),
+					TraceID: ui.TraceID(traceID.String()),
+				})
+				continue
+			}
+			if err != nil {
+				h.writeStructuredError(w, err)
+				return
+			}
+			traces = append(traces, trace)
+		}
+	} else {
+		if query.ServiceName == "" {
+			h.writeStructuredError(w, newBadRequestError("parameter 'service' is required"))
+			return
+		}
+		traces, err = h.reader.FindTraces(ctx, query)
+		if err != nil {
+			h.writeStructuredError(w, err)
+			return
+		}
+	}
+
+	data := make([]*ui.Trace, 0, len(traces))
+	for _, trace := range traces {
+		data = append(data, h.uiTrace(trace))
+	}
+	response.Data = data
+	h.writeJSON(w, response)
+}
+
+func (h *HTTPHandler) getTrace(w http.ResponseWriter, r *http.Request) {
+	traceID, err := model.TraceIDFromString(r.PathValue("traceID"))
+	if err != nil {
+		h.writeStructuredError(w, newBadRequestError("cannot parse traceID: %s", err))
+		return
+	}
+
+	trace, err := h.reader.GetTrace(h.context(r), traceID)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	h.writeJSON(w, &structuredResponse{Data: []*ui.Trace{h.uiTrace(trace)}})
+}
+
+// getDependencies takes endTs and lookback in milliseconds, like jaeger-query, and an optional service.
+func (h *HTTPHandler) getDependencies(w http.ResponseWriter, r *http.Request) {
+	endTs, err := parseUnixMillis(r, "endTs", time.Now())
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	lookback, err := parseMillis(r, "lookback", defaultDependenciesLookback)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+
+	dependencies, err := h.dependencyReader.GetDependencies(h.context(r), endTs, lookback)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	h.writeJSON(w, &structuredResponse{Data: uiDependencies(dependencies, r.FormValue("service"))})
+}
+
+// uiDependencies merges the links between the same services, and keeps the links of service
+// if it is set.
+func uiDependencies(dependencies []model.DependencyLink, service string) []ui.DependencyLink {
+	type key struct {
+		parent string
+		child  string
+	}
+	indexes := make(map[key]int, len(dependencies))
+	links := make([]ui.DependencyLink, 0, len(dependencies))
+
+	for _, dependency := range dependencies {
+		if service != "" && dependency.Parent != service && dependency.Child != service {
+			continue
+		}
+		k := key{parent: dependency.Parent, child: dependency.Child}
+		if i, ok := indexes[k]; ok {
+			links[i].CallCount += dependency.CallCount
+			continue
+		}
+		indexes[k] = len(links)
+		links = append(links, ui.DependencyLink{
+			Parent:    dependency.Parent,
+			Child:     dependency.Child,
+			CallCount: dependency.CallCount,
+		})
+	}
+	return links
+}
+
+func (h *HTTPHandler) uiTrace(trace *model.Trace) *ui.Trace {
+	trace, err := traceAdjuster.Adjust(trace)
+	if err != nil {
+		h.logger.Debug("failed to adjust trace", zap.Error(err))
+	}
+	return uiconv.FromDomain(trace)
+}
+
+func (h *HTTPHandler) writeStructuredError(w http.ResponseWriter, err error) {
+	code := h.errorStatusCode(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	h.writeJSON(w, &structuredResponse{
+		Errors: []structuredError{{Code: code, Msg: err.Error()}},
+	})
+}
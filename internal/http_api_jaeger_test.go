@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	dependencyStoreMocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanStoreMocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHTTPHandlerJaegerAPI(t *testing.T) {
+	spanReader := &spanStoreMocks.Reader{}
+	spanReader.On("GetServices", mock.Anything).Return([]string{"checkout", "frontend"}, nil)
+	spanReader.On("GetOperations", mock.Anything, spanstore.OperationQueryParameters{ServiceName: "checkout"}).
+		Return([]spanstore.Operation{{Name: "GET", SpanKind: "server"}, {Name: "GET", SpanKind: "client"}}, nil)
+	depsReader := &dependencyStoreMocks.Reader{}
+	depsReader.On("GetDependencies", mock.Anything, time.UnixMilli(1704070861000), time.Hour).
+		Return([]model.DependencyLink{
+			{Parent: "frontend", Child: "checkout", CallCount: 3},
+			{Parent: "frontend", Child: "checkout", CallCount: 2},
+			{Parent: "checkout", Child: "db", CallCount: 1},
+		}, nil)
+
+	reader := newAccessControlledReader(spanReader, nil, depsReader, AccessDeniedSpansStrip)
+	h := &HTTPHandler{
+		logger:           zap.NewNop(),
+		reader:           reader,
+		dependencyReader: reader,
+	}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, tc := range []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/api/services", http.StatusOK, `{"data":["checkout","frontend"],"total":2,"limit":0,"offset":0,"errors":null}`},
+		{"/api/services/checkout/operations", http.StatusOK, `{"data":["GET"],"total":1,"limit":0,"offset":0,"errors":null}`},
+		{"/api/operations?service=checkout", http.StatusOK, `{"data":[{"name":"GET","spanKind":"server"},{"name":"GET","spanKind":"client"}],"total":2,"limit":0,"offset":0,"errors":null}`},
+		{"/api/dependencies?endTs=1704070861000&lookback=3600000", http.StatusOK, `{"data":[{"parent":"frontend","child":"checkout","callCount":5},{"parent":"checkout","child":"db","callCount":1}],"total":0,"limit":0,"offset":0,"errors":null}`},
+		{"/api/dependencies?endTs=1704070861000&lookback=3600000&service=db", http.StatusOK, `{"data":[{"parent":"checkout","child":"db","callCount":1}],"total":0,"limit":0,"offset":0,"errors":null}`},
+		{"/api/traces", http.StatusBadRequest, `{"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":400,"msg":"parameter 'service' is required"}]}`},
+		{"/api/traces/xyz", http.StatusBadRequest, `{"data":null,"total":0,"limit":0,"offset":0,"errors":[{"code":400,"msg":"cannot parse traceID: strconv.ParseUint: parsing \"xyz\": invalid syntax"}]}`},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		require.Equal(t, tc.code, w.Code, tc.url)
+		require.JSONEq(t, tc.body, w.Body.String(), tc.url)
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+const defaultLogsLimit = 1000
+
+// uiLogRecord is a log record in the format of the logs of the Jaeger UI, with the span it belongs to.
+type uiLogRecord struct {
+	TraceID     ui.TraceID `json:"traceID"`
+	SpanID      ui.SpanID  `json:"spanID,omitempty"`
+	ServiceName string     `json:"serviceName"`
+	ui.Log
+}
+
+// registerLogsRoutes registers the endpoint of the log records of a trace.
+func (h *HTTPHandler) registerLogsRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/traces/{traceID}/logs", h.getLogs)
+}
+
+// getLogs returns the log records of the trace, or of the span given by spanID. start and end
+// in unix microseconds limit the scanned time range, and limit the number of records.
+func (h *HTTPHandler) getLogs(w http.ResponseWriter, r *http.Request) {
+	traceID, err := model.TraceIDFromString(r.PathValue("traceID"))
+	if err != nil {
+		h.writeStructuredError(w, newBadRequestError("cannot parse traceID: %s", err))
+		return
+	}
+	spanID := ""
+	if v := r.FormValue("spanID"); v != "" {
+		id, err := model.SpanIDFromString(v)
+		if err != nil {
+			h.writeStructuredError(w, newBadRequestError("cannot parse spanID: %s", err))
+			return
+		}
+		spanID = id.String()
+	}
+	startTime, err := parseUnixMicros(r, "start")
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	endTime, err := parseUnixMicros(r, "end")
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	limit := defaultLogsLimit
+	if v := r.FormValue("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			h.writeStructuredError(w, newBadRequestError("malformed limit parameter: %s", v))
+			return
+		}
+	}
+
+	records, err := h.reader.GetLogs(h.context(r), traceID, spanID, startTime, endTime, limit)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+
+	data := make([]*uiLogRecord, 0, len(records))
+	for _, lr := range records {
+		data = append(data, &uiLogRecord{
+			TraceID:     ui.TraceID(traceIDToString(traceID)),
+			SpanID:      ui.SpanID(lr.SpanID),
+			ServiceName: lr.ServiceName,
+			Log:         uiLog(lr.Log),
+		})
+	}
+	h.writeJSON(w, &structuredResponse{Data: data, Total: len(data), Limit: limit})
+}
+
+// uiLog converts log like the JSON converter of jaeger-query.
+func uiLog(log model.Log) ui.Log {
+	fields := make([]ui.KeyValue, 0, len(log.Fields))
+	for _, kv := range log.Fields {
+		fields = append(fields, ui.KeyValue{
+			Key:   kv.Key,
+			Type:  ui.ValueType(strings.ToLower(kv.VType.String())),
+			Value: kv.Value(),
+		})
+	}
+	return ui.Log{
+		Timestamp: model.TimeAsEpochMicroseconds(log.Timestamp),
+		Fields:    fields,
+	}
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	ui "github.com/jaegertracing/jaeger/model/json"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHTTPHandlerLogs(t *testing.T) {
+	for _, url := range []string{
+		"/api/traces/xyz/logs",
+		"/api/traces/0102030100000000/logs?spanID=xyz",
+		"/api/traces/0102030100000000/logs?start=yesterday",
+		"/api/traces/0102030100000000/logs?limit=-1",
+	} {
+		h := &HTTPHandler{logger: zap.NewNop(), logs: true}
+		mux := http.NewServeMux()
+		h.RegisterRoutes(mux)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+
+	require.Equal(t, ui.Log{
+		Timestamp: 1704070861000000,
+		Fields:    []ui.KeyValue{{Key: "message", Type: ui.StringType, Value: "ok"}, {Key: "retry", Type: ui.Int64Type, Value: int64(1)}},
+	}, uiLog(model.Log{
+		Timestamp: time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+		Fields:    []model.KeyValue{model.String("message", "ok"), model.Int64("retry", 1)},
+	}))
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2/metrics"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"go.uber.org/zap"
+)
+
+// registerMetricsRoutes registers the metrics endpoints of the jaeger-query HTTP API used by the Monitor tab.
+func (h *HTTPHandler) registerMetricsRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/metrics/latencies", h.getLatencies)
+	mux.HandleFunc("GET /api/metrics/calls", h.getCallRates)
+	mux.HandleFunc("GET /api/metrics/errors", h.getErrorRates)
+	mux.HandleFunc("GET /api/metrics/minstep", h.getMinStep)
+}
+
+func (h *HTTPHandler) getLatencies(w http.ResponseWriter, r *http.Request) {
+	quantile, err := strconv.ParseFloat(r.FormValue("quantile"), 64)
+	if err != nil {
+		h.writeStructuredError(w, newBadRequestError("malformed quantile parameter: %s", r.FormValue("quantile")))
+		return
+	}
+	params, err := parseMetricsQuery(r)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+
+	m, err := h.metricsReader.GetLatencies(h.context(r), &metricsstore.LatenciesQueryParameters{
+		BaseQueryParameters: params,
+		Quantile:            quantile,
+	})
+	h.writeMetrics(w, m, err)
+}
+
+func (h *HTTPHandler) getCallRates(w http.ResponseWriter, r *http.Request) {
+	params, err := parseMetricsQuery(r)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	m, err := h.metricsReader.GetCallRates(h.context(r), &metricsstore.CallRateQueryParameters{BaseQueryParameters: params})
+	h.writeMetrics(w, m, err)
+}
+
+func (h *HTTPHandler) getErrorRates(w http.ResponseWriter, r *http.Request) {
+	params, err := parseMetricsQuery(r)
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	m, err := h.metricsReader.GetErrorRates(h.context(r), &metricsstore.ErrorRateQueryParameters{BaseQueryParameters: params})
+	h.writeMetrics(w, m, err)
+}
+
+// getMinStep returns the minimum step in milliseconds.
+func (h *HTTPHandler) getMinStep(w http.ResponseWriter, r *http.Request) {
+	minStep, err := h.metricsReader.GetMinStepDuration(h.context(r), &metricsstore.MinStepDurationQueryParameters{})
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	h.writeJSON(w, &structuredResponse{Data: minStep.Milliseconds()})
+}
+
+// writeMetrics writes m like jaeger-query, with the JSON mapping of protobuf.
+func (h *HTTPHandler) writeMetrics(w http.ResponseWriter, m *metrics.MetricFamily, err error) {
+	if err != nil {
+		h.writeStructuredError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = new(jsonpb.Marshaler).Marshal(w, m)
+	if err != nil {
+		h.logger.Warn("failed to write response", zap.Error(err))
+	}
+}
+
+// parseMetricsQuery parses the parameters of the metrics endpoints of jaeger-query: service (repeated),
+// groupByOperation, spanKind (repeated, e.g. server), endTs, lookback, step and ratePer in milliseconds.
+func parseMetricsQuery(r *http.Request) (metricsstore.BaseQueryParameters, error) {
+	params := metricsstore.BaseQueryParameters{}
+	err := r.ParseForm()
+	if err != nil {
+		return params, newBadRequestError("malformed query: %s", err)
+	}
+
+	params.ServiceNames = r.Form["service"]
+	if len(params.ServiceNames) == 0 {
+		return params, newBadRequestError("please provide at least one service name")
+	}
+	if v := r.FormValue("groupByOperation"); v != "" {
+		params.GroupByOperation, err = strconv.ParseBool(v)
+		if err != nil {
+			return params, newBadRequestError("malformed groupByOperation parameter: %s", v)
+		}
+	}
+	for _, spanKind := range r.Form["spanKind"] {
+		v, ok := metrics.SpanKind_value["SPAN_KIND_"+strings.ToUpper(spanKind)]
+		if !ok {
+			return params, newBadRequestError("unsupported span kind: '%s'", spanKind)
+		}
+		params.SpanKinds = append(params.SpanKinds, metrics.SpanKind(v).String())
+	}
+
+	endTs, err := parseUnixMillis(r, "endTs", time.Now())
+	if err != nil {
+		return params, err
+	}
+	params.EndTime = &endTs
+	for name, p := range map[string]**time.Duration{"lookback": &params.Lookback, "step": &params.Step, "ratePer": &params.RatePer} {
+		if r.FormValue(name) == "" {
+			continue
+		}
+		d, err := parseMillis(r, name, 0)
+		if err != nil {
+			return params, err
+		}
+		*p = &d
+	}
+
+	return params, nil
+}
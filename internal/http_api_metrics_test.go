@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2/metrics"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	metricsStoreMocks "github.com/jaegertracing/jaeger/storage/metricsstore/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHTTPHandlerMetrics(t *testing.T) {
+	endTs := time.UnixMilli(1704070861000)
+	step := 60 * time.Second
+	metricsReader := &metricsStoreMocks.Reader{}
+	metricsReader.On("GetCallRates", mock.Anything, &metricsstore.CallRateQueryParameters{BaseQueryParameters: metricsstore.BaseQueryParameters{
+		ServiceNames:     []string{"checkout", "frontend"},
+		GroupByOperation: true,
+		EndTime:          &endTs,
+		Step:             &step,
+		SpanKinds:        []string{"SPAN_KIND_SERVER", "SPAN_KIND_CLIENT"},
+	}}).Return(newMetricFamily("service_call_rate", "calls/sec, grouped by service", true), nil)
+	metricsReader.On("GetMinStepDuration", mock.Anything, mock.Anything).Return(time.Second, nil)
+
+	h := &HTTPHandler{
+		logger:        zap.NewNop(),
+		metricsReader: metricsReader,
+	}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, tc := range []struct {
+		url  string
+		body string
+	}{
+		{
+			"/api/metrics/calls?service=checkout&service=frontend&groupByOperation=true&endTs=1704070861000&step=60000&spanKind=server&spanKind=client",
+			`{"name":"service_operation_call_rate","type":"GAUGE","help":"calls/sec, grouped by service & operation","metrics":[]}`,
+		},
+		{"/api/metrics/minstep", `{"data":1000,"total":0,"limit":0,"offset":0,"errors":null}`},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		require.Equal(t, http.StatusOK, w.Code, tc.url)
+		require.JSONEq(t, tc.body, w.Body.String(), tc.url)
+	}
+
+	for _, url := range []string{
+		"/api/metrics/calls",
+		"/api/metrics/errors?service=checkout&spanKind=queue",
+		"/api/metrics/errors?service=checkout&step=-1",
+		"/api/metrics/latencies?service=checkout",
+		"/api/metrics/latencies?service=checkout&quantile=0.95&groupByOperation=maybe",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestBaseQueryParametersFromRequest(t *testing.T) {
+	_, err := baseQueryParametersFromRequest(&metrics.MetricsQueryBaseRequest{})
+	require.Error(t, err)
+
+	params, err := baseQueryParametersFromRequest(&metrics.MetricsQueryBaseRequest{
+		ServiceNames: []string{"checkout"},
+		SpanKinds:    []metrics.SpanKind{metrics.SpanKind_SPAN_KIND_CONSUMER},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"checkout"}, params.ServiceNames)
+	require.Equal(t, []string{"SPAN_KIND_CONSUMER"}, params.SpanKinds)
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultSearchLookback = time.Hour
+
+type searchResponse struct {
+	Traces []*traceSummary `json:"traces"`
+}
+
+// registerTraceQLRoutes registers the TraceQL search endpoint of Tempo.
+func (h *HTTPHandler) registerTraceQLRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/search", h.searchTraceQL)
+}
+
+// searchTraceQL takes the TraceQL query q, start and end in unix seconds (the last hour by default)
+// and limit, and returns summaries of the newest matching traces.
+func (h *HTTPHandler) searchTraceQL(w http.ResponseWriter, r *http.Request) {
+	q := r.FormValue("q")
+	if q == "" {
+		h.writeError(w, newBadRequestError("parameter 'q' is required"))
+		return
+	}
+	query, err := parseTraceQL(q)
+	if err != nil {
+		h.writeError(w, newBadRequestError("invalid TraceQL query: %s", err))
+		return
+	}
+
+	end, err := parseUnixSeconds(r, "end", time.Now())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	start, err := parseUnixSeconds(r, "start", end.Add(-defaultSearchLookback))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	limit := defaultHTTPQueryLimit
+	if v := r.FormValue("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			h.writeError(w, newBadRequestError("malformed limit parameter: %s", v))
+			return
+		}
+	}
+
+	traces, err := h.reader.SearchTraceQL(h.context(r), query, start, end, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, &searchResponse{Traces: traces})
+}
@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHTTPHandlerTraceQLSearch(t *testing.T) {
+	h := &HTTPHandler{logger: zap.NewNop()}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, tc := range []struct {
+		url  string
+		body string
+	}{
+		{"/api/search", "parameter 'q' is required\n"},
+		{"/api/search?q=%7B", "invalid TraceQL query: expected a field at position 1\n"},
+		{"/api/search?q=%7B%7D&start=yesterday", ""},
+		{"/api/search?q=%7B%7D&limit=0", "malformed limit parameter: 0\n"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, tc.url)
+		if tc.body != "" {
+			require.Equal(t, tc.body, w.Body.String(), tc.url)
+		}
+	}
+}
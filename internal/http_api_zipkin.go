@@ -0,0 +1,416 @@
+package internal
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/plugin/storage/grpc/shared"
+)
+
+const (
+	defaultZipkinQueryLimit = 10
+	defaultZipkinLookback   = 24 * time.Hour
+)
+
+// zipkinSpan is the span of the Zipkin API v2.
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ParentID       string             `json:"parentId,omitempty"`
+	ID             string             `json:"id"`
+	Kind           string             `json:"kind,omitempty"`
+	Name           string             `json:"name,omitempty"`
+	Timestamp      int64              `json:"timestamp,omitempty"`
+	Duration       int64              `json:"duration,omitempty"`
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint,omitempty"`
+	Annotations    []zipkinAnnotation `json:"annotations,omitempty"`
+	Tags           map[string]string  `json:"tags,omitempty"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        int64  `json:"port,omitempty"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+var zipkinSpanKinds = map[ptrace.SpanKind]string{
+	ptrace.SpanKindClient:   "CLIENT",
+	ptrace.SpanKindServer:   "SERVER",
+	ptrace.SpanKindProducer: "PRODUCER",
+	ptrace.SpanKindConsumer: "CONSUMER",
+}
+
+// The attributes of the endpoints, in order of preference, with the current and the older
+// OpenTelemetry semantic conventions.
+var (
+	zipkinLocalIPKeys    = []string{"network.local.address", "net.sock.host.addr", "net.host.ip"}
+	zipkinLocalPortKeys  = []string{"network.local.port", "net.host.port"}
+	zipkinRemoteNameKeys = []string{"peer.service", "server.address", "net.peer.name"}
+	zipkinRemoteIPKeys   = []string{"network.peer.address", "net.sock.peer.addr", "net.peer.ip", "peer.ipv4", "peer.ipv6"}
+	zipkinRemotePortKeys = []string{"network.peer.port", "server.port", "net.peer.port", "peer.port"}
+)
+
+// registerZipkinRoutes registers the read endpoints of the Zipkin API v2 used by the Zipkin UI.
+func (h *HTTPHandler) registerZipkinRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v2/services", h.getZipkinServices)
+	mux.HandleFunc("GET /api/v2/spans", h.getZipkinSpanNames)
+	mux.HandleFunc("GET /api/v2/remoteServices", h.getZipkinRemoteServices)
+	mux.HandleFunc("GET /api/v2/autocompleteKeys", h.getZipkinAutocompleteKeys)
+	mux.HandleFunc("GET /api/v2/traces", h.findZipkinTraces)
+	mux.HandleFunc("GET /api/v2/trace/{traceId}", h.getZipkinTrace)
+	mux.HandleFunc("GET /api/v2/dependencies", h.getZipkinDependencies)
+}
+
+func (h *HTTPHandler) getZipkinServices(w http.ResponseWriter, r *http.Request) {
+	services, err := h.reader.GetServices(h.context(r))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	sort.Strings(services)
+	h.writeJSON(w, services)
+}
+
+func (h *HTTPHandler) getZipkinSpanNames(w http.ResponseWriter, r *http.Request) {
+	service := r.FormValue("serviceName")
+	if service == "" {
+		h.writeError(w, newBadRequestError("parameter 'serviceName' is required"))
+		return
+	}
+
+	operations, err := h.reader.GetOperations(h.context(r), spanstore.OperationQueryParameters{
+		ServiceName: service,
+		SpanKind:    strings.ToLower(r.FormValue("spanKind")),
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	names := make([]string, 0, len(operations))
+	seen := make(map[string]struct{}, len(operations))
+	for _, operation := range operations {
+		if _, ok := seen[operation.Name]; ok {
+			continue
+		}
+		seen[operation.Name] = struct{}{}
+		names = append(names, operation.Name)
+	}
+	sort.Strings(names)
+	h.writeJSON(w, names)
+}
+
+// getZipkinRemoteServices returns the services called by serviceName according to the graph table.
+func (h *HTTPHandler) getZipkinRemoteServices(w http.ResponseWriter, r *http.Request) {
+	service := r.FormValue("serviceName")
+	if service == "" {
+		h.writeError(w, newBadRequestError("parameter 'serviceName' is required"))
+		return
+	}
+	err := checkServicePermission(h.context(r), service)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	dependencies, err := h.dependencyReader.GetDependencies(h.context(r), time.Now(), defaultZipkinLookback)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	services := make([]string, 0)
+	for _, dependency := range uiDependencies(dependencies, service) {
+		if dependency.Parent == service && dependency.Child != service {
+			services = append(services, dependency.Child)
+		}
+	}
+	sort.Strings(services)
+	h.writeJSON(w, services)
+}
+
+// getZipkinAutocompleteKeys returns no keys, the values of tags are not indexed for autocompletion.
+func (h *HTTPHandler) getZipkinAutocompleteKeys(w http.ResponseWriter, _ *http.Request) {
+	h.writeJSON(w, []string{})
+}
+
+func (h *HTTPHandler) findZipkinTraces(w http.ResponseWriter, r *http.Request) {
+	query, err := parseZipkinTraceQuery(r)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	traces := make([][]*zipkinSpan, 0)
+	err = h.reader.dr.streamOTLPTraces(h.context(r), query, h.reader.deniedSpans, func(td ptrace.Traces) error {
+		traces = append(traces, zipkinSpans(td))
+		return nil
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, traces)
+}
+
+func (h *HTTPHandler) getZipkinTrace(w http.ResponseWriter, r *http.Request) {
+	traceID, err := model.TraceIDFromString(r.PathValue("traceId"))
+	if err != nil {
+		h.writeError(w, newBadRequestError("cannot parse traceId: %s", err))
+		return
+	}
+
+	td, err := h.reader.dr.getOTLPTrace(h.context(r), shared.GetTraceParameters{TraceID: traceID}, h.reader.deniedSpans)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.writeJSON(w, zipkinSpans(td))
+}
+
+// getZipkinDependencies takes endTs and lookback in milliseconds, like getDependencies.
+func (h *HTTPHandler) getZipkinDependencies(w http.ResponseWriter, r *http.Request) {
+	endTs, err := parseUnixMillis(r, "endTs", time.Now())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	lookback, err := parseMillis(r, "lookback", defaultZipkinLookback)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	dependencies, err := h.dependencyReader.GetDependencies(h.context(r), endTs, lookback)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	// the links of the Jaeger UI have the fields of the Zipkin links, without the error count
+	h.writeJSON(w, uiDependencies(dependencies, ""))
+}
+
+// parseZipkinTraceQuery parses the parameters of /api/v2/traces: serviceName, spanName,
+// annotationQuery (e.g. "error and http.method=GET"), minDuration and maxDuration (microseconds),
+// endTs and lookback (milliseconds) and limit.
+func parseZipkinTraceQuery(r *http.Request) (*spanstore.TraceQueryParameters, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return nil, newBadRequestError("malformed query: %s", err)
+	}
+
+	query := &spanstore.TraceQueryParameters{
+		ServiceName:   r.FormValue("serviceName"),
+		OperationName: r.FormValue("spanName"),
+		Tags:          make(map[string]string),
+		NumTraces:     defaultZipkinQueryLimit,
+	}
+	if query.OperationName == "all" {
+		query.OperationName = ""
+	}
+
+	if annotationQuery := strings.TrimSpace(r.FormValue("annotationQuery")); annotationQuery != "" {
+		for _, term := range strings.Split(annotationQuery, " and ") {
+			k, v, ok := strings.Cut(strings.TrimSpace(term), "=")
+			switch {
+			case ok:
+				query.Tags[k] = v
+			case k == "error":
+				query.Tags[k] = "true"
+			default:
+				return nil, newBadRequestError("unsupported annotationQuery term without value: %s", term)
+			}
+		}
+	}
+
+	endTs, err := parseUnixMillis(r, "endTs", time.Now())
+	if err != nil {
+		return nil, err
+	}
+	lookback, err := parseMillis(r, "lookback", defaultZipkinLookback)
+	if err != nil {
+		return nil, err
+	}
+	query.StartTimeMin = endTs.Add(-lookback)
+	query.StartTimeMax = endTs
+
+	query.DurationMin, err = parseMicros(r, "minDuration")
+	if err != nil {
+		return nil, err
+	}
+	query.DurationMax, err = parseMicros(r, "maxDuration")
+	if err != nil {
+		return nil, err
+	}
+
+	if limit := r.FormValue("limit"); limit != "" {
+		query.NumTraces, err = strconv.Atoi(limit)
+		if err != nil || query.NumTraces <= 0 {
+			return nil, newBadRequestError("malformed limit parameter: %s", limit)
+		}
+	}
+
+	return query, nil
+}
+
+func parseMicros(r *http.Request, name string) (time.Duration, error) {
+	v := r.FormValue(name)
+	if v == "" {
+		return 0, nil
+	}
+	micros, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || micros < 0 {
+		return 0, newBadRequestError("malformed %s parameter: %s", name, v)
+	}
+	return time.Duration(micros) * time.Microsecond, nil
+}
+
+// zipkinSpans converts the spans of td. Resource and span attributes become tags, except the
+// ones which are mapped to the local and remote endpoints, and events become annotations.
+// Links have no equivalent in Zipkin and are dropped.
+func zipkinSpans(td ptrace.Traces) []*zipkinSpan {
+	spans := make([]*zipkinSpan, 0, td.SpanCount())
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		resourceSpans := td.ResourceSpans().At(i)
+		resource := resourceSpans.Resource().Attributes()
+		serviceName := ""
+		if v, ok := resource.Get(resourceKeyServiceName); ok {
+			serviceName = v.AsString()
+		}
+
+		for j := 0; j < resourceSpans.ScopeSpans().Len(); j++ {
+			scopeSpans := resourceSpans.ScopeSpans().At(j)
+			for k := 0; k < scopeSpans.Spans().Len(); k++ {
+				span := zipkinSpanFromOTLP(scopeSpans.Spans().At(k), serviceName, resource)
+				if name := scopeSpans.Scope().Name(); name != "" {
+					span.Tags["otel.scope.name"] = name
+				}
+				if version := scopeSpans.Scope().Version(); version != "" {
+					span.Tags["otel.scope.version"] = version
+				}
+				if len(span.Tags) == 0 {
+					span.Tags = nil
+				}
+				spans = append(spans, span)
+			}
+		}
+	}
+	return spans
+}
+
+func zipkinSpanFromOTLP(span ptrace.Span, serviceName string, resource pcommon.Map) *zipkinSpan {
+	startTime := span.StartTimestamp().AsTime()
+	result := &zipkinSpan{
+		TraceID:   modelTraceID(span.TraceID()).String(),
+		ID:        span.SpanID().String(),
+		Kind:      zipkinSpanKinds[span.Kind()],
+		Name:      span.Name(),
+		Timestamp: startTime.UnixMicro(),
+		Duration:  span.EndTimestamp().AsTime().Sub(startTime).Microseconds(),
+		Tags:      make(map[string]string, resource.Len()+span.Attributes().Len()),
+	}
+	if !span.ParentSpanID().IsEmpty() {
+		result.ParentID = span.ParentSpanID().String()
+	}
+
+	resource.Range(func(k string, v pcommon.Value) bool {
+		result.Tags[k] = v.AsString()
+		return true
+	})
+	delete(result.Tags, resourceKeyServiceName)
+	span.Attributes().Range(func(k string, v pcommon.Value) bool {
+		result.Tags[k] = v.AsString()
+		return true
+	})
+
+	local := &zipkinEndpoint{ServiceName: serviceName}
+	setZipkinEndpointIP(local, takeZipkinTag(result.Tags, zipkinLocalIPKeys))
+	local.Port = zipkinPort(takeZipkinTag(result.Tags, zipkinLocalPortKeys))
+	result.LocalEndpoint = local
+
+	remote := &zipkinEndpoint{ServiceName: takeZipkinTag(result.Tags, zipkinRemoteNameKeys)}
+	setZipkinEndpointIP(remote, takeZipkinTag(result.Tags, zipkinRemoteIPKeys))
+	remote.Port = zipkinPort(takeZipkinTag(result.Tags, zipkinRemotePortKeys))
+	if *remote != (zipkinEndpoint{}) {
+		result.RemoteEndpoint = remote
+	}
+
+	switch span.Status().Code() {
+	case ptrace.StatusCodeError:
+		result.Tags["otel.status_code"] = "ERROR"
+		result.Tags["error"] = span.Status().Message()
+		if result.Tags["error"] == "" {
+			result.Tags["error"] = "true"
+		}
+	case ptrace.StatusCodeOk:
+		result.Tags["otel.status_code"] = "OK"
+	}
+
+	for i := 0; i < span.Events().Len(); i++ {
+		event := span.Events().At(i)
+		value := event.Name()
+		if event.Attributes().Len() > 0 {
+			attributes, err := json.Marshal(event.Attributes().AsRaw())
+			if err == nil {
+				value += ": " + string(attributes)
+			}
+		}
+		result.Annotations = append(result.Annotations, zipkinAnnotation{
+			Timestamp: event.Timestamp().AsTime().UnixMicro(),
+			Value:     value,
+		})
+	}
+
+	return result
+}
+
+// takeZipkinTag removes the tags of keys, and returns the value of the first one which is set.
+func takeZipkinTag(tags map[string]string, keys []string) string {
+	result := ""
+	for _, k := range keys {
+		v, ok := tags[k]
+		if !ok {
+			continue
+		}
+		delete(tags, k)
+		if result == "" {
+			result = v
+		}
+	}
+	return result
+}
+
+func setZipkinEndpointIP(endpoint *zipkinEndpoint, v string) {
+	ip := net.ParseIP(v)
+	switch {
+	case ip == nil:
+	case ip.To4() != nil:
+		endpoint.IPv4 = ip.String()
+	default:
+		endpoint.IPv6 = ip.String()
+	}
+}
+
+func zipkinPort(v string) int64 {
+	port, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || port <= 0 || port > 65535 {
+		return 0
+	}
+	return port
+}
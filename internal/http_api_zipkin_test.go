@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	dependencyStoreMocks "github.com/jaegertracing/jaeger/storage/dependencystore/mocks"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanStoreMocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHTTPHandlerZipkinAPI(t *testing.T) {
+	spanReader := &spanStoreMocks.Reader{}
+	spanReader.On("GetServices", mock.Anything).Return([]string{"frontend", "checkout"}, nil)
+	spanReader.On("GetOperations", mock.Anything, spanstore.OperationQueryParameters{ServiceName: "checkout", SpanKind: "server"}).
+		Return([]spanstore.Operation{{Name: "POST", SpanKind: "server"}, {Name: "GET", SpanKind: "server"}}, nil)
+	depsReader := &dependencyStoreMocks.Reader{}
+	depsReader.On("GetDependencies", mock.Anything, time.UnixMilli(1704070861000), time.Hour).
+		Return([]model.DependencyLink{
+			{Parent: "frontend", Child: "checkout", CallCount: 3},
+			{Parent: "frontend", Child: "checkout", CallCount: 2},
+		}, nil)
+	depsReader.On("GetDependencies", mock.Anything, mock.Anything, defaultZipkinLookback).
+		Return([]model.DependencyLink{
+			{Parent: "frontend", Child: "checkout", CallCount: 3},
+			{Parent: "frontend", Child: "auth", CallCount: 1},
+			{Parent: "checkout", Child: "db", CallCount: 1},
+		}, nil)
+
+	reader := newAccessControlledReader(spanReader, nil, depsReader, AccessDeniedSpansStrip)
+	h := &HTTPHandler{
+		logger:           zap.NewNop(),
+		reader:           reader,
+		dependencyReader: reader,
+		zipkin:           true,
+	}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, tc := range []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/api/v2/services", http.StatusOK, `["checkout","frontend"]`},
+		{"/api/v2/spans?serviceName=checkout&spanKind=SERVER", http.StatusOK, `["GET","POST"]`},
+		{"/api/v2/remoteServices?serviceName=frontend", http.StatusOK, `["auth","checkout"]`},
+		{"/api/v2/autocompleteKeys", http.StatusOK, `[]`},
+		{"/api/v2/dependencies?endTs=1704070861000&lookback=3600000", http.StatusOK, `[{"parent":"frontend","child":"checkout","callCount":5}]`},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		require.Equal(t, tc.code, w.Code, tc.url)
+		require.JSONEq(t, tc.body, w.Body.String(), tc.url)
+	}
+
+	for _, url := range []string{
+		"/api/v2/spans",
+		"/api/v2/trace/xyz",
+		"/api/v2/traces?annotationQuery=http.method",
+		"/api/v2/traces?minDuration=1s",
+		"/api/v2/dependencies?lookback=-1",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestParseZipkinTraceQuery(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v2/traces?serviceName=frontend&spanName=all"+
+		"&annotationQuery=error+and+http.method%3DGET&minDuration=1500&endTs=1704070861000&lookback=3600000&limit=5", nil)
+	query, err := parseZipkinTraceQuery(r)
+	require.NoError(t, err)
+	require.Equal(t, &spanstore.TraceQueryParameters{
+		ServiceName:  "frontend",
+		Tags:         map[string]string{"error": "true", "http.method": "GET"},
+		StartTimeMin: time.UnixMilli(1704067261000),
+		StartTimeMax: time.UnixMilli(1704070861000),
+		DurationMin:  1500 * time.Microsecond,
+		NumTraces:    5,
+	}, query)
+}
+
+func TestZipkinSpans(t *testing.T) {
+	cfg := newOTLPTestConfig()
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	record := newOTLPTestRecord("frontend", "0102030100000002")
+	record["span_attributes"] = `{"http.method":"GET","peer.service":"checkout","network.peer.address":"::1","server.port":8080,"net.peer.port":80,"net.host.ip":"10.0.0.1"}`
+	record["trace_id"] = "00000000000000000000000000000001"
+	builder := newOTLPTraceBuilder(ctx, cfg, nil, "")
+	require.NoError(t, builder.Add(ctx, record))
+
+	spans := zipkinSpans(builder.Traces())
+	require.Equal(t, []*zipkinSpan{{
+		TraceID:        "0000000000000001",
+		ParentID:       "0102030100000000",
+		ID:             "0102030100000002",
+		Kind:           "CLIENT",
+		Name:           "test-operation",
+		Timestamp:      1704070861000001,
+		Duration:       1000,
+		LocalEndpoint:  &zipkinEndpoint{ServiceName: "frontend", IPv4: "10.0.0.1"},
+		RemoteEndpoint: &zipkinEndpoint{ServiceName: "checkout", IPv6: "::1", Port: 8080},
+		Annotations:    []zipkinAnnotation{{Timestamp: 1704070861000002, Value: `retry: {"attempt":2}`}},
+		Tags: map[string]string{
+			"http.method":         "GET",
+			"host.name":           "localhost",
+			"service.instance.id": "instance-1",
+			"otel.scope.name":     "test-scope",
+			"otel.scope.version":  "1.0.0",
+			"otel.status_code":    "ERROR",
+			"error":               "timeout",
+		},
+	}}, spans)
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"go.uber.org/zap"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/plugin/storage/grpc/shared"
+)
+
+const (
+	// maxLogsPerTrace limits the log records read for a trace.
+	maxLogsPerTrace = 10000
+	// logsTimeSlack widens the time range of the spans of a trace when reading its log records,
+	// which are written while the spans run.
+	logsTimeSlack = 10 * time.Minute
+
+	LogFieldKeyLevel   = "level"
+	LogFieldKeyMessage = "message"
+	SpanLogEventLog    = "log"
+)
+
+// logRecord is a record of the logs table, converted to a jaeger log.
+type logRecord struct {
+	ServiceName string
+	SpanID      string // empty for records without a span
+	Log         model.Log
+}
+
+// getLogs returns the log records of a trace, or of a span if spanID is set, redacted like the spans of their service.
+func (dr *dorisReader) getLogs(ctx context.Context, traceID string, spanID string, startTime, endTime time.Time, limit int) ([]*logRecord, error) {
+	records := make([]*logRecord, 0)
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		lr, err := recordToLog(ctx, cfg, record)
+		if err != nil {
+			dr.logger.Warn("Failed to convert record to log", zap.Error(err))
+			return nil
+		}
+		// the redaction rules apply to the log fields of the spans of the service
+		redacted := dr.redactor.Redact(ctx, &model.Span{Process: &model.Process{ServiceName: lr.ServiceName}, Logs: []model.Log{lr.Log}})
+		lr.Log = redacted.Logs[0]
+		records = append(records, lr)
+		return nil
+	}
+
+	schema := dr.cfg.Doris.LogsSchemaMapping
+	sql := queryGetLogs(schema, dr.cfg.Doris.LogsTableFullName(), traceID, spanID, startTime, endTime, dr.cfg.Doris.Location, limit)
+	err := executeQuery(ctx, dr.db, dr.cfg, sql, f)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// getSpanLogs returns the log records of the trace by span ID if they are merged into the spans,
+// and nil otherwise. The time range of the trace is resolved into query, so that it is only
+// looked up once.
+func (dr *dorisReader) getSpanLogs(ctx context.Context, query *shared.GetTraceParameters) (map[model.SpanID][]model.Log, error) {
+	if !dr.cfg.Doris.MergeLogs {
+		return nil, nil
+	}
+
+	traceID := traceIDToString(query.TraceID)
+	if query.StartTime.IsZero() && query.EndTime.IsZero() && dr.cfg.Doris.TraceIndexTable != "" {
+		var err error
+		query.StartTime, query.EndTime, err = dr.getTraceTimeRange(ctx, traceID)
+		if err != nil {
+			dr.logger.Warn("Failed to look up the time range of the trace", zap.Error(err))
+		}
+	}
+	startTime, endTime := query.StartTime, query.EndTime
+	if !startTime.IsZero() {
+		startTime = startTime.Add(-logsTimeSlack)
+	}
+	if !endTime.IsZero() {
+		endTime = endTime.Add(logsTimeSlack)
+	}
+
+	records, err := dr.getLogs(ctx, traceID, "", startTime, endTime, maxLogsPerTrace)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make(map[model.SpanID][]model.Log)
+	for _, lr := range records {
+		if lr.SpanID == "" {
+			continue
+		}
+		spanID, err := model.SpanIDFromString(lr.SpanID)
+		if err != nil {
+			dr.logger.Warn("Failed to parse span ID of log", zap.Error(err))
+			continue
+		}
+		logs[spanID] = append(logs[spanID], lr.Log)
+	}
+	return logs, nil
+}
+
+// mergeLogs adds the log records to the logs of the span, ordered by time.
+func mergeLogs(span *model.Span, logs map[model.SpanID][]model.Log) {
+	records, ok := logs[span.SpanID]
+	if !ok {
+		return
+	}
+	span.Logs = append(span.Logs, records...)
+	sort.SliceStable(span.Logs, func(i, j int) bool {
+		return span.Logs[i].Timestamp.Before(span.Logs[j].Timestamp)
+	})
+}
+
+func recordToLog(ctx context.Context, cfg *Config, record map[string]string) (*logRecord, error) {
+	logger := LoggerFromContext(ctx)
+	schema := cfg.Doris.LogsSchemaMapping
+
+	timestamp, err := time.ParseInLocation(timeFormat, record[schema.Timestamp], cfg.Doris.Location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	fields := []model.KeyValue{model.String(SpanLogFieldKeyEvent, SpanLogEventLog)}
+	if level := record[schema.SeverityText]; level != "" {
+		fields = append(fields, model.String(LogFieldKeyLevel, level))
+	}
+	fields = append(fields, model.String(LogFieldKeyMessage, record[schema.Body]))
+
+	if attributesString := record[schema.LogAttributes]; attributesString != "" {
+		attributes := map[string]any{}
+		err = unmarshalUseNumber(attributesString, &attributes)
+		if err != nil {
+			logger.Warn("failed to unmarshal log attributes", zap.Error(err))
+		}
+		for k, v := range attributes {
+			fields = append(fields, kvToKeyValue(k, v))
+		}
+	}
+
+	return &logRecord{
+		ServiceName: record[schema.ServiceName],
+		SpanID:      record[schema.SpanID],
+		Log:         model.Log{Timestamp: timestamp, Fields: fields},
+	}, nil
+}
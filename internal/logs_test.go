@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRecordToLog(t *testing.T) {
+	schema := &LogsSchemaMapping{}
+	schema.FillDefaultValues()
+	cfg := &Config{Doris: &DorisConfig{LogsSchemaMapping: schema, Location: time.UTC}}
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	lr, err := recordToLog(ctx, cfg, map[string]string{
+		"service_name":   "checkout",
+		"timestamp":      "2024-01-01 01:01:01.000001",
+		"trace_id":       "01020301000000000000000000000000",
+		"span_id":        "0102030100000000",
+		"severity_text":  "ERROR",
+		"body":           "payment declined",
+		"log_attributes": `{"retry":1}`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "checkout", lr.ServiceName)
+	require.Equal(t, "0102030100000000", lr.SpanID)
+	require.Equal(t, time.Date(2024, 1, 1, 1, 1, 1, 1000, time.UTC), lr.Log.Timestamp)
+	require.Equal(t, []model.KeyValue{
+		model.String(SpanLogFieldKeyEvent, SpanLogEventLog),
+		model.String(LogFieldKeyLevel, "ERROR"),
+		model.String(LogFieldKeyMessage, "payment declined"),
+		model.Int64("retry", 1),
+	}, lr.Log.Fields)
+
+	_, err = recordToLog(ctx, cfg, map[string]string{"timestamp": "yesterday"})
+	require.Error(t, err)
+}
+
+func TestMergeLogs(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC)
+	span := &model.Span{
+		SpanID: model.NewSpanID(1),
+		Logs:   []model.Log{{Timestamp: ts.Add(time.Second)}},
+	}
+	mergeLogs(span, map[model.SpanID][]model.Log{
+		model.NewSpanID(1): {{Timestamp: ts}, {Timestamp: ts.Add(2 * time.Second)}},
+		model.NewSpanID(2): {{Timestamp: ts}},
+	})
+	require.Equal(t, []model.Log{{Timestamp: ts}, {Timestamp: ts.Add(time.Second)}, {Timestamp: ts.Add(2 * time.Second)}}, span.Logs)
+
+	mergeLogs(span, nil)
+	require.Len(t, span.Logs, 3)
+}
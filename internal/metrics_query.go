@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2/metrics"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ metrics.MetricsQueryServiceServer = (*MetricsQueryService)(nil)
+
+// MetricsQueryService serves the metrics query service of jaeger-query with the metrics of dorisMetricsReader.
+type MetricsQueryService struct {
+	logger *zap.Logger
+	reader metricsstore.Reader
+}
+
+func NewMetricsQueryService(logger *zap.Logger, ds *DorisStorage) *MetricsQueryService {
+	return &MetricsQueryService{
+		logger: logger,
+		reader: ds.MetricsReader(),
+	}
+}
+
+func (ms *MetricsQueryService) Register(s *grpc.Server) {
+	metrics.RegisterMetricsQueryServiceServer(s, ms)
+}
+
+func (ms *MetricsQueryService) GetLatencies(ctx context.Context, request *metrics.GetLatenciesRequest) (*metrics.GetMetricsResponse, error) {
+	params, err := baseQueryParametersFromRequest(request.GetBaseRequest())
+	if err != nil {
+		return nil, err
+	}
+	m, err := ms.reader.GetLatencies(LoggerWithContext(ctx, ms.logger), &metricsstore.LatenciesQueryParameters{
+		BaseQueryParameters: params,
+		Quantile:            request.GetQuantile(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &metrics.GetMetricsResponse{Metrics: *m}, nil
+}
+
+func (ms *MetricsQueryService) GetCallRates(ctx context.Context, request *metrics.GetCallRatesRequest) (*metrics.GetMetricsResponse, error) {
+	params, err := baseQueryParametersFromRequest(request.GetBaseRequest())
+	if err != nil {
+		return nil, err
+	}
+	m, err := ms.reader.GetCallRates(LoggerWithContext(ctx, ms.logger), &metricsstore.CallRateQueryParameters{
+		BaseQueryParameters: params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &metrics.GetMetricsResponse{Metrics: *m}, nil
+}
+
+func (ms *MetricsQueryService) GetErrorRates(ctx context.Context, request *metrics.GetErrorRatesRequest) (*metrics.GetMetricsResponse, error) {
+	params, err := baseQueryParametersFromRequest(request.GetBaseRequest())
+	if err != nil {
+		return nil, err
+	}
+	m, err := ms.reader.GetErrorRates(LoggerWithContext(ctx, ms.logger), &metricsstore.ErrorRateQueryParameters{
+		BaseQueryParameters: params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &metrics.GetMetricsResponse{Metrics: *m}, nil
+}
+
+func (ms *MetricsQueryService) GetMinStepDuration(ctx context.Context, _ *metrics.GetMinStepDurationRequest) (*metrics.GetMinStepDurationResponse, error) {
+	minStep, err := ms.reader.GetMinStepDuration(ctx, &metricsstore.MinStepDurationQueryParameters{})
+	if err != nil {
+		return nil, err
+	}
+	return &metrics.GetMinStepDurationResponse{MinStep: minStep}, nil
+}
+
+// baseQueryParametersFromRequest converts the request, unset parameters get the defaults of dorisMetricsReader.
+func baseQueryParametersFromRequest(request *metrics.MetricsQueryBaseRequest) (metricsstore.BaseQueryParameters, error) {
+	if request == nil || len(request.ServiceNames) == 0 {
+		return metricsstore.BaseQueryParameters{}, status.Error(codes.InvalidArgument, "please provide at least one service name")
+	}
+
+	params := metricsstore.BaseQueryParameters{
+		ServiceNames:     request.ServiceNames,
+		GroupByOperation: request.GroupByOperation,
+		EndTime:          request.EndTime,
+		Lookback:         request.Lookback,
+		Step:             request.Step,
+		RatePer:          request.RatePer,
+	}
+	for _, spanKind := range request.SpanKinds {
+		params.SpanKinds = append(params.SpanKinds, spanKind.String())
+	}
+	return params, nil
+}
@@ -0,0 +1,355 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2/metrics"
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ metricsstore.Reader = (*dorisMetricsReader)(nil)
+
+// The defaults of jaeger-query for the parameters of metrics queries.
+var (
+	defaultMetricsLookback  = time.Hour
+	defaultMetricsStep      = 5 * time.Second
+	defaultMetricsRatePer   = 10 * time.Minute
+	defaultMetricsSpanKinds = []string{metrics.SpanKind_SPAN_KIND_SERVER.String()}
+)
+
+// minMetricsStep is the resolution of the buckets, the timestamps are compared in seconds.
+const minMetricsStep = time.Second
+
+// maxMetricsPoints limits the points of a query, and the buckets of its ratePer, like the maximum
+// resolution of Prometheus range queries.
+const maxMetricsPoints = 11000
+
+// dorisMetricsReader computes the RED metrics of the Monitor tab of the Jaeger UI from the span table,
+// like the Prometheus reader computes them from the metrics of the spanmetrics connector. Spans are
+// counted in buckets of step, rates are computed over the buckets of the last ratePer like rate() and
+// latencies are the quantile of the durations of the spans of the buckets of the last ratePer.
+type dorisMetricsReader struct {
+	logger *zap.Logger
+	dr     *dorisReader
+}
+
+// metricsSeries are the buckets of a service or an operation, by index since the start of the range.
+type metricsSeries struct {
+	service   string
+	operation string
+	buckets   map[int64]*metricsBucket
+}
+
+// metricsBucket holds the spans of a bucket, or the quantile of the durations of a point.
+type metricsBucket struct {
+	calls    int64
+	errors   int64
+	quantile float64
+}
+
+// metricsSeriesIndex collects the series of the rows of a query.
+type metricsSeriesIndex struct {
+	index  map[[2]string]*metricsSeries
+	series []*metricsSeries
+}
+
+// metricsRange are the points of a query: count points from start to end, step apart. Rates are
+// computed over window buckets.
+type metricsRange struct {
+	start  time.Time
+	end    time.Time
+	step   time.Duration
+	count  int64
+	window int64
+}
+
+func (mr *dorisMetricsReader) GetLatencies(ctx context.Context, params *metricsstore.LatenciesQueryParameters) (*metrics.MetricFamily, error) {
+	if params.Quantile < 0 || params.Quantile > 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "quantile must be between 0 and 1, got %v", params.Quantile)
+	}
+	r, spanKinds, err := mr.prepare(ctx, &params.BaseQueryParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := mr.dr.cfg
+	schema := cfg.Doris.SchemaMapping
+	sql := queryLatencies(schema, cfg.Doris.TableFullName(), params.ServiceNames, spanKinds, params.GroupByOperation,
+		r.start, r.at(1-r.window), r.end, r.step, r.window, r.count, params.Quantile, cfg.Doris.Location)
+
+	series := &metricsSeriesIndex{}
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		p, err := strconv.ParseFloat(record["p"], 64)
+		if err != nil {
+			mr.logger.Warn("Failed to parse point of latencies", zap.Error(err))
+			return nil
+		}
+		quantile, _ := strconv.ParseFloat(record["q"], 64)
+		series.get(record[schema.ServiceName], record[schema.SpanName]).buckets[int64(p)] = &metricsBucket{quantile: quantile}
+		return nil
+	}
+	err = executeQuery(ctx, mr.dr.db, cfg, sql, f)
+	if err != nil {
+		return nil, err
+	}
+
+	family := newMetricFamily("service_latencies", fmt.Sprintf("%.2fth quantile latency, grouped by service", params.Quantile), params.GroupByOperation)
+	for _, s := range series.sorted() {
+		points := make([]*metrics.MetricPoint, 0, len(s.buckets))
+		for i := int64(0); i < r.count; i++ {
+			bucket, ok := s.buckets[i]
+			if !ok {
+				continue
+			}
+			// durations are stored in microseconds, the Jaeger UI shows milliseconds
+			points = append(points, newMetricPoint(r.at(i), bucket.quantile/1000))
+		}
+		family.Metrics = appendMetric(family.Metrics, s, params.GroupByOperation, points)
+	}
+	return family, nil
+}
+
+func (mr *dorisMetricsReader) GetCallRates(ctx context.Context, params *metricsstore.CallRateQueryParameters) (*metrics.MetricFamily, error) {
+	series, r, err := mr.aggregate(ctx, &params.BaseQueryParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	family := newMetricFamily("service_call_rate", "calls/sec, grouped by service", params.GroupByOperation)
+	for _, s := range series {
+		calls, _ := r.sums(s)
+		points := make([]*metrics.MetricPoint, 0)
+		for i := int64(0); i < r.count; i++ {
+			if calls[i] == 0 {
+				continue
+			}
+			points = append(points, newMetricPoint(r.at(i), float64(calls[i])/(float64(r.window)*r.step.Seconds())))
+		}
+		family.Metrics = appendMetric(family.Metrics, s, params.GroupByOperation, points)
+	}
+	return family, nil
+}
+
+func (mr *dorisMetricsReader) GetErrorRates(ctx context.Context, params *metricsstore.ErrorRateQueryParameters) (*metrics.MetricFamily, error) {
+	series, r, err := mr.aggregate(ctx, &params.BaseQueryParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	family := newMetricFamily("service_error_rate", "error rate, computed as a fraction of errors/sec over calls/sec, grouped by service", params.GroupByOperation)
+	for _, s := range series {
+		calls, errors := r.sums(s)
+		points := make([]*metrics.MetricPoint, 0)
+		for i := int64(0); i < r.count; i++ {
+			if calls[i] == 0 {
+				continue
+			}
+			points = append(points, newMetricPoint(r.at(i), float64(errors[i])/float64(calls[i])))
+		}
+		family.Metrics = appendMetric(family.Metrics, s, params.GroupByOperation, points)
+	}
+	return family, nil
+}
+
+func (*dorisMetricsReader) GetMinStepDuration(context.Context, *metricsstore.MinStepDurationQueryParameters) (time.Duration, error) {
+	return minMetricsStep, nil
+}
+
+// prepare checks the services of params, and returns their points and the stored values of their span kinds.
+func (mr *dorisMetricsReader) prepare(ctx context.Context, params *metricsstore.BaseQueryParameters) (*metricsRange, []string, error) {
+	if len(params.ServiceNames) == 0 {
+		return nil, nil, status.Error(codes.InvalidArgument, "please provide at least one service name")
+	}
+	for _, service := range params.ServiceNames {
+		err := checkServicePermission(ctx, service)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	r, err := newMetricsRange(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schema := mr.dr.cfg.Doris.SchemaMapping
+	otelSpanKinds := params.SpanKinds
+	if len(otelSpanKinds) == 0 {
+		otelSpanKinds = defaultMetricsSpanKinds
+	}
+	spanKinds := make([]string, 0, len(otelSpanKinds))
+	for _, spanKind := range otelSpanKinds {
+		spanKinds = append(spanKinds, schema.spanKindValue(spanKind))
+	}
+	return r, spanKinds, nil
+}
+
+// aggregate counts the spans of the services in the buckets of params.
+func (mr *dorisMetricsReader) aggregate(ctx context.Context, params *metricsstore.BaseQueryParameters) ([]*metricsSeries, *metricsRange, error) {
+	r, spanKinds, err := mr.prepare(ctx, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := mr.dr.cfg
+	schema := cfg.Doris.SchemaMapping
+	// the first points count the buckets before the start
+	sql := queryMetrics(schema, cfg.Doris.TableFullName(), params.ServiceNames, spanKinds, params.GroupByOperation,
+		r.start, r.at(1-r.window), r.end, r.step, cfg.Doris.Location)
+
+	series := &metricsSeriesIndex{}
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		// FLOOR may return a DOUBLE
+		b, err := strconv.ParseFloat(record["b"], 64)
+		if err != nil {
+			mr.logger.Warn("Failed to parse bucket of metrics", zap.Error(err))
+			return nil
+		}
+		bucket := &metricsBucket{}
+		bucket.calls, _ = strconv.ParseInt(record["c"], 10, 64)
+		bucket.errors, _ = strconv.ParseInt(record["e"], 10, 64)
+		series.get(record[schema.ServiceName], record[schema.SpanName]).buckets[int64(b)] = bucket
+		return nil
+	}
+
+	err = executeQuery(ctx, mr.dr.db, cfg, sql, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return series.sorted(), r, nil
+}
+
+func (x *metricsSeriesIndex) get(service string, operation string) *metricsSeries {
+	key := [2]string{service, operation}
+	s, ok := x.index[key]
+	if !ok {
+		if x.index == nil {
+			x.index = make(map[[2]string]*metricsSeries)
+		}
+		s = &metricsSeries{service: service, operation: operation, buckets: make(map[int64]*metricsBucket)}
+		x.index[key] = s
+		x.series = append(x.series, s)
+	}
+	return s
+}
+
+// sorted returns the series by service and operation.
+func (x *metricsSeriesIndex) sorted() []*metricsSeries {
+	sort.Slice(x.series, func(i, j int) bool {
+		if x.series[i].service != x.series[j].service {
+			return x.series[i].service < x.series[j].service
+		}
+		return x.series[i].operation < x.series[j].operation
+	})
+	return x.series
+}
+
+// newMetricsRange returns the points of params, with the defaults of jaeger-query for unset parameters.
+// The step is rounded up to whole seconds, and ratePer to whole steps. Queries of more than
+// maxMetricsPoints points, or ratePer of more than maxMetricsPoints steps, are rejected.
+func newMetricsRange(params *metricsstore.BaseQueryParameters) (*metricsRange, error) {
+	end := time.Now()
+	if params.EndTime != nil {
+		end = *params.EndTime
+	}
+	lookback := defaultMetricsLookback
+	if params.Lookback != nil {
+		lookback = *params.Lookback
+	}
+	step := defaultMetricsStep
+	if params.Step != nil {
+		step = *params.Step
+	}
+	ratePer := defaultMetricsRatePer
+	if params.RatePer != nil {
+		ratePer = *params.RatePer
+	}
+
+	step = max(minMetricsStep, (step + minMetricsStep - 1).Truncate(minMetricsStep))
+	r := &metricsRange{
+		start:  end.Add(-lookback),
+		end:    end,
+		step:   step,
+		count:  int64(lookback/step) + 1,
+		window: max(1, int64(math.Ceil(float64(ratePer)/float64(step)))),
+	}
+	if r.count > maxMetricsPoints {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"exceeded maximum resolution of %d points per timeseries, got %d: increase the step", maxMetricsPoints, r.count)
+	}
+	if r.window > maxMetricsPoints {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"exceeded maximum of %d steps per ratePer, got %d: increase the step or decrease ratePer", maxMetricsPoints, r.window)
+	}
+	return r, nil
+}
+
+func (r *metricsRange) at(i int64) time.Time {
+	return r.start.Add(time.Duration(i) * r.step)
+}
+
+// sums returns the calls and errors of the window of each point.
+func (r *metricsRange) sums(s *metricsSeries) ([]int64, []int64) {
+	calls := make([]int64, r.count)
+	errors := make([]int64, r.count)
+	var c, e int64
+	for i := 1 - r.window; i < r.count; i++ {
+		if bucket, ok := s.buckets[i]; ok {
+			c += bucket.calls
+			e += bucket.errors
+		}
+		// the bucket which left the window
+		if bucket, ok := s.buckets[i-r.window]; ok {
+			c -= bucket.calls
+			e -= bucket.errors
+		}
+		if i >= 0 {
+			calls[i], errors[i] = c, e
+		}
+	}
+	return calls, errors
+}
+
+// newMetricFamily names the family like the Prometheus reader of jaeger.
+func newMetricFamily(name string, help string, groupByOperation bool) *metrics.MetricFamily {
+	if groupByOperation {
+		name = "service_operation" + name[len("service"):]
+		help += " & operation"
+	}
+	return &metrics.MetricFamily{
+		Name:    name,
+		Type:    metrics.MetricType_GAUGE,
+		Help:    help,
+		Metrics: make([]*metrics.Metric, 0),
+	}
+}
+
+func appendMetric(result []*metrics.Metric, s *metricsSeries, groupByOperation bool, points []*metrics.MetricPoint) []*metrics.Metric {
+	if len(points) == 0 {
+		return result
+	}
+	labels := []*metrics.Label{{Name: "service_name", Value: s.service}}
+	if groupByOperation {
+		labels = append(labels, &metrics.Label{Name: "operation", Value: s.operation})
+	}
+	return append(result, &metrics.Metric{Labels: labels, MetricPoints: points})
+}
+
+func newMetricPoint(t time.Time, value float64) *metrics.MetricPoint {
+	timestamp, _ := types.TimestampProto(t)
+	return &metrics.MetricPoint{
+		Timestamp: timestamp,
+		Value: &metrics.MetricPoint_GaugeValue{
+			GaugeValue: &metrics.GaugeValue{Value: &metrics.GaugeValue_DoubleValue{DoubleValue: value}},
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/jaegertracing/jaeger/storage/metricsstore"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewMetricsRange(t *testing.T) {
+	end := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	lookback := time.Minute
+	step := 1500 * time.Millisecond
+	ratePer := 10 * time.Second
+	r, err := newMetricsRange(&metricsstore.BaseQueryParameters{EndTime: &end, Lookback: &lookback, Step: &step, RatePer: &ratePer})
+	require.NoError(t, err)
+
+	require.Equal(t, end.Add(-time.Minute), r.start)
+	require.Equal(t, 2*time.Second, r.step)
+	require.Equal(t, int64(31), r.count)
+	require.Equal(t, int64(5), r.window)
+	require.Equal(t, end, r.at(r.count-1))
+
+	r, err = newMetricsRange(&metricsstore.BaseQueryParameters{EndTime: &end})
+	require.NoError(t, err)
+	require.Equal(t, end.Add(-defaultMetricsLookback), r.start)
+	require.Equal(t, defaultMetricsStep, r.step)
+	require.Equal(t, int64(120), r.window)
+}
+
+func TestNewMetricsRangeMaxPoints(t *testing.T) {
+	end := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	step := time.Second
+	lookback := maxMetricsPoints * step
+	_, err := newMetricsRange(&metricsstore.BaseQueryParameters{EndTime: &end, Lookback: &lookback, Step: &step})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	lookback = (maxMetricsPoints - 1) * step
+	r, err := newMetricsRange(&metricsstore.BaseQueryParameters{EndTime: &end, Lookback: &lookback, Step: &step})
+	require.NoError(t, err)
+	require.Equal(t, int64(maxMetricsPoints), r.count)
+
+	ratePer := 24 * time.Hour
+	_, err = newMetricsRange(&metricsstore.BaseQueryParameters{EndTime: &end, Step: &step, RatePer: &ratePer})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetricsRangeSums(t *testing.T) {
+	r := &metricsRange{count: 6, window: 3}
+	s := &metricsSeries{buckets: map[int64]*metricsBucket{
+		-1: {calls: 1},
+		0:  {calls: 2, errors: 1},
+		2:  {calls: 4, errors: 2},
+	}}
+
+	calls, errors := r.sums(s)
+	require.Equal(t, []int64{3, 3, 6, 4, 4, 0}, calls)
+	require.Equal(t, []int64{1, 1, 3, 2, 2, 0}, errors)
+}
+
+func TestGetLatencies(t *testing.T) {
+	cfg := &Config{}
+	require.NoError(t, cfg.Init(configPath))
+	require.NoError(t, cfg.Validate())
+
+	var queries []string
+	db := newFakeDB(t, func(query string) ([]string, [][]driver.Value, error) {
+		queries = append(queries, query)
+		return []string{cfg.Doris.SchemaMapping.ServiceName, "p", "q"}, [][]driver.Value{
+			{[]byte("checkout"), []byte("1"), []byte("2000.0")},
+			{[]byte("checkout"), []byte("0"), []byte("1500.0")},
+		}, nil
+	})
+	mr := &dorisMetricsReader{logger: zap.NewNop(), dr: &dorisReader{logger: zap.NewNop(), db: db, cfg: cfg}}
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	end := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	lookback := 10 * time.Second
+	step := 5 * time.Second
+	ratePer := time.Minute
+	family, err := mr.GetLatencies(ctx, &metricsstore.LatenciesQueryParameters{
+		BaseQueryParameters: metricsstore.BaseQueryParameters{
+			ServiceNames: []string{"checkout"}, EndTime: &end, Lookback: &lookback, Step: &step, RatePer: &ratePer,
+		},
+		Quantile: 0.95,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, queries, 1)
+	require.Contains(t, queries[0], "PERCENTILE_APPROX(duration, 0.95) AS q")
+	require.Contains(t, queries[0], "LATERAL VIEW EXPLODE_NUMBERS(12) w AS n")
+	require.Contains(t, queries[0], "HAVING p >= 0 AND p < 3")
+
+	require.Equal(t, "service_latencies", family.Name)
+	require.Len(t, family.Metrics, 1)
+	points := family.Metrics[0].MetricPoints
+	require.Len(t, points, 2)
+	require.Equal(t, end.Add(-lookback).Unix(), points[0].Timestamp.Seconds)
+	require.Equal(t, 1.5, points[0].GetGaugeValue().GetDoubleValue())
+	require.Equal(t, 2.0, points[1].GetGaugeValue().GetDoubleValue())
+
+	_, err = mr.GetLatencies(ctx, &metricsstore.LatenciesQueryParameters{
+		BaseQueryParameters: metricsstore.BaseQueryParameters{ServiceNames: []string{"checkout"}},
+		Quantile:            2,
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,530 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const resourceKeyServiceName = "service.name"
+
+var otelSpanKinds = map[string]ptrace.SpanKind{
+	SpanKindInternal: ptrace.SpanKindInternal,
+	SpanKindServer:   ptrace.SpanKindServer,
+	SpanKindClient:   ptrace.SpanKindClient,
+	SpanKindProducer: ptrace.SpanKindProducer,
+	SpanKindConsumer: ptrace.SpanKindConsumer,
+}
+
+var otelStatusCodes = map[string]ptrace.StatusCode{
+	StatusCodeOk:    ptrace.StatusCodeOk,
+	StatusCodeError: ptrace.StatusCodeError,
+}
+
+// otlpTraceBuilder builds the OTLP representation of a trace directly from doris records. Unlike
+// recordToSpan it keeps the resource and scope grouping, links and events with their attributes,
+// and structured attribute values. The same redaction, sanitizing, span limit and permissions as
+// on the model.Span path are applied.
+type otlpTraceBuilder struct {
+	cfg         *Config
+	redactor    *redactor
+	permissions *servicePermissions
+	deniedSpans string
+	tenant      string
+
+	traces    ptrace.Traces
+	resources map[string]ptrace.ResourceSpans
+	scopes    map[string]ptrace.ScopeSpans
+
+	first ptrace.Span // first span of the trace, parent of the truncation warning span
+	count int         // spans of the trace, including dropped ones
+	spans int         // spans in traces
+}
+
+func newOTLPTraceBuilder(ctx context.Context, cfg *Config, redactor *redactor, deniedSpans string) *otlpTraceBuilder {
+	return &otlpTraceBuilder{
+		cfg:         cfg,
+		redactor:    redactor,
+		permissions: permissionsFromContext(ctx),
+		deniedSpans: deniedSpans,
+		tenant:      tenancy.GetTenant(ctx),
+		traces:      ptrace.NewTraces(),
+		resources:   make(map[string]ptrace.ResourceSpans),
+		scopes:      make(map[string]ptrace.ScopeSpans),
+	}
+}
+
+// Len returns the number of spans of the trace, including the truncation warning span.
+func (b *otlpTraceBuilder) Len() int {
+	return b.spans
+}
+
+// Add converts the record to a span of the trace.
+func (b *otlpTraceBuilder) Add(ctx context.Context, record map[string]string) error {
+	schema := b.cfg.Doris.SchemaMapping
+
+	serviceName, ok := record[schema.ServiceName]
+	if !ok {
+		return fmt.Errorf("invalid service_name")
+	}
+
+	redacted := false
+	if !b.permissions.Allowed(serviceName) {
+		if b.deniedSpans != AccessDeniedSpansRedact {
+			return nil
+		}
+		redacted = true
+	}
+
+	span := ptrace.NewSpan()
+	err := b.fillSpan(ctx, span, record, redacted)
+	if err != nil {
+		return err
+	}
+
+	b.count++
+	maxSpans := b.cfg.Doris.MaxSpansPerTrace
+	if maxSpans > 0 && b.count > maxSpans {
+		return nil
+	}
+
+	var scopeSpans ptrace.ScopeSpans
+	if redacted {
+		scopeSpans = b.scopeSpans(ctx, serviceName, map[string]string{schema.ServiceName: serviceName})
+	} else {
+		b.redactor.RedactAttributes(serviceName, b.tenant, spanAttributeMaps(span)...)
+		sanitizeOTLPSpan(b.cfg.Doris.Sanitize, span)
+		scopeSpans = b.scopeSpans(ctx, serviceName, record)
+	}
+
+	span.MoveTo(scopeSpans.Spans().AppendEmpty())
+	if b.spans == 0 {
+		b.first = scopeSpans.Spans().At(scopeSpans.Spans().Len() - 1)
+	}
+	b.spans++
+	return nil
+}
+
+// Traces returns the trace, with a truncation warning span if spans have been dropped.
+func (b *otlpTraceBuilder) Traces() ptrace.Traces {
+	maxSpans := b.cfg.Doris.MaxSpansPerTrace
+	if maxSpans > 0 && b.count > maxSpans && b.spans > 0 {
+		b.appendTruncationWarningSpan(maxSpans)
+	}
+	return b.traces
+}
+
+// scopeSpans returns the spans of the resource and scope of the record.
+func (b *otlpTraceBuilder) scopeSpans(ctx context.Context, serviceName string, record map[string]string) ptrace.ScopeSpans {
+	schema := b.cfg.Doris.SchemaMapping
+
+	resourceKey := serviceName + "\x00" + record[schema.ServiceInstanceID] + "\x00" + record[schema.ResourceAttributes]
+	resourceSpans, ok := b.resources[resourceKey]
+	if !ok {
+		resourceSpans = b.traces.ResourceSpans().AppendEmpty()
+		attributes := resourceSpans.Resource().Attributes()
+		putJSONAttributes(ctx, attributes, schema, schema.ResourceAttributes, record[schema.ResourceAttributes])
+		if serviceInstanceID := record[schema.ServiceInstanceID]; serviceInstanceID != "" {
+			if _, ok := attributes.Get(ProcessTagKeyServiceInstanceID); !ok {
+				attributes.PutStr(ProcessTagKeyServiceInstanceID, serviceInstanceID)
+			}
+		}
+		b.redactor.RedactAttributes(serviceName, b.tenant, attributes)
+		truncateAttributeValues(b.cfg.Doris.Sanitize, attributes)
+		// like Process.ServiceName, the service name is not subject to redaction
+		attributes.PutStr(resourceKeyServiceName, serviceName)
+		b.resources[resourceKey] = resourceSpans
+	}
+
+	scopeKey := resourceKey + "\x00" + record[schema.ScopeName] + "\x00" + record[schema.ScopeVersion]
+	scopeSpans, ok := b.scopes[scopeKey]
+	if !ok {
+		scopeSpans = resourceSpans.ScopeSpans().AppendEmpty()
+		scopeSpans.Scope().SetName(record[schema.ScopeName])
+		scopeSpans.Scope().SetVersion(record[schema.ScopeVersion])
+		b.scopes[scopeKey] = scopeSpans
+	}
+	return scopeSpans
+}
+
+// fillSpan sets the fields of span from the record, only the structure of the trace is kept
+// if the span is redacted.
+func (b *otlpTraceBuilder) fillSpan(ctx context.Context, span ptrace.Span, record map[string]string, redacted bool) error {
+	logger := LoggerFromContext(ctx)
+	schema := b.cfg.Doris.SchemaMapping
+	location := b.cfg.Doris.Location
+
+	traceID, err := otlpTraceID(schema.decodeID(record[schema.TraceID]))
+	if err != nil {
+		return fmt.Errorf("invalid trace_id: %w", err)
+	}
+	span.SetTraceID(traceID)
+
+	spanID, err := otlpSpanID(schema.decodeID(record[schema.SpanID]))
+	if err != nil {
+		return fmt.Errorf("invalid span_id: %w", err)
+	}
+	span.SetSpanID(spanID)
+
+	parentSpanIDString := schema.decodeID(record[schema.ParentSpanID])
+	if parentSpanIDString != "" {
+		parentSpanID, err := otlpSpanID(parentSpanIDString)
+		if err != nil {
+			return fmt.Errorf("invalid parent_span_id: %w", err)
+		}
+		span.SetParentSpanID(parentSpanID)
+	}
+
+	startTime, err := time.ParseInLocation(timeFormat, record[schema.Timestamp], location)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(startTime))
+
+	endTime, err := time.ParseInLocation(timeFormat, record[schema.EndTime], location)
+	if err != nil {
+		// the end time may not be mapped, the duration is in microseconds
+		duration, err := strconv.ParseInt(record[schema.Duration], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid end_time and duration: %w", err)
+		}
+		endTime = startTime.Add(time.Duration(duration) * time.Microsecond)
+	}
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(endTime))
+
+	if redacted {
+		span.SetName(redactedOperationName)
+		return nil
+	}
+
+	operationName, ok := record[schema.SpanName]
+	if !ok {
+		return fmt.Errorf("invalid span_name")
+	}
+	span.SetName(operationName)
+
+	if spanKind, ok := otelSpanKinds[schema.otelSpanKind(record[schema.SpanKind])]; ok {
+		span.SetKind(spanKind)
+	} else {
+		logger.Warn("invalid span_kind")
+	}
+
+	span.Status().SetCode(otelStatusCodes[schema.otelStatusCode(record[schema.StatusCode])])
+	span.Status().SetMessage(record[schema.StatusMessage])
+	span.TraceState().FromRaw(record[schema.TraceState])
+
+	putJSONAttributes(ctx, span.Attributes(), schema, schema.SpanAttributes, record[schema.SpanAttributes])
+
+	if eventsString := record[schema.Events]; eventsString != "" {
+		events, err := schema.unmarshalEvents(eventsString, location)
+		if err != nil {
+			logger.Warn("failed to unmarshal events", zap.Error(err))
+		}
+		for _, e := range events {
+			timestamp, err := time.ParseInLocation(timeFormat, e.Timestamp, location)
+			if err != nil {
+				logger.Warn("failed to parse timestamp of event", zap.Error(err))
+				continue
+			}
+			event := span.Events().AppendEmpty()
+			event.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+			event.SetName(e.Name)
+			putAttributes(event.Attributes(), schema.flattenAttributes(schema.Events, e.Attributes))
+		}
+	}
+
+	if linksString := record[schema.Links]; linksString != "" {
+		links, err := schema.unmarshalLinks(linksString, parentSpanIDString)
+		if err != nil {
+			logger.Warn("failed to unmarshal links", zap.Error(err))
+		}
+		for _, l := range links {
+			traceID, err := otlpTraceID(l.TraceID)
+			if err != nil {
+				logger.Warn("failed to parse trace_id of link", zap.Error(err))
+				continue
+			}
+			spanID, err := otlpSpanID(l.SpanID)
+			if err != nil {
+				logger.Warn("failed to parse span_id of link", zap.Error(err))
+				continue
+			}
+			link := span.Links().AppendEmpty()
+			link.SetTraceID(traceID)
+			link.SetSpanID(spanID)
+			link.TraceState().FromRaw(l.TraceState)
+			putAttributes(link.Attributes(), schema.flattenAttributes(schema.Links, l.Attributes))
+		}
+	}
+
+	return nil
+}
+
+// appendTruncationWarningSpan appends the counterpart of truncationWarningSpan, the warning is
+// an event since OTLP spans have no warnings.
+func (b *otlpTraceBuilder) appendTruncationWarningSpan(maxSpans int) {
+	resourceSpans := b.traces.ResourceSpans().AppendEmpty()
+	resourceSpans.Resource().Attributes().PutStr(resourceKeyServiceName, truncationWarningServiceName)
+
+	span := resourceSpans.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetTraceID(b.first.TraceID())
+	span.SetSpanID(otlpSpanIDFromModel(model.NewSpanID(rand.Uint64())))
+	span.SetParentSpanID(b.first.SpanID())
+	span.SetName(truncationWarningOperationName)
+	span.SetStartTimestamp(b.first.StartTimestamp())
+	span.SetEndTimestamp(b.first.StartTimestamp())
+
+	event := span.Events().AppendEmpty()
+	event.SetTimestamp(b.first.StartTimestamp())
+	event.SetName(fmt.Sprintf("trace truncated to %d spans, see doris.max_spans_per_trace", maxSpans))
+	b.spans++
+}
+
+func spanAttributeMaps(span ptrace.Span) []pcommon.Map {
+	maps := make([]pcommon.Map, 0, 1+span.Events().Len()+span.Links().Len())
+	maps = append(maps, span.Attributes())
+	for i := 0; i < span.Events().Len(); i++ {
+		maps = append(maps, span.Events().At(i).Attributes())
+	}
+	for i := 0; i < span.Links().Len(); i++ {
+		maps = append(maps, span.Links().At(i).Attributes())
+	}
+	return maps
+}
+
+// sanitizeOTLPSpan is sanitizeSpan for OTLP spans, attributes and events beyond the maximum
+// count are dropped and counted in the dropped counts of the span.
+func sanitizeOTLPSpan(cfg *SanitizeConfig, span ptrace.Span) {
+	truncated := false
+
+	attributes := span.Attributes()
+	if cfg.MaxTagsPerSpan > 0 && attributes.Len() > cfg.MaxTagsPerSpan {
+		dropped := 0
+		kept := 0
+		attributes.RemoveIf(func(string, pcommon.Value) bool {
+			if kept < cfg.MaxTagsPerSpan {
+				kept++
+				return false
+			}
+			dropped++
+			return true
+		})
+		span.SetDroppedAttributesCount(span.DroppedAttributesCount() + uint32(dropped))
+		truncated = true
+	}
+
+	events := span.Events()
+	if cfg.MaxLogsPerSpan > 0 && events.Len() > cfg.MaxLogsPerSpan {
+		dropped := events.Len() - cfg.MaxLogsPerSpan
+		i := 0
+		events.RemoveIf(func(ptrace.SpanEvent) bool {
+			i++
+			return i > cfg.MaxLogsPerSpan
+		})
+		span.SetDroppedEventsCount(span.DroppedEventsCount() + uint32(dropped))
+		truncated = true
+	}
+
+	for _, m := range spanAttributeMaps(span) {
+		if truncateAttributeValues(cfg, m) {
+			truncated = true
+		}
+	}
+
+	if truncated {
+		attributes.PutBool(cfg.MarkerTagKey, true)
+	}
+}
+
+// truncateAttributeValues is truncateValues for OTLP attributes, the values of nested maps and
+// slices are truncated with the limit of the attribute.
+func truncateAttributeValues(cfg *SanitizeConfig, m pcommon.Map) bool {
+	truncated := false
+	m.Range(func(k string, v pcommon.Value) bool {
+		maxLength := cfg.MaxValueLength
+		if l, ok := cfg.keyMaxValueLength[k]; ok {
+			maxLength = l
+		}
+		if maxLength >= 0 && truncateValue(v, maxLength) {
+			truncated = true
+		}
+		return true
+	})
+	return truncated
+}
+
+func truncateValue(v pcommon.Value, maxLength int) bool {
+	truncated := false
+	switch v.Type() {
+	case pcommon.ValueTypeStr:
+		if len(v.Str()) > maxLength {
+			v.SetStr(truncateString(v.Str(), maxLength))
+			truncated = true
+		}
+	case pcommon.ValueTypeBytes:
+		if v.Bytes().Len() > maxLength {
+			v.SetEmptyBytes().FromRaw(v.Bytes().AsRaw()[:maxLength])
+			truncated = true
+		}
+	case pcommon.ValueTypeSlice:
+		for i := 0; i < v.Slice().Len(); i++ {
+			if truncateValue(v.Slice().At(i), maxLength) {
+				truncated = true
+			}
+		}
+	case pcommon.ValueTypeMap:
+		v.Map().Range(func(_ string, v pcommon.Value) bool {
+			if truncateValue(v, maxLength) {
+				truncated = true
+			}
+			return true
+		})
+	}
+	return truncated
+}
+
+// RedactAttributes applies the rules matching the service and the tenant to OTLP attributes.
+func (r *redactor) RedactAttributes(serviceName string, tenant string, maps ...pcommon.Map) {
+	if r == nil || len(r.rules) == 0 {
+		return
+	}
+
+	count := 0
+	for _, rule := range r.rules {
+		if !rule.matches(serviceName, tenant) {
+			continue
+		}
+		for _, m := range maps {
+			count += rule.applyAttributes(r.salt, m)
+		}
+	}
+
+	if count > 0 {
+		redactionsTotal.Add(int64(count))
+	}
+}
+
+// applyAttributes is apply for OTLP attributes.
+func (rule *redactionRule) applyAttributes(salt string, m pcommon.Map) int {
+	count := 0
+	m.RemoveIf(func(k string, _ pcommon.Value) bool {
+		if rule.deny.Match(k) || (!rule.allow.Empty() && !rule.allow.Match(k)) {
+			count++
+			return true
+		}
+		return false
+	})
+
+	m.Range(func(k string, v pcommon.Value) bool {
+		if rule.hash.Match(k) {
+			sum := sha256.Sum256([]byte(salt + v.AsString()))
+			v.SetStr("sha256:" + hex.EncodeToString(sum[:]))
+			count++
+		} else if v.Type() == pcommon.ValueTypeStr {
+			for _, mask := range rule.masks {
+				if mask.re.MatchString(v.Str()) {
+					v.SetStr(mask.re.ReplaceAllString(v.Str(), mask.replacement))
+					count++
+				}
+			}
+		}
+		return true
+	})
+	return count
+}
+
+// putJSONAttributes puts the attributes of a JSON object column into m.
+func putJSONAttributes(ctx context.Context, m pcommon.Map, schema *SchemaMapping, column string, data string) {
+	if data == "" {
+		return
+	}
+	attributes, err := schema.unmarshalAttributes(column, data)
+	if err != nil {
+		LoggerFromContext(ctx).Warn("failed to unmarshal "+column, zap.Error(err))
+		return
+	}
+	putAttributes(m, attributes)
+}
+
+func putAttributes(m pcommon.Map, attributes map[string]any) {
+	m.EnsureCapacity(m.Len() + len(attributes))
+	for k, v := range attributes {
+		setAttributeValue(m.PutEmpty(k), v)
+	}
+}
+
+// setAttributeValue is kvToKeyValue for OTLP values, which keep arrays, maps and empty values.
+func setAttributeValue(dst pcommon.Value, v any) {
+	switch vv := v.(type) {
+	case nil:
+	case bool:
+		dst.SetBool(vv)
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			dst.SetInt(i)
+		} else if f, err := vv.Float64(); err == nil {
+			dst.SetDouble(f)
+		} else {
+			dst.SetStr(vv.String())
+		}
+	case float64:
+		dst.SetDouble(vv)
+	case int64:
+		dst.SetInt(vv)
+	case string:
+		dst.SetStr(vv)
+	case []byte:
+		dst.SetEmptyBytes().FromRaw(vv)
+	case []any:
+		s := dst.SetEmptySlice()
+		s.EnsureCapacity(len(vv))
+		for _, e := range vv {
+			setAttributeValue(s.AppendEmpty(), e)
+		}
+	case map[string]any:
+		putAttributes(dst.SetEmptyMap(), vv)
+	default:
+		dst.SetStr(fmt.Sprint(vv))
+	}
+}
+
+func otlpTraceID(s string) (pcommon.TraceID, error) {
+	traceID, err := model.TraceIDFromString(s)
+	if err != nil {
+		return pcommon.NewTraceIDEmpty(), err
+	}
+	return otlpTraceIDFromModel(traceID), nil
+}
+
+func otlpTraceIDFromModel(traceID model.TraceID) pcommon.TraceID {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], traceID.High)
+	binary.BigEndian.PutUint64(id[8:], traceID.Low)
+	return id
+}
+
+func otlpSpanID(s string) (pcommon.SpanID, error) {
+	spanID, err := model.SpanIDFromString(s)
+	if err != nil {
+		return pcommon.NewSpanIDEmpty(), err
+	}
+	return otlpSpanIDFromModel(spanID), nil
+}
+
+func otlpSpanIDFromModel(spanID model.SpanID) pcommon.SpanID {
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], uint64(spanID))
+	return id
+}
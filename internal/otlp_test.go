@@ -0,0 +1,238 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/cmd/query/app/api_v3"
+)
+
+func newOTLPTestConfig() *Config {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+	return &Config{Doris: &DorisConfig{
+		SchemaMapping: schema,
+		Location:      time.UTC,
+		Sanitize:      &SanitizeConfig{MaxValueLength: -1, MarkerTagKey: "truncated"},
+	}}
+}
+
+func newOTLPTestRecord(serviceName string, spanID string) map[string]string {
+	return map[string]string{
+		"service_name":        serviceName,
+		"service_instance_id": "instance-1",
+		"timestamp":           "2024-01-01 01:01:01.000001",
+		"end_time":            "2024-01-01 01:01:01.001001",
+		"trace_id":            "01020301000000000000000000000000",
+		"span_id":             spanID,
+		"parent_span_id":      "0102030100000000",
+		"trace_state":         "rojo=00f067aa0ba902b7",
+		"span_name":           "test-operation",
+		"span_kind":           SpanKindClient,
+		"duration":            "1000",
+		"span_attributes":     `{"http.status_code":200,"tags":["a","b"],"user.email":"jane@example.com"}`,
+		"events":              `[{"timestamp":"2024-01-01 01:01:01.000002","name":"retry","attributes":{"attempt":2}}]`,
+		"links":               `[{"trace_id":"01020301000000000000000000000001","span_id":"0102030100000001","trace_state":"k=v","attributes":{"retry":1}}]`,
+		"status_message":      "timeout",
+		"status_code":         StatusCodeError,
+		"resource_attributes": `{"host.name":"localhost"}`,
+		"scope_name":          "test-scope",
+		"scope_version":       "1.0.0",
+	}
+}
+
+func TestOTLPTraceBuilder(t *testing.T) {
+	cfg := newOTLPTestConfig()
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	builder := newOTLPTraceBuilder(ctx, cfg, nil, "")
+	require.NoError(t, builder.Add(ctx, newOTLPTestRecord("frontend", "0102030100000002")))
+	require.NoError(t, builder.Add(ctx, newOTLPTestRecord("frontend", "0102030100000003")))
+	other := newOTLPTestRecord("frontend", "0102030100000004")
+	other["scope_name"] = "other-scope"
+	require.NoError(t, builder.Add(ctx, other))
+	require.NoError(t, builder.Add(ctx, newOTLPTestRecord("backend", "0102030100000005")))
+	require.Error(t, builder.Add(ctx, newOTLPTestRecord("backend", "invalid")))
+	require.Equal(t, 4, builder.Len())
+
+	traces := builder.Traces()
+	require.Equal(t, 2, traces.ResourceSpans().Len())
+
+	resourceSpans := traces.ResourceSpans().At(0)
+	require.Equal(t, map[string]any{
+		"service.name":        "frontend",
+		"service.instance.id": "instance-1",
+		"host.name":           "localhost",
+	}, resourceSpans.Resource().Attributes().AsRaw())
+	require.Equal(t, 2, resourceSpans.ScopeSpans().Len())
+
+	scopeSpans := resourceSpans.ScopeSpans().At(0)
+	require.Equal(t, "test-scope", scopeSpans.Scope().Name())
+	require.Equal(t, "1.0.0", scopeSpans.Scope().Version())
+	require.Equal(t, 2, scopeSpans.Spans().Len())
+
+	span := scopeSpans.Spans().At(0)
+	require.Equal(t, pcommon.TraceID{1, 2, 3, 1}, span.TraceID())
+	require.Equal(t, pcommon.SpanID{1, 2, 3, 1, 0, 0, 0, 2}, span.SpanID())
+	require.Equal(t, pcommon.SpanID{1, 2, 3, 1}, span.ParentSpanID())
+	require.Equal(t, "test-operation", span.Name())
+	require.Equal(t, ptrace.SpanKindClient, span.Kind())
+	require.Equal(t, "rojo=00f067aa0ba902b7", span.TraceState().AsRaw())
+	require.Equal(t, time.Millisecond, span.EndTimestamp().AsTime().Sub(span.StartTimestamp().AsTime()))
+	require.Equal(t, ptrace.StatusCodeError, span.Status().Code())
+	require.Equal(t, "timeout", span.Status().Message())
+	require.Equal(t, map[string]any{
+		"http.status_code": int64(200),
+		"tags":             []any{"a", "b"},
+		"user.email":       "jane@example.com",
+	}, span.Attributes().AsRaw())
+
+	require.Equal(t, 1, span.Events().Len())
+	require.Equal(t, "retry", span.Events().At(0).Name())
+	require.Equal(t, map[string]any{"attempt": int64(2)}, span.Events().At(0).Attributes().AsRaw())
+
+	require.Equal(t, 1, span.Links().Len())
+	link := span.Links().At(0)
+	require.Equal(t, pcommon.TraceID{1, 2, 3, 1, 15: 1}, link.TraceID())
+	require.Equal(t, pcommon.SpanID{1, 2, 3, 1, 0, 0, 0, 1}, link.SpanID())
+	require.Equal(t, "k=v", link.TraceState().AsRaw())
+	require.Equal(t, map[string]any{"retry": int64(1)}, link.Attributes().AsRaw())
+
+	require.Equal(t, "other-scope", resourceSpans.ScopeSpans().At(1).Scope().Name())
+	require.Equal(t, "backend", traces.ResourceSpans().At(1).Resource().Attributes().AsRaw()["service.name"])
+}
+
+func TestOTLPTraceBuilderEndTimeFromDuration(t *testing.T) {
+	cfg := newOTLPTestConfig()
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	record := newOTLPTestRecord("frontend", "0102030100000002")
+	delete(record, "end_time")
+	builder := newOTLPTraceBuilder(ctx, cfg, nil, "")
+	require.NoError(t, builder.Add(ctx, record))
+
+	span := builder.Traces().ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	require.Equal(t, time.Millisecond, span.EndTimestamp().AsTime().Sub(span.StartTimestamp().AsTime()))
+}
+
+func TestOTLPTraceBuilderPermissions(t *testing.T) {
+	cfg := newOTLPTestConfig()
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+	ctx = permissionsWithContext(ctx, &servicePermissions{identity: "alice", services: []string{"front*"}})
+
+	builder := newOTLPTraceBuilder(ctx, cfg, nil, AccessDeniedSpansStrip)
+	require.NoError(t, builder.Add(ctx, newOTLPTestRecord("frontend", "0102030100000002")))
+	require.NoError(t, builder.Add(ctx, newOTLPTestRecord("backend", "0102030100000003")))
+	require.Equal(t, 1, builder.Len())
+
+	builder = newOTLPTraceBuilder(ctx, cfg, nil, AccessDeniedSpansRedact)
+	require.NoError(t, builder.Add(ctx, newOTLPTestRecord("frontend", "0102030100000002")))
+	require.NoError(t, builder.Add(ctx, newOTLPTestRecord("backend", "0102030100000003")))
+	require.Equal(t, 2, builder.Len())
+
+	resourceSpans := builder.Traces().ResourceSpans().At(1)
+	require.Equal(t, map[string]any{"service.name": "backend"}, resourceSpans.Resource().Attributes().AsRaw())
+	require.Equal(t, "", resourceSpans.ScopeSpans().At(0).Scope().Name())
+	span := resourceSpans.ScopeSpans().At(0).Spans().At(0)
+	require.Equal(t, redactedOperationName, span.Name())
+	require.Equal(t, pcommon.SpanID{1, 2, 3, 1}, span.ParentSpanID())
+	require.Equal(t, 0, span.Attributes().Len())
+	require.Equal(t, 0, span.Events().Len())
+	require.Equal(t, 0, span.Links().Len())
+}
+
+func TestOTLPTraceBuilderMaxSpans(t *testing.T) {
+	cfg := newOTLPTestConfig()
+	cfg.Doris.MaxSpansPerTrace = 2
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	builder := newOTLPTraceBuilder(ctx, cfg, nil, "")
+	for _, spanID := range []string{"0102030100000002", "0102030100000003", "0102030100000004"} {
+		require.NoError(t, builder.Add(ctx, newOTLPTestRecord("frontend", spanID)))
+	}
+
+	traces := builder.Traces()
+	require.Equal(t, 3, traces.SpanCount())
+	warning := traces.ResourceSpans().At(1)
+	require.Equal(t, truncationWarningServiceName, warning.Resource().Attributes().AsRaw()["service.name"])
+	span := warning.ScopeSpans().At(0).Spans().At(0)
+	require.Equal(t, truncationWarningOperationName, span.Name())
+	require.Equal(t, pcommon.SpanID{1, 2, 3, 1, 0, 0, 0, 2}, span.ParentSpanID())
+	require.Equal(t, "trace truncated to 2 spans, see doris.max_spans_per_trace", span.Events().At(0).Name())
+}
+
+func TestOTLPTraceBuilderRedactAndSanitize(t *testing.T) {
+	cfg := newOTLPTestConfig()
+	cfg.Doris.Sanitize = &SanitizeConfig{MaxValueLength: 8, MaxLogsPerSpan: 1, MarkerTagKey: "truncated"}
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	r, err := newRedactor(&RedactionConfig{
+		Rules: []*RedactionRule{{
+			DenyKeys: []string{"host.*", "service.*"},
+			Masks:    []*RedactionMask{{Pattern: `[\w.+-]+@[\w-]+\.\w+`, Replacement: "<email>"}},
+		}},
+	})
+	require.NoError(t, err)
+
+	record := newOTLPTestRecord("frontend", "0102030100000002")
+	record["events"] = `[{"timestamp":"2024-01-01 01:01:01.000002","name":"a"},{"timestamp":"2024-01-01 01:01:01.000003","name":"b"}]`
+	builder := newOTLPTraceBuilder(ctx, cfg, r, "")
+	require.NoError(t, builder.Add(ctx, record))
+
+	resourceSpans := builder.Traces().ResourceSpans().At(0)
+	require.Equal(t, map[string]any{"service.name": "frontend"}, resourceSpans.Resource().Attributes().AsRaw())
+
+	span := resourceSpans.ScopeSpans().At(0).Spans().At(0)
+	require.Equal(t, map[string]any{
+		"http.status_code": int64(200),
+		"tags":             []any{"a", "b"},
+		"user.email":       "<email>",
+		"truncated":        true,
+	}, span.Attributes().AsRaw())
+	require.Equal(t, 1, span.Events().Len())
+	require.Equal(t, uint32(1), span.DroppedEventsCount())
+}
+
+func TestTraceQueryFromAPIv3(t *testing.T) {
+	_, err := traceQueryFromAPIv3(nil)
+	require.Error(t, err)
+	_, err = traceQueryFromAPIv3(&api_v3.TraceQueryParameters{ServiceName: "frontend"})
+	require.Error(t, err)
+
+	startTimeMin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	startTimeMax := startTimeMin.Add(time.Hour)
+	query, err := traceQueryFromAPIv3(&api_v3.TraceQueryParameters{
+		ServiceName:  "frontend",
+		Attributes:   map[string]string{"http.status_code": "500"},
+		StartTimeMin: mustTimestampProto(t, startTimeMin),
+		StartTimeMax: mustTimestampProto(t, startTimeMax),
+		DurationMin:  types.DurationProto(time.Second),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "frontend", query.ServiceName)
+	require.Equal(t, map[string]string{"http.status_code": "500"}, query.Tags)
+	require.Equal(t, startTimeMin, query.StartTimeMin)
+	require.Equal(t, startTimeMax, query.StartTimeMax)
+	require.Equal(t, time.Second, query.DurationMin)
+	require.Equal(t, defaultHTTPQueryLimit, query.NumTraces)
+}
+
+func mustTimestampProto(t *testing.T, ts time.Time) *types.Timestamp {
+	p, err := types.TimestampProto(ts)
+	require.NoError(t, err)
+	return p
+}
+
+func TestModelTraceID(t *testing.T) {
+	traceID := pcommon.TraceID{1, 2, 3, 1, 15: 1}
+	require.Equal(t, model.NewTraceID(0x0102030100000000, 1), modelTraceID(traceID))
+	require.Equal(t, traceID, otlpTraceIDFromModel(modelTraceID(traceID)))
+}
@@ -2,45 +2,151 @@ package internal
 
 import (
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
 
-func queryGetTrace(schema *SchemaMapping, tableName string, traceID string) string {
-	return fmt.Sprintf(
-		`SELECT * FROM %s WHERE %s = "%s"`,
+func queryGetTrace(schema *SchemaMapping, tableName string, traceID string, startTime time.Time, endTime time.Time, location *time.Location, limit int) string {
+	query := fmt.Sprintf(
+		`SELECT * FROM %s WHERE %s = %s`,
 		tableName,
 		schema.TraceID,
-		traceID,
+		schema.idLiteral(traceID),
+	)
+
+	if !startTime.IsZero() {
+		query += fmt.Sprintf(
+			` AND %s >= '%s'`,
+			schema.Timestamp,
+			startTime.In(location).Format(timeFormat),
+		)
+	}
+
+	if !endTime.IsZero() {
+		query += fmt.Sprintf(
+			` AND %s <= '%s'`,
+			schema.Timestamp,
+			endTime.In(location).Format(timeFormat),
+		)
+	}
+
+	// the limit keeps the root spans and the earliest spans, rather than arbitrary ones
+	if limit > 0 {
+		query += fmt.Sprintf(
+			` ORDER BY %s = '' DESC, %s LIMIT %d`,
+			schema.ParentSpanID,
+			schema.Timestamp,
+			limit,
+		)
+	}
+
+	return query
+}
+
+// queryGetLogs selects the log records of a trace, or of a span if spanID is set, oldest first.
+func queryGetLogs(schema *LogsSchemaMapping, tableName string, traceID string, spanID string, startTime time.Time, endTime time.Time, location *time.Location, limit int) string {
+	query := fmt.Sprintf(
+		`SELECT * FROM %s WHERE %s = '%s'`,
+		tableName,
+		schema.TraceID,
+		escapeStringLiteral(traceID),
+	)
+
+	if spanID != "" {
+		query += fmt.Sprintf(
+			` AND %s = '%s'`,
+			schema.SpanID,
+			escapeStringLiteral(spanID),
+		)
+	}
+
+	if !startTime.IsZero() {
+		query += fmt.Sprintf(
+			` AND %s >= '%s'`,
+			schema.Timestamp,
+			startTime.In(location).Format(timeFormat),
+		)
+	}
+
+	if !endTime.IsZero() {
+		query += fmt.Sprintf(
+			` AND %s <= '%s'`,
+			schema.Timestamp,
+			endTime.In(location).Format(timeFormat),
+		)
+	}
+
+	query += fmt.Sprintf(
+		` ORDER BY %s LIMIT %d`,
+		schema.Timestamp,
+		limit,
 	)
+
+	return query
 }
 
-func queryGetServices(schema *SchemaMapping, tableName string) string {
+func queryGetTraceTimeRange(indexSchema *TraceIndexSchemaMapping, tableName string, traceID string) string {
 	return fmt.Sprintf(
-		`SELECT %s FROM %s GROUP BY %s`,
+		`SELECT MIN(%s) AS %s, MAX(%s) AS %s FROM %s WHERE %s = "%s"`,
+		indexSchema.StartTime, indexSchema.StartTime,
+		indexSchema.EndTime, indexSchema.EndTime,
+		tableName,
+		indexSchema.TraceID,
+		traceID,
+	)
+}
+
+func queryGetServices(schema *SchemaMapping, tableName string, endTs time.Time, lookback time.Duration, location *time.Location) string {
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s`,
 		schema.ServiceName,
 		tableName,
+	)
+
+	if lookback > 0 {
+		query += fmt.Sprintf(
+			` WHERE %s >= '%s'`,
+			schema.Timestamp,
+			endTs.Add(-lookback).In(location).Format(timeFormat),
+		)
+	}
+
+	query += fmt.Sprintf(
+		` GROUP BY %s`,
 		schema.ServiceName,
 	)
+
+	return query
 }
 
-func queryGetOperations(schema *SchemaMapping, tableName string, param spanstore.OperationQueryParameters) string {
+func queryGetOperations(schema *SchemaMapping, tableName string, param spanstore.OperationQueryParameters, endTs time.Time, lookback time.Duration, location *time.Location) string {
 	query := fmt.Sprintf(
 		`SELECT %s, %s FROM %s WHERE %s = "%s"`,
 		schema.SpanName,
 		schema.SpanKind,
 		tableName,
 		schema.ServiceName,
-		param.ServiceName,
+		escapeStringLiteral(param.ServiceName),
 	)
 
+	if lookback > 0 {
+		query += fmt.Sprintf(
+			` AND %s >= '%s'`,
+			schema.Timestamp,
+			endTs.Add(-lookback).In(location).Format(timeFormat),
+		)
+	}
+
 	if param.SpanKind != "" {
 		query += fmt.Sprintf(
 			` AND %s = "%s"`,
 			schema.SpanKind,
-			jeagerToOtelSpanKind[param.SpanKind],
+			schema.spanKindValue(jeagerToOtelSpanKind[param.SpanKind]),
 		)
 	}
 
@@ -53,25 +159,228 @@ func queryGetOperations(schema *SchemaMapping, tableName string, param spanstore
 	return query
 }
 
-func queryFindTraces(schema *SchemaMapping, tableName string, traceIDs []string) string {
-	for i, traceID := range traceIDs {
-		traceIDs[i] = fmt.Sprintf(`'%s'`, traceID)
+func queryGetServicesFromOperationsTable(opsSchema *OperationsSchemaMapping, tableName string, endTs time.Time, lookback time.Duration, location *time.Location) string {
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s`,
+		opsSchema.ServiceName,
+		tableName,
+	)
+
+	if lookback > 0 {
+		query += fmt.Sprintf(
+			` WHERE %s >= '%s'`,
+			opsSchema.LastSeen,
+			endTs.Add(-lookback).In(location).Format(timeFormat),
+		)
+	}
+
+	query += fmt.Sprintf(
+		` GROUP BY %s`,
+		opsSchema.ServiceName,
+	)
+
+	return query
+}
+
+func queryGetOperationsFromOperationsTable(schema *SchemaMapping, opsSchema *OperationsSchemaMapping, tableName string, param spanstore.OperationQueryParameters, endTs time.Time, lookback time.Duration, location *time.Location) string {
+	query := fmt.Sprintf(
+		`SELECT %s, %s FROM %s WHERE %s = "%s"`,
+		opsSchema.SpanName,
+		opsSchema.SpanKind,
+		tableName,
+		opsSchema.ServiceName,
+		escapeStringLiteral(param.ServiceName),
+	)
+
+	if lookback > 0 {
+		query += fmt.Sprintf(
+			` AND %s >= '%s'`,
+			opsSchema.LastSeen,
+			endTs.Add(-lookback).In(location).Format(timeFormat),
+		)
+	}
+
+	if param.SpanKind != "" {
+		query += fmt.Sprintf(
+			` AND %s = "%s"`,
+			opsSchema.SpanKind,
+			schema.spanKindValue(jeagerToOtelSpanKind[param.SpanKind]),
+		)
+	}
+
+	query += fmt.Sprintf(
+		` GROUP BY %s, %s`,
+		opsSchema.SpanName,
+		opsSchema.SpanKind,
+	)
+
+	return query
+}
+
+// queryCreateOperationsMaterializedView creates an async materialized view which doris
+// refreshes on its own, so that it can be used as the operations table.
+func queryCreateOperationsMaterializedView(schema *SchemaMapping, opsSchema *OperationsSchemaMapping, viewName string, tableName string, mv *MaterializedViewConfig) string {
+	template := `CREATE MATERIALIZED VIEW IF NOT EXISTS %s
+BUILD IMMEDIATE REFRESH AUTO ON SCHEDULE EVERY %d MINUTE
+DISTRIBUTED BY HASH(%s) BUCKETS AUTO%s
+AS SELECT %s AS %s, %s AS %s, %s AS %s, MAX(%s) AS %s
+FROM %s
+GROUP BY %s, %s, %s`
+
+	minutes := int64(mv.RefreshInterval / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+
+	properties := ""
+	if len(mv.Properties) > 0 {
+		keys := make([]string, 0, len(mv.Properties))
+		for k := range mv.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		pairs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, fmt.Sprintf(`"%s" = "%s"`, escapeStringLiteral(k), escapeStringLiteral(mv.Properties[k])))
+		}
+		properties = fmt.Sprintf("\nPROPERTIES (%s)", strings.Join(pairs, ", "))
 	}
-	traceIDsString := strings.Join(traceIDs, ",")
 
 	return fmt.Sprintf(
+		template,
+		viewName,
+		minutes,
+		opsSchema.ServiceName,
+		properties,
+		schema.ServiceName, opsSchema.ServiceName,
+		schema.SpanName, opsSchema.SpanName,
+		schema.SpanKind, opsSchema.SpanKind,
+		schema.Timestamp, opsSchema.LastSeen,
+		tableName,
+		schema.ServiceName, schema.SpanName, schema.SpanKind,
+	)
+}
+
+func queryFindTraces(schema *SchemaMapping, tableName string, traceIDs []string, startTimeMin time.Time, startTimeMax time.Time, location *time.Location) string {
+	quotedTraceIDs := make([]string, len(traceIDs))
+	for i, traceID := range traceIDs {
+		quotedTraceIDs[i] = schema.idLiteral(traceID)
+	}
+	traceIDsString := strings.Join(quotedTraceIDs, ",")
+
+	query := fmt.Sprintf(
 		`SELECT * FROM %s WHERE %s IN (%s)`,
 		tableName,
 		schema.TraceID,
 		traceIDsString,
 	)
+
+	for _, predicate := range timeRangePredicates(schema, "", startTimeMin, startTimeMax, location) {
+		query += " AND " + predicate
+	}
+
+	return query
 }
 
-func queryFindTraceIDs(schema *SchemaMapping, tableName string, param *spanstore.TraceQueryParameters, location *time.Location) string {
+// orderByTraceIDs orders the spans of queryFindTraces like traceIDs.
+func orderByTraceIDs(schema *SchemaMapping, traceIDs []string) string {
+	quotedTraceIDs := make([]string, len(traceIDs))
+	for i, traceID := range traceIDs {
+		quotedTraceIDs[i] = schema.idLiteral(traceID)
+	}
+
+	return fmt.Sprintf(
+		` ORDER BY FIELD(%s, %s)`,
+		schema.TraceID,
+		strings.Join(quotedTraceIDs, ","),
+	)
+}
+
+// queryFindTracesJoin fetches the spans of the traces found by traceIDsQuery in a single statement.
+// The spans are ordered like the traces of traceIDsQuery, which must have been built with the same sort.
+func queryFindTracesJoin(schema *SchemaMapping, tableName string, traceIDsQuery string, sort string, startTimeMin time.Time, startTimeMax time.Time, location *time.Location) string {
+	query := fmt.Sprintf(
+		`SELECT s.* FROM %s s INNER JOIN (%s) ids ON s.%s = ids.%s`,
+		tableName,
+		traceIDsQuery,
+		schema.TraceID,
+		schema.TraceID,
+	)
+
+	predicates := timeRangePredicates(schema, "s.", startTimeMin, startTimeMax, location)
+	if len(predicates) > 0 {
+		query += fmt.Sprintf(
+			" WHERE %s",
+			strings.Join(predicates, " AND "),
+		)
+	}
+
+	_, order := findTracesOrder(schema, sort)
+	for i := range order {
+		order[i] = "ids." + order[i]
+	}
+	query += fmt.Sprintf(
+		" ORDER BY %s",
+		strings.Join(order, ", "),
+	)
+
+	return query
+}
+
+// findTracesOrder returns the aggregate which the trace ID query selects in addition for the
+// given sort, and the ORDER BY terms referring to the selected columns. The trace ID is
+// always the last term, so that the order is deterministic.
+func findTracesOrder(schema *SchemaMapping, sort string) (string, []string) {
+	switch sort {
+	case FindTracesSortOldest:
+		return "", []string{"t ASC", schema.TraceID + " ASC"}
+	case FindTracesSortLongest:
+		return fmt.Sprintf(", MAX(%s) AS d", schema.Duration), []string{"d DESC", "t DESC", schema.TraceID + " DESC"}
+	case FindTracesSortMostSpans:
+		return ", COUNT(*) AS c", []string{"c DESC", "t DESC", schema.TraceID + " DESC"}
+	case FindTracesSortMostErrors:
+		return fmt.Sprintf(`, SUM(IF(%s = "%s", 1, 0)) AS e`, schema.StatusCode, schema.statusCodeValue(StatusCodeError)), []string{"e DESC", "t DESC", schema.TraceID + " DESC"}
+	default:
+		return "", []string{"t DESC", schema.TraceID + " DESC"}
+	}
+}
+
+// timeRangePredicates restricts the timestamp column, zero times are ignored.
+func timeRangePredicates(schema *SchemaMapping, prefix string, startTimeMin time.Time, startTimeMax time.Time, location *time.Location) []string {
+	predicates := make([]string, 0, 2)
+
+	if !startTimeMin.IsZero() {
+		predicates = append(predicates, fmt.Sprintf(
+			`%s%s >= '%s'`,
+			prefix,
+			schema.Timestamp,
+			startTimeMin.In(location).Format(timeFormat),
+		))
+	}
+
+	if !startTimeMax.IsZero() {
+		predicates = append(predicates, fmt.Sprintf(
+			`%s%s <= '%s'`,
+			prefix,
+			schema.Timestamp,
+			startTimeMax.In(location).Format(timeFormat),
+		))
+	}
+
+	return predicates
+}
+
+// queryFindTraceIDs selects the trace ID and the minimum timestamp "t" of the matching spans
+// of each trace. With a cursor, which is only supported by the newest and oldest sorts, the
+// traces start strictly after the cursor. Tag predicates are rewritten by guard.
+func queryFindTraceIDs(schema *SchemaMapping, tableName string, param *spanstore.TraceQueryParameters, location *time.Location, textSearch *FullTextSearchConfig, sort string, cursor *traceCursor, guard attributeGuard) string {
 	tags := make(map[string]string, len(param.Tags))
 	for k, v := range param.Tags {
 		tags[k] = v
 	}
+	delete(tags, TagKeySort)
+	delete(tags, TagKeyCursor)
 
 	predicates := make([]string, 0, len(tags)+6)
 	for k, v := range tags {
@@ -84,19 +393,21 @@ func queryFindTraceIDs(schema *SchemaMapping, tableName string, param *spanstore
 		if k == "error" && v == "true" {
 			predicates = append(predicates,
 				fmt.Sprintf(
-					`((%s['error.msg'] IS NOT NULL) OR (%s == "STATUS_CODE_ERROR"))`,
-					schema.SpanAttributes,
+					`((%s IS NOT NULL) OR (%s == "%s"))`,
+					schema.attributeExpr("", schema.SpanAttributes, "error.msg", false),
 					schema.StatusCode,
+					schema.statusCodeValue(StatusCodeError),
 				))
 
+		} else if textSearch != nil && textSearch.Enabled && k == textSearch.TagKey {
+			predicates = append(predicates, guard.apply(schema.ServiceName, "", textSearchPredicate(textSearch.Columns, v)))
 		} else {
-			predicates = append(predicates,
+			predicates = append(predicates, guard.apply(schema.ServiceName, k,
 				fmt.Sprintf(
-					`%s['%s'] = '%s'`,
-					schema.SpanAttributes,
-					k,
-					v,
-				))
+					`%s = '%s'`,
+					schema.attributeExpr("", schema.SpanAttributes, k, false),
+					escapeStringLiteral(v),
+				)))
 		}
 	}
 
@@ -104,7 +415,7 @@ func queryFindTraceIDs(schema *SchemaMapping, tableName string, param *spanstore
 		predicates = append(predicates, fmt.Sprintf(
 			`%s = '%s'`,
 			schema.ServiceName,
-			param.ServiceName,
+			escapeStringLiteral(param.ServiceName),
 		))
 	}
 
@@ -112,7 +423,7 @@ func queryFindTraceIDs(schema *SchemaMapping, tableName string, param *spanstore
 		predicates = append(predicates, fmt.Sprintf(
 			`%s = '%s'`,
 			schema.SpanName,
-			param.OperationName,
+			escapeStringLiteral(param.OperationName),
 		))
 	}
 
@@ -150,10 +461,33 @@ func queryFindTraceIDs(schema *SchemaMapping, tableName string, param *spanstore
 			))
 	}
 
+	// bound the scanned time range by the cursor, so that doris can prune partitions. The minimum
+	// timestamp of a trace before the cursor is not changed by an upper bound, but by a lower bound,
+	// so the oldest sort excludes the traces with matching spans before the cursor instead. These
+	// spans are only looked up from StartTimeMin, without it the pages are only bounded by HAVING.
+	if cursor != nil {
+		startTime := cursor.StartTime.In(location).Format(timeFormat)
+		if sort == FindTracesSortOldest {
+			if !param.StartTimeMin.IsZero() {
+				// the predicates hold the lower bound of StartTimeMin
+				earlier := append(slices.Clone(predicates), fmt.Sprintf(`%s < '%s'`, schema.Timestamp, startTime))
+				predicates = append(predicates,
+					fmt.Sprintf(`%s >= '%s'`, schema.Timestamp, startTime),
+					fmt.Sprintf(`%s NOT IN (SELECT %s FROM %s WHERE %s)`, schema.TraceID, schema.TraceID, tableName, strings.Join(earlier, " AND ")),
+				)
+			}
+		} else {
+			predicates = append(predicates, fmt.Sprintf(`%s <= '%s'`, schema.Timestamp, startTime))
+		}
+	}
+
+	aggregate, order := findTracesOrder(schema, sort)
+
 	query := fmt.Sprintf(
-		`SELECT %s, MIN(%s) AS t FROM %s`,
+		`SELECT %s, MIN(%s) AS t%s FROM %s`,
 		schema.TraceID,
 		schema.Timestamp,
+		aggregate,
 		tableName,
 	)
 
@@ -165,14 +499,236 @@ func queryFindTraceIDs(schema *SchemaMapping, tableName string, param *spanstore
 	}
 
 	query += fmt.Sprintf(
-		` GROUP BY %s ORDER BY t DESC LIMIT %d`,
+		` GROUP BY %s`,
 		schema.TraceID,
+	)
+
+	if cursor != nil {
+		operator := "<"
+		if sort == FindTracesSortOldest {
+			operator = ">"
+		}
+		startTime := cursor.StartTime.In(location).Format(timeFormat)
+		query += fmt.Sprintf(
+			` HAVING MIN(%s) %s '%s' OR (MIN(%s) = '%s' AND %s %s %s)`,
+			schema.Timestamp, operator, startTime,
+			schema.Timestamp, startTime,
+			schema.TraceID, operator, schema.idLiteral(cursor.TraceID),
+		)
+	}
+
+	query += fmt.Sprintf(
+		` ORDER BY %s LIMIT %d`,
+		strings.Join(order, ", "),
 		param.NumTraces,
 	)
 
 	return query
 }
 
+// queryTraceSummaries summarizes the newest traces matching predicate: the start time "t",
+// the duration "d" in microseconds, the number of spans "c" and the service and name of the root span.
+// spanPredicates restrict the summarized spans.
+func queryTraceSummaries(schema *SchemaMapping, tableName string, predicate string, having []string, spanPredicates []string, limit int) string {
+	isRoot := fmt.Sprintf(`(%s = '' OR %s IS NULL)`, schema.ParentSpanID, schema.ParentSpanID)
+	query := fmt.Sprintf(
+		`SELECT %s, MIN(%s) AS t, MICROSECONDS_DIFF(MAX(MICROSECONDS_ADD(%s, %s)), MIN(%s)) AS d, COUNT(*) AS c, `+
+			`MAX(IF(%s, %s, NULL)) AS root_service_name, MAX(IF(%s, %s, NULL)) AS root_span_name FROM %s`,
+		schema.TraceID,
+		schema.Timestamp,
+		schema.Timestamp, schema.Duration, schema.Timestamp,
+		isRoot, schema.ServiceName,
+		isRoot, schema.SpanName,
+		tableName,
+	)
+
+	predicates := append(spanPredicates, predicate)
+	query += fmt.Sprintf(
+		" WHERE %s GROUP BY %s",
+		strings.Join(predicates, " AND "),
+		schema.TraceID,
+	)
+
+	if len(having) > 0 {
+		query += fmt.Sprintf(
+			" HAVING %s",
+			strings.Join(having, " AND "),
+		)
+	}
+
+	query += fmt.Sprintf(
+		` ORDER BY t DESC, %s DESC LIMIT %d`,
+		schema.TraceID,
+		limit,
+	)
+
+	return query
+}
+
+// queryMetrics aggregates the spans of services between startTime and endTime in buckets of step
+// since origin: the index of the bucket "b", the number of spans "c" and of errors "e".
+func queryMetrics(schema *SchemaMapping, tableName string, services []string, spanKinds []string, groupByOperation bool,
+	origin time.Time, startTime time.Time, endTime time.Time, step time.Duration, location *time.Location) string {
+	groupBy := schema.ServiceName
+	if groupByOperation {
+		groupBy += ", " + schema.SpanName
+	}
+
+	return fmt.Sprintf(
+		`SELECT %s, %s AS b, COUNT(*) AS c, SUM(IF(%s = '%s', 1, 0)) AS e FROM %s WHERE %s GROUP BY %s, b`,
+		groupBy,
+		metricsBucketExpr(schema, origin, step, location),
+		schema.StatusCode,
+		schema.statusCodeValue(StatusCodeError),
+		tableName,
+		strings.Join(metricsPredicates(schema, services, spanKinds, startTime, endTime, location), " AND "),
+		groupBy,
+	)
+}
+
+// queryLatencies computes the quantile of the durations of the spans of services over the window
+// buckets of step since origin before each point: the index of the point "p", from 0 to count, and
+// the quantile "q". Each span is counted in the points of the windows holding its bucket.
+func queryLatencies(schema *SchemaMapping, tableName string, services []string, spanKinds []string, groupByOperation bool,
+	origin time.Time, startTime time.Time, endTime time.Time, step time.Duration, window int64, count int64, quantile float64,
+	location *time.Location) string {
+	groupBy := schema.ServiceName
+	if groupByOperation {
+		groupBy += ", " + schema.SpanName
+	}
+
+	return fmt.Sprintf(
+		`SELECT %s, %s + n AS p, PERCENTILE_APPROX(%s, %s) AS q FROM %s LATERAL VIEW EXPLODE_NUMBERS(%d) w AS n `+
+			`WHERE %s GROUP BY %s, p HAVING p >= 0 AND p < %d`,
+		groupBy,
+		metricsBucketExpr(schema, origin, step, location),
+		schema.Duration,
+		strconv.FormatFloat(quantile, 'f', -1, 64),
+		tableName,
+		window,
+		strings.Join(metricsPredicates(schema, services, spanKinds, startTime, endTime, location), " AND "),
+		groupBy,
+		count,
+	)
+}
+
+// metricsBucketExpr is the index of the bucket of step since origin of a span.
+func metricsBucketExpr(schema *SchemaMapping, origin time.Time, step time.Duration, location *time.Location) string {
+	return fmt.Sprintf(
+		`FLOOR(SECONDS_DIFF(%s, '%s') / %d)`,
+		schema.Timestamp,
+		origin.In(location).Format(timeFormat),
+		int64(step/time.Second),
+	)
+}
+
+func metricsPredicates(schema *SchemaMapping, services []string, spanKinds []string, startTime time.Time, endTime time.Time, location *time.Location) []string {
+	predicates := []string{
+		fmt.Sprintf(`%s IN (%s)`, schema.ServiceName, quoteStringLiterals(services)),
+		fmt.Sprintf(`%s IN (%s)`, schema.SpanKind, quoteStringLiterals(spanKinds)),
+	}
+	return append(predicates, timeRangePredicates(schema, "", startTime, endTime, location)...)
+}
+
+// queryOperationStats aggregates the matching spans by operation: the number of spans "c" and of
+// errors "e", and the median "p50" and 99th percentile "p99" of the durations. Operations are sorted
+// by p99, or by the ratio of errors when errors is set, which skips the operations without errors.
+func queryOperationStats(schema *SchemaMapping, tableName string, predicates []string, errors bool, limit int) string {
+	query := fmt.Sprintf(
+		`SELECT %s, COUNT(*) AS c, SUM(IF(%s = '%s', 1, 0)) AS e, PERCENTILE_APPROX(%s, 0.5) AS p50, PERCENTILE_APPROX(%s, 0.99) AS p99 `+
+			`FROM %s WHERE %s GROUP BY %s`,
+		schema.SpanName,
+		schema.StatusCode, schema.statusCodeValue(StatusCodeError),
+		schema.Duration,
+		schema.Duration,
+		tableName,
+		strings.Join(predicates, " AND "),
+		schema.SpanName,
+	)
+
+	if errors {
+		query += " HAVING e > 0 ORDER BY e / c DESC, e DESC"
+	} else {
+		query += " ORDER BY p99 DESC"
+	}
+
+	query += fmt.Sprintf(
+		`, %s LIMIT %d`,
+		schema.SpanName,
+		limit,
+	)
+
+	return query
+}
+
+// durationBucketExpr is the index of the histogram bucket of a duration: bucket k holds the
+// durations from 2^k to 2^(k+1) microseconds.
+func durationBucketExpr(schema *SchemaMapping) string {
+	return fmt.Sprintf(`FLOOR(LOG2(GREATEST(%s, 1)))`, schema.Duration)
+}
+
+// queryDurationHistogram counts the matching spans "c" by histogram bucket "k".
+func queryDurationHistogram(schema *SchemaMapping, tableName string, predicates []string) string {
+	return fmt.Sprintf(
+		`SELECT %s AS k, COUNT(*) AS c FROM %s WHERE %s GROUP BY k ORDER BY k`,
+		durationBucketExpr(schema),
+		tableName,
+		strings.Join(predicates, " AND "),
+	)
+}
+
+// queryExampleTraceIDs selects the trace IDs of the n slowest matching spans of each value "k" of key.
+func queryExampleTraceIDs(schema *SchemaMapping, tableName string, key string, predicates []string, n int) string {
+	return fmt.Sprintf(
+		`SELECT k, %s FROM (SELECT %s AS k, %s, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s DESC) AS r `+
+			`FROM %s WHERE %s) examples WHERE r <= %d ORDER BY k, r`,
+		schema.TraceID,
+		key, schema.TraceID, key, schema.Duration,
+		tableName,
+		strings.Join(predicates, " AND "),
+		n,
+	)
+}
+
+// quoteStringLiterals joins the quoted values with commas.
+func quoteStringLiterals(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, fmt.Sprintf(`'%s'`, escapeStringLiteral(v)))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// textSearchPredicate matches any of the terms of text against the given columns.
+// A text wrapped in double quotes is matched as a phrase.
+func textSearchPredicate(columns []string, text string) string {
+	operator := "MATCH_ANY"
+	if len(text) >= 2 && strings.HasPrefix(text, `"`) && strings.HasSuffix(text, `"`) {
+		operator = "MATCH_PHRASE"
+		text = text[1 : len(text)-1]
+	}
+
+	predicates := make([]string, 0, len(columns))
+	for _, column := range columns {
+		predicates = append(predicates, fmt.Sprintf(
+			`%s %s '%s'`,
+			column,
+			operator,
+			escapeStringLiteral(text),
+		))
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(predicates, " OR "))
+}
+
+func queryShowIndex(tableName string) string {
+	return fmt.Sprintf(`SHOW INDEX FROM %s`, tableName)
+}
+
+func queryDescribeTable(tableName string) string {
+	return fmt.Sprintf(`DESC %s`, tableName)
+}
+
 func queryGetDependencies(graphSchema *GraphSchemaMapping, tableName string, endTs time.Time, lookback time.Duration, location *time.Location) string {
 	template := `select
 %s, %s, sum(%s) as %s
@@ -193,3 +749,10 @@ group by %s, %s`
 		graphSchema.CalleeServiceName,
 	)
 }
+
+var stringLiteralReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `"`, `\"`)
+
+// escapeStringLiteral escapes a value so that it can be embedded in a quoted SQL string literal.
+func escapeStringLiteral(s string) string {
+	return stringLiteralReplacer.Replace(s)
+}
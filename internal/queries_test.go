@@ -17,8 +17,20 @@ func TestQueryGetTrace(t *testing.T) {
 
 	tableName := "otel2.traces"
 	traceID := "01020301000000000000000000000000"
-	want := `SELECT * FROM otel2.traces WHERE trace_id = "01020301000000000000000000000000"`
-	require.Equal(t, want, queryGetTrace(schema, tableName, traceID))
+	want := `SELECT * FROM otel2.traces WHERE trace_id = '01020301000000000000000000000000'`
+	require.Equal(t, want, queryGetTrace(schema, tableName, traceID, time.Time{}, time.Time{}, time.Local, 0))
+
+	ts := time.Date(2024, 1, 1, 1, 1, 1, 1000, time.Local)
+	want = `SELECT * FROM otel2.traces WHERE trace_id = '01020301000000000000000000000000' AND timestamp >= '2024-01-01 01:01:01.000001' AND timestamp <= '2024-01-01 02:01:01.000001' ORDER BY parent_span_id = '' DESC, timestamp LIMIT 1001`
+	require.Equal(t, want, queryGetTrace(schema, tableName, traceID, ts, ts.Add(time.Hour), time.Local, 1001))
+}
+
+func TestQueryGetTraceTimeRange(t *testing.T) {
+	indexSchema := &TraceIndexSchemaMapping{}
+	indexSchema.FillDefaultValues()
+
+	want := `SELECT MIN(start_time) AS start_time, MAX(end_time) AS end_time FROM otel2.trace_index WHERE trace_id = "01020301000000000000000000000000"`
+	require.Equal(t, want, queryGetTraceTimeRange(indexSchema, "otel2.trace_index", "01020301000000000000000000000000"))
 }
 
 func TestQueryGetServices(t *testing.T) {
@@ -27,7 +39,11 @@ func TestQueryGetServices(t *testing.T) {
 
 	tableName := "otel2.traces"
 	want := `SELECT service_name FROM otel2.traces GROUP BY service_name`
-	require.Equal(t, want, queryGetServices(schema, tableName))
+	require.Equal(t, want, queryGetServices(schema, tableName, time.Now(), 0, time.Local))
+
+	ts := time.Date(2024, 1, 1, 1, 1, 1, 1000, time.Local)
+	want = `SELECT service_name FROM otel2.traces WHERE timestamp >= '2024-01-01 00:01:01.000001' GROUP BY service_name`
+	require.Equal(t, want, queryGetServices(schema, tableName, ts, time.Hour, time.Local))
 }
 
 func TestQueryGetOperations(t *testing.T) {
@@ -39,11 +55,59 @@ func TestQueryGetOperations(t *testing.T) {
 		ServiceName: "test-service",
 	}
 	want := `SELECT span_name, span_kind FROM otel2.traces WHERE service_name = "test-service" GROUP BY span_name, span_kind`
-	require.Equal(t, want, queryGetOperations(schema, tableName, param))
+	require.Equal(t, want, queryGetOperations(schema, tableName, param, time.Now(), 0, time.Local))
 
 	param.SpanKind = "internal"
 	want = `SELECT span_name, span_kind FROM otel2.traces WHERE service_name = "test-service" AND span_kind = "SPAN_KIND_INTERNAL" GROUP BY span_name, span_kind`
-	require.Equal(t, want, queryGetOperations(schema, tableName, param))
+	require.Equal(t, want, queryGetOperations(schema, tableName, param, time.Now(), 0, time.Local))
+
+	ts := time.Date(2024, 1, 1, 1, 1, 1, 1000, time.Local)
+	want = `SELECT span_name, span_kind FROM otel2.traces WHERE service_name = "test-service" AND timestamp >= '2024-01-01 00:01:01.000001' AND span_kind = "SPAN_KIND_INTERNAL" GROUP BY span_name, span_kind`
+	require.Equal(t, want, queryGetOperations(schema, tableName, param, ts, time.Hour, time.Local))
+}
+
+func TestQueryOperationsTable(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+	opsSchema := &OperationsSchemaMapping{}
+	opsSchema.FillDefaultValues()
+
+	tableName := "otel2.operations"
+	ts := time.Date(2024, 1, 1, 1, 1, 1, 1000, time.Local)
+
+	want := `SELECT service_name FROM otel2.operations WHERE last_seen >= '2024-01-01 00:01:01.000001' GROUP BY service_name`
+	require.Equal(t, want, queryGetServicesFromOperationsTable(opsSchema, tableName, ts, time.Hour, time.Local))
+
+	param := spanstore.OperationQueryParameters{
+		ServiceName: "test-service",
+		SpanKind:    "server",
+	}
+	want = `SELECT span_name, span_kind FROM otel2.operations WHERE service_name = "test-service" AND span_kind = "SPAN_KIND_SERVER" GROUP BY span_name, span_kind`
+	require.Equal(t, want, queryGetOperationsFromOperationsTable(schema, opsSchema, tableName, param, ts, 0, time.Local))
+
+	param = spanstore.OperationQueryParameters{ServiceName: `x" OR "1"="1`}
+	want = `SELECT span_name, span_kind FROM otel2.operations WHERE service_name = "x\" OR \"1\"=\"1" GROUP BY span_name, span_kind`
+	require.Equal(t, want, queryGetOperationsFromOperationsTable(schema, opsSchema, tableName, param, ts, 0, time.Local))
+}
+
+func TestQueryCreateOperationsMaterializedView(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+	opsSchema := &OperationsSchemaMapping{}
+	opsSchema.FillDefaultValues()
+
+	mv := &MaterializedViewConfig{
+		RefreshInterval: time.Hour,
+		Properties:      map[string]string{"replication_num": "1"},
+	}
+	want := `CREATE MATERIALIZED VIEW IF NOT EXISTS otel2.operations
+BUILD IMMEDIATE REFRESH AUTO ON SCHEDULE EVERY 60 MINUTE
+DISTRIBUTED BY HASH(service_name) BUCKETS AUTO
+PROPERTIES ("replication_num" = "1")
+AS SELECT service_name AS service_name, span_name AS span_name, span_kind AS span_kind, MAX(timestamp) AS last_seen
+FROM otel2.traces
+GROUP BY service_name, span_name, span_kind`
+	require.Equal(t, want, queryCreateOperationsMaterializedView(schema, opsSchema, "otel2.operations", "otel2.traces", mv))
 }
 
 func TestQueryFindTraces(t *testing.T) {
@@ -53,7 +117,35 @@ func TestQueryFindTraces(t *testing.T) {
 	tableName := "otel2.traces"
 	traceIDs := []string{"01020301000000000000000000000000", "01020301000000000000000000000001"}
 	want := `SELECT * FROM otel2.traces WHERE trace_id IN ('01020301000000000000000000000000','01020301000000000000000000000001')`
-	require.Equal(t, want, queryFindTraces(schema, tableName, traceIDs))
+	require.Equal(t, want, queryFindTraces(schema, tableName, traceIDs, time.Time{}, time.Time{}, time.Local))
+
+	ts := time.Date(2024, 1, 1, 1, 1, 1, 1000, time.Local)
+	traceIDs = []string{"01020301000000000000000000000000"}
+	want = `SELECT * FROM otel2.traces WHERE trace_id IN ('01020301000000000000000000000000') AND timestamp >= '2024-01-01 01:01:01.000001' AND timestamp <= '2024-01-01 02:01:01.000001'`
+	require.Equal(t, want, queryFindTraces(schema, tableName, traceIDs, ts, ts.Add(time.Hour), time.Local))
+
+	want = ` ORDER BY FIELD(trace_id, '01020301000000000000000000000001','01020301000000000000000000000000')`
+	require.Equal(t, want, orderByTraceIDs(schema, []string{"01020301000000000000000000000001", "01020301000000000000000000000000"}))
+}
+
+func TestQueryFindTracesJoin(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	tableName := "otel2.traces"
+	ts := time.Date(2024, 1, 1, 1, 1, 1, 1000, time.Local)
+	param := &spanstore.TraceQueryParameters{
+		ServiceName:  "test-service",
+		StartTimeMin: ts,
+		NumTraces:    10,
+	}
+	traceIDsQuery := queryFindTraceIDs(schema, tableName, param, time.Local, nil, FindTracesSortNewest, nil, nil)
+
+	want := `SELECT s.* FROM otel2.traces s INNER JOIN (SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces WHERE service_name = 'test-service' AND timestamp >= '2024-01-01 01:01:01.000001' GROUP BY trace_id ORDER BY t DESC, trace_id DESC LIMIT 10) ids ON s.trace_id = ids.trace_id WHERE s.timestamp >= '2024-01-01 00:01:01.000001' ORDER BY ids.t DESC, ids.trace_id DESC`
+	require.Equal(t, want, queryFindTracesJoin(schema, tableName, traceIDsQuery, FindTracesSortNewest, ts.Add(-time.Hour), time.Time{}, time.Local))
+
+	want = `SELECT s.* FROM otel2.traces s INNER JOIN (SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces WHERE service_name = 'test-service' AND timestamp >= '2024-01-01 01:01:01.000001' GROUP BY trace_id ORDER BY t DESC, trace_id DESC LIMIT 10) ids ON s.trace_id = ids.trace_id ORDER BY ids.t DESC, ids.trace_id DESC`
+	require.Equal(t, want, queryFindTracesJoin(schema, tableName, traceIDsQuery, FindTracesSortNewest, time.Time{}, time.Time{}, time.Local))
 }
 
 func TestQueryFindTraceIDs(t *testing.T) {
@@ -88,9 +180,9 @@ func TestQueryFindTraceIDs(t *testing.T) {
 		"duration <= 60000000",
 	}
 	sort.Strings(middle_list)
-	last := ` GROUP BY trace_id ORDER BY t DESC LIMIT 10`
+	last := ` GROUP BY trace_id ORDER BY t DESC, trace_id DESC LIMIT 10`
 
-	realQuery := queryFindTraceIDs(schema, tableName, param, time.Local)
+	realQuery := queryFindTraceIDs(schema, tableName, param, time.Local, nil, FindTracesSortNewest, nil, nil)
 	fmt.Println(realQuery)
 	require.Equal(t, first, realQuery[:len(first)])
 	require.Equal(t, last, realQuery[len(realQuery)-len(last):])
@@ -100,6 +192,104 @@ func TestQueryFindTraceIDs(t *testing.T) {
 	require.Equal(t, middle_list, middle)
 }
 
+func TestQueryFindTraceIDsFullTextSearch(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	tableName := "otel2.traces"
+	textSearch := &FullTextSearchConfig{
+		Enabled: true,
+		TagKey:  "_text",
+		Columns: []string{"span_attributes", "status_message"},
+	}
+
+	param := &spanstore.TraceQueryParameters{
+		Tags:      map[string]string{"_text": "connection refused"},
+		NumTraces: 10,
+	}
+	want := `SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces WHERE (span_attributes MATCH_ANY 'connection refused' OR status_message MATCH_ANY 'connection refused') GROUP BY trace_id ORDER BY t DESC, trace_id DESC LIMIT 10`
+	require.Equal(t, want, queryFindTraceIDs(schema, tableName, param, time.Local, textSearch, FindTracesSortNewest, nil, nil))
+
+	param.Tags = map[string]string{"_text": `"it's down"`}
+	want = `SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces WHERE (span_attributes MATCH_PHRASE 'it\'s down' OR status_message MATCH_PHRASE 'it\'s down') GROUP BY trace_id ORDER BY t DESC, trace_id DESC LIMIT 10`
+	require.Equal(t, want, queryFindTraceIDs(schema, tableName, param, time.Local, textSearch, FindTracesSortNewest, nil, nil))
+
+	textSearch.Enabled = false
+	want = `SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces WHERE span_attributes['_text'] = '\"it\'s down\"' GROUP BY trace_id ORDER BY t DESC, trace_id DESC LIMIT 10`
+	require.Equal(t, want, queryFindTraceIDs(schema, tableName, param, time.Local, textSearch, FindTracesSortNewest, nil, nil))
+}
+
+func TestQueryFindTraceIDsEscaping(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	param := &spanstore.TraceQueryParameters{
+		ServiceName:   "x' OR '1'='1",
+		OperationName: `GET /it's`,
+		Tags:          map[string]string{"k": "x' OR '1'='1"},
+		NumTraces:     10,
+	}
+
+	realQuery := queryFindTraceIDs(schema, "otel2.traces", param, time.Local, nil, FindTracesSortNewest, nil, nil)
+	require.Contains(t, realQuery, `service_name = 'x\' OR \'1\'=\'1'`)
+	require.Contains(t, realQuery, `span_name = 'GET /it\'s'`)
+	require.Contains(t, realQuery, `span_attributes['k'] = 'x\' OR \'1\'=\'1'`)
+}
+
+func TestQueryFindTraceIDsSort(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	tableName := "otel2.traces"
+	param := &spanstore.TraceQueryParameters{
+		Tags:      map[string]string{TagKeySort: FindTracesSortMostErrors},
+		NumTraces: 10,
+	}
+
+	for sort, want := range map[string]string{
+		FindTracesSortNewest:     `SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces GROUP BY trace_id ORDER BY t DESC, trace_id DESC LIMIT 10`,
+		FindTracesSortOldest:     `SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces GROUP BY trace_id ORDER BY t ASC, trace_id ASC LIMIT 10`,
+		FindTracesSortLongest:    `SELECT trace_id, MIN(timestamp) AS t, MAX(duration) AS d FROM otel2.traces GROUP BY trace_id ORDER BY d DESC, t DESC, trace_id DESC LIMIT 10`,
+		FindTracesSortMostSpans:  `SELECT trace_id, MIN(timestamp) AS t, COUNT(*) AS c FROM otel2.traces GROUP BY trace_id ORDER BY c DESC, t DESC, trace_id DESC LIMIT 10`,
+		FindTracesSortMostErrors: `SELECT trace_id, MIN(timestamp) AS t, SUM(IF(status_code = "STATUS_CODE_ERROR", 1, 0)) AS e FROM otel2.traces GROUP BY trace_id ORDER BY e DESC, t DESC, trace_id DESC LIMIT 10`,
+	} {
+		require.Equal(t, want, queryFindTraceIDs(schema, tableName, param, time.Local, nil, sort, nil, nil), sort)
+	}
+}
+
+func TestQueryFindTraceIDsCursor(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	tableName := "otel2.traces"
+	param := &spanstore.TraceQueryParameters{
+		Tags:      map[string]string{TagKeyCursor: "ignored"},
+		NumTraces: 10,
+	}
+
+	cursor, err := parseTraceCursor((&traceCursor{
+		StartTime: time.Date(2024, 1, 1, 1, 1, 1, 1000, time.Local),
+		TraceID:   "01020301000000000000000000000000",
+	}).String())
+	require.NoError(t, err)
+
+	want := `SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces WHERE timestamp <= '2024-01-01 01:01:01.000001' GROUP BY trace_id HAVING MIN(timestamp) < '2024-01-01 01:01:01.000001' OR (MIN(timestamp) = '2024-01-01 01:01:01.000001' AND trace_id < '01020301000000000000000000000000') ORDER BY t DESC, trace_id DESC LIMIT 10`
+	require.Equal(t, want, queryFindTraceIDs(schema, tableName, param, time.Local, nil, FindTracesSortNewest, cursor, nil))
+
+	// without a lower bound, the spans before the cursor are not looked up
+	want = `SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces GROUP BY trace_id HAVING MIN(timestamp) > '2024-01-01 01:01:01.000001' OR (MIN(timestamp) = '2024-01-01 01:01:01.000001' AND trace_id > '01020301000000000000000000000000') ORDER BY t ASC, trace_id ASC LIMIT 10`
+	require.Equal(t, want, queryFindTraceIDs(schema, tableName, param, time.Local, nil, FindTracesSortOldest, cursor, nil))
+
+	// the traces with matching spans between StartTimeMin and the cursor were returned by previous pages
+	param.ServiceName = "checkout"
+	param.StartTimeMin = time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	want = `SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces WHERE service_name = 'checkout' AND timestamp >= '2024-01-01 00:00:00' AND timestamp >= '2024-01-01 01:01:01.000001' AND trace_id NOT IN (SELECT trace_id FROM otel2.traces WHERE service_name = 'checkout' AND timestamp >= '2024-01-01 00:00:00' AND timestamp < '2024-01-01 01:01:01.000001') GROUP BY trace_id HAVING MIN(timestamp) > '2024-01-01 01:01:01.000001' OR (MIN(timestamp) = '2024-01-01 01:01:01.000001' AND trace_id > '01020301000000000000000000000000') ORDER BY t ASC, trace_id ASC LIMIT 10`
+	require.Equal(t, want, queryFindTraceIDs(schema, tableName, param, time.Local, nil, FindTracesSortOldest, cursor, nil))
+
+	_, err = parseTraceCursor("MTIzOicgT1IgMT0x")
+	require.Error(t, err)
+}
+
 func TestQueryGetDependencies(t *testing.T) {
 	schema := &GraphSchemaMapping{}
 	schema.FillDefaultValues()
@@ -114,3 +304,91 @@ and timestamp <= '2024-01-01 01:01:01.000001'
 group by caller_service_name, callee_service_name`
 	require.Equal(t, want, queryGetDependencies(schema, tableName, ts, time.Hour, time.Local))
 }
+
+func TestQueryTraceSummaries(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	want := `SELECT trace_id, MIN(timestamp) AS t, MICROSECONDS_DIFF(MAX(MICROSECONDS_ADD(timestamp, duration)), MIN(timestamp)) AS d, COUNT(*) AS c, ` +
+		`MAX(IF((parent_span_id = '' OR parent_span_id IS NULL), service_name, NULL)) AS root_service_name, ` +
+		`MAX(IF((parent_span_id = '' OR parent_span_id IS NULL), span_name, NULL)) AS root_span_name FROM otel.traces ` +
+		`WHERE timestamp >= '2024-01-01 00:00:00' AND trace_id IN (SELECT 1) GROUP BY trace_id HAVING d > 1000 ORDER BY t DESC, trace_id DESC LIMIT 20`
+	require.Equal(t, want, queryTraceSummaries(schema, "otel.traces", "trace_id IN (SELECT 1)", []string{"d > 1000"}, []string{"timestamp >= '2024-01-01 00:00:00'"}, 20))
+}
+
+func TestQueryMetrics(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	origin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := `SELECT service_name, span_name, FLOOR(SECONDS_DIFF(timestamp, '2024-01-01 00:00:00') / 5) AS b, COUNT(*) AS c, ` +
+		`SUM(IF(status_code = 'STATUS_CODE_ERROR', 1, 0)) AS e FROM otel.traces ` +
+		`WHERE service_name IN ('checkout', 'o\'k') AND span_kind IN ('SPAN_KIND_SERVER') ` +
+		`AND timestamp >= '2023-12-31 23:59:00' AND timestamp <= '2024-01-01 01:00:00' GROUP BY service_name, span_name, b`
+	require.Equal(t, want, queryMetrics(schema, "otel.traces", []string{"checkout", "o'k"}, []string{"SPAN_KIND_SERVER"}, true,
+		origin, origin.Add(-time.Minute), origin.Add(time.Hour), 5*time.Second, time.UTC))
+
+	want = `SELECT service_name, FLOOR(SECONDS_DIFF(timestamp, '2024-01-01 00:00:00') / 5) AS b, COUNT(*) AS c, ` +
+		`SUM(IF(status_code = 'STATUS_CODE_ERROR', 1, 0)) AS e FROM otel.traces ` +
+		`WHERE service_name IN ('checkout') AND span_kind IN ('SPAN_KIND_SERVER') ` +
+		`AND timestamp >= '2023-12-31 23:59:00' AND timestamp <= '2024-01-01 01:00:00' GROUP BY service_name, b`
+	require.Equal(t, want, queryMetrics(schema, "otel.traces", []string{"checkout"}, []string{"SPAN_KIND_SERVER"}, false,
+		origin, origin.Add(-time.Minute), origin.Add(time.Hour), 5*time.Second, time.UTC))
+}
+
+func TestQueryLatencies(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	origin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := `SELECT service_name, span_name, FLOOR(SECONDS_DIFF(timestamp, '2024-01-01 00:00:00') / 5) + n AS p, ` +
+		`PERCENTILE_APPROX(duration, 0.95) AS q FROM otel.traces LATERAL VIEW EXPLODE_NUMBERS(12) w AS n ` +
+		`WHERE service_name IN ('checkout') AND span_kind IN ('SPAN_KIND_SERVER') ` +
+		`AND timestamp >= '2023-12-31 23:59:05' AND timestamp <= '2024-01-01 01:00:00' GROUP BY service_name, span_name, p HAVING p >= 0 AND p < 721`
+	require.Equal(t, want, queryLatencies(schema, "otel.traces", []string{"checkout"}, []string{"SPAN_KIND_SERVER"}, true,
+		origin, origin.Add(-55*time.Second), origin.Add(time.Hour), 5*time.Second, 12, 721, 0.95, time.UTC))
+}
+
+func TestQueryOperationStats(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	predicates := []string{"service_name = 'checkout'"}
+	want := `SELECT span_name, COUNT(*) AS c, SUM(IF(status_code = 'STATUS_CODE_ERROR', 1, 0)) AS e, ` +
+		`PERCENTILE_APPROX(duration, 0.5) AS p50, PERCENTILE_APPROX(duration, 0.99) AS p99 ` +
+		`FROM otel.traces WHERE service_name = 'checkout' GROUP BY span_name`
+	require.Equal(t, want+` ORDER BY p99 DESC, span_name LIMIT 10`, queryOperationStats(schema, "otel.traces", predicates, false, 10))
+	require.Equal(t, want+` HAVING e > 0 ORDER BY e / c DESC, e DESC, span_name LIMIT 10`, queryOperationStats(schema, "otel.traces", predicates, true, 10))
+}
+
+func TestQueryDurationHistogram(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	want := `SELECT FLOOR(LOG2(GREATEST(duration, 1))) AS k, COUNT(*) AS c FROM otel.traces ` +
+		`WHERE service_name = 'checkout' AND span_name = 'GET' GROUP BY k ORDER BY k`
+	require.Equal(t, want, queryDurationHistogram(schema, "otel.traces", []string{"service_name = 'checkout'", "span_name = 'GET'"}))
+}
+
+func TestQueryExampleTraceIDs(t *testing.T) {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+
+	want := `SELECT k, trace_id FROM (SELECT span_name AS k, trace_id, ROW_NUMBER() OVER (PARTITION BY span_name ORDER BY duration DESC) AS r ` +
+		`FROM otel.traces WHERE service_name = 'checkout' AND span_name IN ('GET', 'POST')) examples WHERE r <= 3 ORDER BY k, r`
+	require.Equal(t, want, queryExampleTraceIDs(schema, "otel.traces", "span_name", []string{"service_name = 'checkout'", "span_name IN ('GET', 'POST')"}, 3))
+}
+
+func TestQueryGetLogs(t *testing.T) {
+	schema := &LogsSchemaMapping{}
+	schema.FillDefaultValues()
+
+	traceID := "01020301000000000000000000000000"
+	want := `SELECT * FROM otel.otel_logs WHERE trace_id = '01020301000000000000000000000000' ORDER BY timestamp LIMIT 100`
+	require.Equal(t, want, queryGetLogs(schema, "otel.otel_logs", traceID, "", time.Time{}, time.Time{}, time.UTC, 100))
+
+	ts := time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC)
+	want = `SELECT * FROM otel.otel_logs WHERE trace_id = '01020301000000000000000000000000' AND span_id = '0102030100000000' ` +
+		`AND timestamp >= '2024-01-01 01:01:01' AND timestamp <= '2024-01-01 02:01:01' ORDER BY timestamp LIMIT 100`
+	require.Equal(t, want, queryGetLogs(schema, "otel.otel_logs", traceID, "0102030100000000", ts, ts.Add(time.Hour), time.UTC, 100))
+}
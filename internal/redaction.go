@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+)
+
+// redactionsTotal counts removed, hashed and masked values, served at /debug/vars of the admin port.
+var redactionsTotal = expvar.NewInt("jaeger_doris_redactions_total")
+
+// redactor applies the redaction rules to spans before they are returned.
+type redactor struct {
+	salt  string
+	rules []*redactionRule
+}
+
+type redactionRule struct {
+	services map[string]struct{}
+	tenants  map[string]struct{}
+	deny     *keyMatcher
+	allow    *keyMatcher
+	hash     *keyMatcher
+	masks    []redactionMask
+}
+
+type redactionMask struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+func newRedactor(cfg *RedactionConfig) (*redactor, error) {
+	r := &redactor{
+		salt:  cfg.HashSalt,
+		rules: make([]*redactionRule, 0, len(cfg.Rules)),
+	}
+
+	for _, ruleCfg := range cfg.Rules {
+		rule := &redactionRule{
+			services: toSet(ruleCfg.Services),
+			tenants:  toSet(ruleCfg.Tenants),
+			deny:     newKeyMatcher(ruleCfg.DenyKeys),
+			allow:    newKeyMatcher(ruleCfg.AllowKeys),
+			hash:     newKeyMatcher(ruleCfg.HashKeys),
+		}
+		for _, mask := range ruleCfg.Masks {
+			re, err := regexp.Compile(mask.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			rule.masks = append(rule.masks, redactionMask{re: re, replacement: mask.Replacement})
+		}
+		r.rules = append(r.rules, rule)
+	}
+
+	return r, nil
+}
+
+// Redact applies the rules matching the service of the span and the tenant of ctx to the span
+// tags, log fields and process tags.
+func (r *redactor) Redact(ctx context.Context, span *model.Span) *model.Span {
+	if r == nil || len(r.rules) == 0 {
+		return span
+	}
+
+	serviceName := ""
+	if span.Process != nil {
+		serviceName = span.Process.ServiceName
+	}
+	tenant := tenancy.GetTenant(ctx)
+
+	count := 0
+	for _, rule := range r.rules {
+		if !rule.matches(serviceName, tenant) {
+			continue
+		}
+
+		var n int
+		span.Tags, n = rule.apply(r.salt, span.Tags)
+		count += n
+		for i := range span.Logs {
+			span.Logs[i].Fields, n = rule.apply(r.salt, span.Logs[i].Fields)
+			count += n
+		}
+		if span.Process != nil {
+			span.Process.Tags, n = rule.apply(r.salt, span.Process.Tags)
+			count += n
+		}
+	}
+
+	if count > 0 {
+		redactionsTotal.Add(int64(count))
+	}
+	return span
+}
+
+func (rule *redactionRule) matches(serviceName string, tenant string) bool {
+	if len(rule.services) > 0 {
+		if _, ok := rule.services[serviceName]; !ok {
+			return false
+		}
+	}
+	return rule.matchesTenant(tenant)
+}
+
+// apply redacts kvs in place, it returns the remaining key values and the number of redactions.
+func (rule *redactionRule) apply(salt string, kvs []model.KeyValue) ([]model.KeyValue, int) {
+	count := 0
+	kept := kvs[:0]
+	for _, kv := range kvs {
+		if rule.deny.Match(kv.Key) || (!rule.allow.Empty() && !rule.allow.Match(kv.Key)) {
+			count++
+			continue
+		}
+
+		if rule.hash.Match(kv.Key) {
+			sum := sha256.Sum256([]byte(salt + kv.AsString()))
+			kv = model.String(kv.Key, "sha256:"+hex.EncodeToString(sum[:]))
+			count++
+		} else if kv.VType == model.ValueType_STRING {
+			for _, mask := range rule.masks {
+				if mask.re.MatchString(kv.VStr) {
+					kv.VStr = mask.re.ReplaceAllString(kv.VStr, mask.replacement)
+					count++
+				}
+			}
+		}
+
+		kept = append(kept, kv)
+	}
+	return kept, count
+}
+
+// attributeGuard rewrites a predicate on the value of an attribute key, so that it does not match
+// spans whose value of the key is redacted. Otherwise callers could recover redacted values from
+// which traces match. A nil guard leaves predicates unchanged.
+type attributeGuard func(serviceColumn string, key string, predicate string) string
+
+// apply rewrites predicate, an empty key stands for the values of all keys, e.g. of full-text searches.
+func (g attributeGuard) apply(serviceColumn string, key string, predicate string) string {
+	if g == nil {
+		return predicate
+	}
+	return g(serviceColumn, key, predicate)
+}
+
+// attributeGuard returns the guard of the rules applied to the spans returned to callers of tenant:
+// predicates on keys which are removed, hashed or masked are restricted to the other services, or
+// replaced by FALSE if a rule applies to all services.
+func (r *redactor) attributeGuard(tenant string) attributeGuard {
+	if r == nil || len(r.rules) == 0 {
+		return nil
+	}
+
+	return func(serviceColumn string, key string, predicate string) string {
+		services := make([]string, 0)
+		for _, rule := range r.rules {
+			if !rule.matchesTenant(tenant) || !rule.protects(key) {
+				continue
+			}
+			if len(rule.services) == 0 {
+				return "FALSE"
+			}
+			for service := range rule.services {
+				services = append(services, service)
+			}
+		}
+		if len(services) == 0 {
+			return predicate
+		}
+
+		sort.Strings(services)
+		return fmt.Sprintf(`(%s NOT IN (%s) AND %s)`, serviceColumn, quoteStringLiterals(services), predicate)
+	}
+}
+
+func (rule *redactionRule) matchesTenant(tenant string) bool {
+	if len(rule.tenants) == 0 {
+		return true
+	}
+	_, ok := rule.tenants[tenant]
+	return ok
+}
+
+// protects returns whether the rule may change the value of key, or of any key if key is empty.
+// Masks apply to the values of all keys.
+func (rule *redactionRule) protects(key string) bool {
+	if len(rule.masks) > 0 {
+		return true
+	}
+	if key == "" {
+		return !rule.deny.Empty() || !rule.allow.Empty() || !rule.hash.Empty()
+	}
+	return rule.deny.Match(key) || (!rule.allow.Empty() && !rule.allow.Match(key)) || rule.hash.Match(key)
+}
+
+// keyMatcher matches keys exactly, or by prefix for keys ending with *.
+type keyMatcher struct {
+	keys     map[string]struct{}
+	prefixes []string
+}
+
+func newKeyMatcher(keys []string) *keyMatcher {
+	m := &keyMatcher{keys: make(map[string]struct{}, len(keys))}
+	for _, key := range keys {
+		if prefix, ok := strings.CutSuffix(key, "*"); ok {
+			m.prefixes = append(m.prefixes, prefix)
+			continue
+		}
+		m.keys[key] = struct{}{}
+	}
+	return m
+}
+
+func (m *keyMatcher) Empty() bool {
+	return len(m.keys) == 0 && len(m.prefixes) == 0
+}
+
+func (m *keyMatcher) Match(key string) bool {
+	if _, ok := m.keys[key]; ok {
+		return true
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor(t *testing.T) {
+	r, err := newRedactor(&RedactionConfig{
+		HashSalt: "salt",
+		Rules: []*RedactionRule{
+			{
+				DenyKeys: []string{"http.request.header.*"},
+				HashKeys: []string{"user.id"},
+				Masks:    []*RedactionMask{{Pattern: `[\w.+-]+@[\w-]+\.\w+`, Replacement: "<email>"}},
+			},
+			{
+				Services:  []string{"checkout"},
+				Tenants:   []string{"acme"},
+				AllowKeys: []string{"user.id", "message"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	newSpan := func() *model.Span {
+		return &model.Span{
+			Tags: []model.KeyValue{
+				model.String("http.request.header.authorization", "Bearer token"),
+				model.Int64("user.id", 42),
+				model.String("db.name", "orders"),
+			},
+			Logs: []model.Log{
+				{Fields: []model.KeyValue{model.String("message", "sent to jane.doe@example.com")}},
+			},
+			Process: &model.Process{
+				ServiceName: "checkout",
+				Tags:        []model.KeyValue{model.String("host.name", "web-1")},
+			},
+		}
+	}
+
+	before := redactionsTotal.Value()
+	span := r.Redact(context.Background(), newSpan())
+	require.Equal(t, []model.KeyValue{
+		model.String("user.id", "sha256:ba5bf48c9d94fef61432ae21b346d1307be9476c9c8e98d111366abbb69d45cd"),
+		model.String("db.name", "orders"),
+	}, span.Tags)
+	require.Equal(t, "sent to <email>", span.Logs[0].Fields[0].VStr)
+	require.Len(t, span.Process.Tags, 1)
+	require.Equal(t, int64(3), redactionsTotal.Value()-before)
+
+	ctx := tenancy.WithTenant(context.Background(), "acme")
+	span = r.Redact(ctx, newSpan())
+	require.Len(t, span.Tags, 1)
+	require.Equal(t, "user.id", span.Tags[0].Key)
+	require.Len(t, span.Logs[0].Fields, 1)
+	require.Empty(t, span.Process.Tags)
+}
+
+func TestRedactorAttributeGuard(t *testing.T) {
+	var none *redactor
+	require.Nil(t, none.attributeGuard("acme"))
+	require.Equal(t, "x = 1", attributeGuard(nil).apply("service_name", "user.id", "x = 1"))
+
+	r, err := newRedactor(&RedactionConfig{
+		Rules: []*RedactionRule{
+			{Tenants: []string{"acme"}, DenyKeys: []string{"user.*"}},
+			{Services: []string{"checkout", "billing"}, HashKeys: []string{"card.number"}},
+		},
+	})
+	require.NoError(t, err)
+
+	guard := r.attributeGuard("acme")
+	require.Equal(t, "FALSE", guard.apply("service_name", "user.email", "x = 1"))
+	require.Equal(t, "FALSE", guard.apply("service_name", "", "x = 1"))
+	require.Equal(t, `(service_name NOT IN ('billing', 'checkout') AND x = 1)`, guard.apply("service_name", "card.number", "x = 1"))
+	require.Equal(t, "x = 1", guard.apply("service_name", "http.method", "x = 1"))
+
+	guard = r.attributeGuard("other")
+	require.Equal(t, "x = 1", guard.apply("service_name", "user.email", "x = 1"))
+	require.Equal(t, `(service_name NOT IN ('billing', 'checkout') AND x = 1)`, guard.apply("service_name", "", "x = 1"))
+
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+	query := queryFindTraceIDs(schema, "otel2.traces", &spanstore.TraceQueryParameters{
+		Tags:      map[string]string{"user.email": "jane@example.com"},
+		NumTraces: 10,
+	}, time.Local, nil, FindTracesSortNewest, nil, r.attributeGuard("acme"))
+	require.Equal(t, `SELECT trace_id, MIN(timestamp) AS t FROM otel2.traces WHERE FALSE GROUP BY trace_id ORDER BY t DESC, trace_id DESC LIMIT 10`, query)
+}
@@ -0,0 +1,391 @@
+package internal
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/spf13/viper"
+)
+
+const (
+	SchemaProfileOTelDorisExporterV1 = "otel-doris-exporter-v1" // the tables of the otlp doris exporter with MAP attributes, the default
+	SchemaProfileOTelDorisExporterV2 = "otel-doris-exporter-v2" // the tables of newer otlp doris exporters with VARIANT attributes
+	SchemaProfileJaegerNative        = "jaeger-native"          // spans written in the terms of the jaeger model, like its Elasticsearch documents
+	SchemaProfileCustom              = "custom"                 // loaded from doris.schema_profile_file
+)
+
+// The encodings of the trace and span ID columns.
+const (
+	IDEncodingHex    = "hex"    // lowercase hex strings, the default
+	IDEncodingBinary = "binary" // raw bytes, e.g. VARBINARY, read with HEX and compared with UNHEX
+)
+
+// The formats of the events and links columns.
+const (
+	NestedFormatOTel   = "otel"   // JSON arrays of the otlp doris exporter, the default
+	NestedFormatJaeger = "jaeger" // JSON arrays of jaeger logs and references, see jaegerLog and jaegerReference
+)
+
+// SchemaProfile describes the layout of a span table: the default names of the columns, the default
+// types of the attribute columns when they cannot be detected, how span kinds and status codes are
+// stored, and how IDs, events and links are decoded. Columns of doris.schema_mapping override the
+// names of the profile.
+type SchemaProfile struct {
+	Name          string            `yaml:"name" mapstructure:"name"`
+	Base          string            `yaml:"base" mapstructure:"base"` // built-in profile a custom profile extends, defaults to otel-doris-exporter-v1
+	SchemaMapping *SchemaMapping    `yaml:"schema_mapping" mapstructure:"schema_mapping"`
+	ColumnTypes   map[string]string `yaml:"column_types" mapstructure:"column_types"`   // attribute column: map, json or variant
+	SpanKinds     map[string]string `yaml:"span_kinds" mapstructure:"span_kinds"`       // stored value of each OTel span kind, e.g. SPAN_KIND_SERVER: server
+	StatusCodes   map[string]string `yaml:"status_codes" mapstructure:"status_codes"`   // stored value of each OTel status code, e.g. STATUS_CODE_ERROR: ERROR
+	IDEncoding    string            `yaml:"id_encoding" mapstructure:"id_encoding"`     // trace_id, span_id and parent_span_id: hex or binary
+	EventsFormat  string            `yaml:"events_format" mapstructure:"events_format"` // events column: otel or jaeger
+	LinksFormat   string            `yaml:"links_format" mapstructure:"links_format"`   // links column: otel or jaeger
+}
+
+var builtinSchemaProfiles = map[string]*SchemaProfile{
+	SchemaProfileOTelDorisExporterV1: {
+		Name:          SchemaProfileOTelDorisExporterV1,
+		SchemaMapping: &SchemaMapping{},
+	},
+	SchemaProfileOTelDorisExporterV2: {
+		Name:          SchemaProfileOTelDorisExporterV2,
+		SchemaMapping: &SchemaMapping{},
+		ColumnTypes: map[string]string{
+			"span_attributes":     ColumnTypeVariant,
+			"resource_attributes": ColumnTypeVariant,
+		},
+	},
+	SchemaProfileJaegerNative: {
+		Name: SchemaProfileJaegerNative,
+		SchemaMapping: &SchemaMapping{
+			Timestamp:          "start_time",
+			SpanName:           "operation_name",
+			SpanAttributes:     "tags",
+			Events:             "logs",
+			Links:              "references",
+			ResourceAttributes: "process_tags",
+		},
+		ColumnTypes: map[string]string{
+			"tags":         ColumnTypeJSON,
+			"process_tags": ColumnTypeJSON,
+		},
+		SpanKinds: map[string]string{
+			SpanKindUnspecified: "",
+			SpanKindInternal:    otelToJeagerSpanKind[SpanKindInternal],
+			SpanKindServer:      otelToJeagerSpanKind[SpanKindServer],
+			SpanKindClient:      otelToJeagerSpanKind[SpanKindClient],
+			SpanKindProducer:    otelToJeagerSpanKind[SpanKindProducer],
+			SpanKindConsumer:    otelToJeagerSpanKind[SpanKindConsumer],
+		},
+		StatusCodes: map[string]string{
+			StatusCodeUnset: "",
+			StatusCodeOk:    "OK",
+			StatusCodeError: "ERROR",
+		},
+		EventsFormat: NestedFormatJaeger,
+		LinksFormat:  NestedFormatJaeger,
+	},
+}
+
+// loadSchemaProfile returns the built-in profile name, or the custom profile of file.
+func loadSchemaProfile(name string, file string) (*SchemaProfile, error) {
+	if name != SchemaProfileCustom {
+		if file != "" {
+			return nil, errors.New("doris.schema_profile_file requires doris.schema_profile: custom")
+		}
+		profile, ok := builtinSchemaProfiles[name]
+		if !ok {
+			return nil, fmt.Errorf("doris.schema_profile must be one of %s, %s, %s, %s",
+				SchemaProfileOTelDorisExporterV1, SchemaProfileOTelDorisExporterV2, SchemaProfileJaegerNative, SchemaProfileCustom)
+		}
+		return profile, nil
+	}
+
+	if file == "" {
+		return nil, errors.New("doris.schema_profile_file must be specified for the custom schema profile")
+	}
+	vip := viper.New()
+	vip.SetConfigFile(file)
+	err := vip.ReadInConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read doris.schema_profile_file: %w", err)
+	}
+	profile := &SchemaProfile{}
+	err = vip.Unmarshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read doris.schema_profile_file: %w", err)
+	}
+	return profile.withBase()
+}
+
+// withBase fills in the unset parts of a custom profile from its base profile.
+func (p *SchemaProfile) withBase() (*SchemaProfile, error) {
+	if p.Base == "" {
+		p.Base = SchemaProfileOTelDorisExporterV1
+	}
+	base, ok := builtinSchemaProfiles[p.Base]
+	if !ok {
+		return nil, fmt.Errorf("unknown base %q of the custom schema profile", p.Base)
+	}
+
+	if p.SchemaMapping == nil {
+		p.SchemaMapping = &SchemaMapping{}
+	}
+	fillEmptyFields(p.SchemaMapping, base.SchemaMapping)
+	p.ColumnTypes = mergeMaps(base.ColumnTypes, p.ColumnTypes)
+	// viper lowercases the keys of maps
+	p.SpanKinds = mergeMaps(base.SpanKinds, upperKeys(p.SpanKinds))
+	p.StatusCodes = mergeMaps(base.StatusCodes, upperKeys(p.StatusCodes))
+	if p.IDEncoding == "" {
+		p.IDEncoding = base.IDEncoding
+	}
+	if p.EventsFormat == "" {
+		p.EventsFormat = base.EventsFormat
+	}
+	if p.LinksFormat == "" {
+		p.LinksFormat = base.LinksFormat
+	}
+
+	var err error
+	switch p.IDEncoding {
+	case "", IDEncodingHex, IDEncodingBinary:
+	default:
+		err = errors.Join(err, fmt.Errorf("id_encoding must be one of %s, %s", IDEncodingHex, IDEncodingBinary))
+	}
+	for name, format := range map[string]string{"events_format": p.EventsFormat, "links_format": p.LinksFormat} {
+		switch format {
+		case "", NestedFormatOTel, NestedFormatJaeger:
+		default:
+			err = errors.Join(err, fmt.Errorf("%s must be one of %s, %s", name, NestedFormatOTel, NestedFormatJaeger))
+		}
+	}
+	for column, typ := range p.ColumnTypes {
+		switch typ {
+		case ColumnTypeMap, ColumnTypeJSON, ColumnTypeVariant:
+		default:
+			err = errors.Join(err, fmt.Errorf("column_types.%s must be one of %s, %s, %s", column, ColumnTypeMap, ColumnTypeJSON, ColumnTypeVariant))
+		}
+	}
+	for kind := range p.SpanKinds {
+		if _, ok := otelToJeagerSpanKind[kind]; !ok && kind != SpanKindUnspecified {
+			err = errors.Join(err, fmt.Errorf("unknown span kind %q in span_kinds", kind))
+		}
+	}
+	for code := range p.StatusCodes {
+		if code != StatusCodeUnset && code != StatusCodeOk && code != StatusCodeError {
+			err = errors.Join(err, fmt.Errorf("unknown status code %q in status_codes", code))
+		}
+	}
+	return p, err
+}
+
+// applySchemaProfile fills in the columns which are not set from the profile, and then from the
+// default columns.
+func (s *SchemaMapping) applySchemaProfile(profile *SchemaProfile) {
+	fillEmptyFields(s, profile.SchemaMapping)
+	s.FillDefaultValues()
+
+	s.defaultColumnTypes = profile.ColumnTypes
+	s.spanKinds, s.otelSpanKinds = mappingAndInverse(profile.SpanKinds)
+	s.statusCodes, s.otelStatusCodes = mappingAndInverse(profile.StatusCodes)
+	s.idEncoding = profile.IDEncoding
+	s.eventsFormat = profile.EventsFormat
+	s.linksFormat = profile.LinksFormat
+}
+
+// decodeID returns the hex string of a stored trace or span ID.
+func (s *SchemaMapping) decodeID(v string) string {
+	if s.idEncoding == IDEncodingBinary {
+		return hex.EncodeToString([]byte(v))
+	}
+	return v
+}
+
+// idLiteral returns the SQL literal of the hex string of a trace or span ID, as it is stored.
+func (s *SchemaMapping) idLiteral(id string) string {
+	if s.idEncoding == IDEncodingBinary {
+		return fmt.Sprintf(`UNHEX('%s')`, escapeStringLiteral(id))
+	}
+	return fmt.Sprintf(`'%s'`, escapeStringLiteral(id))
+}
+
+// jaegerLog is a log of the logs column in the jaeger format, like the logs of the Elasticsearch
+// documents of jaeger: the timestamp in microseconds since the epoch and typed fields.
+type jaegerLog struct {
+	Timestamp json.Number       `json:"timestamp"`
+	Fields    []*jaegerKeyValue `json:"fields"`
+}
+
+type jaegerKeyValue struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"` // string, bool, int64, float64 or binary
+	Value any    `json:"value"`
+}
+
+// jaegerReference is a reference of the links column in the jaeger format.
+type jaegerReference struct {
+	RefType string `json:"refType"` // CHILD_OF or FOLLOWS_FROM
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+// unmarshalEvents decodes the events column. Jaeger logs become events named by their "event" field.
+func (s *SchemaMapping) unmarshalEvents(data string, location *time.Location) ([]*otelEvent, error) {
+	if s.eventsFormat != NestedFormatJaeger {
+		events := []*otelEvent{}
+		err := unmarshalUseNumber(data, &events)
+		return events, err
+	}
+
+	logs := []*jaegerLog{}
+	err := unmarshalUseNumber(data, &logs)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*otelEvent, 0, len(logs))
+	for _, log := range logs {
+		micros, err := log.Timestamp.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp of log: %w", err)
+		}
+		event := &otelEvent{
+			Timestamp:  time.UnixMicro(micros).In(location).Format(timeFormat),
+			Attributes: make(map[string]any, len(log.Fields)),
+		}
+		for _, field := range log.Fields {
+			if name, ok := field.Value.(string); ok && field.Key == SpanLogFieldKeyEvent {
+				event.Name = name
+				continue
+			}
+			event.Attributes[field.Key] = field.value()
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// unmarshalLinks decodes the links column. The CHILD_OF reference of the parent of jaeger spans
+// is skipped, since the parent is read from parent_span_id, the other references become links.
+func (s *SchemaMapping) unmarshalLinks(data string, parentSpanID string) ([]*otelLink, error) {
+	if s.linksFormat != NestedFormatJaeger {
+		links := []*otelLink{}
+		err := unmarshalUseNumber(data, &links)
+		return links, err
+	}
+
+	references := []*jaegerReference{}
+	err := unmarshalUseNumber(data, &references)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]*otelLink, 0, len(references))
+	for _, ref := range references {
+		if ref.RefType == model.ChildOf.String() && ref.SpanID == parentSpanID {
+			continue
+		}
+		links = append(links, &otelLink{TraceID: ref.TraceID, SpanID: ref.SpanID})
+	}
+	return links, nil
+}
+
+// value returns the value of the field like the attributes of the otlp doris exporter, binary
+// values are stored as hex strings like jaeger stores them.
+func (kv *jaegerKeyValue) value() any {
+	str, ok := kv.Value.(string)
+	if !ok {
+		return kv.Value
+	}
+	switch kv.Type {
+	case "bool":
+		if b, err := strconv.ParseBool(str); err == nil {
+			return b
+		}
+	case "int64", "float64":
+		return json.Number(str)
+	case "binary":
+		if b, err := hex.DecodeString(str); err == nil {
+			return b
+		}
+	}
+	return str
+}
+
+// spanKindValue returns the stored value of an OTel span kind, e.g. SPAN_KIND_SERVER.
+func (s *SchemaMapping) spanKindValue(kind string) string {
+	if v, ok := s.spanKinds[kind]; ok {
+		return v
+	}
+	return kind
+}
+
+// otelSpanKind returns the OTel span kind of a stored value.
+func (s *SchemaMapping) otelSpanKind(v string) string {
+	if kind, ok := s.otelSpanKinds[v]; ok {
+		return kind
+	}
+	return v
+}
+
+// statusCodeValue returns the stored value of an OTel status code, e.g. STATUS_CODE_ERROR.
+func (s *SchemaMapping) statusCodeValue(code string) string {
+	if v, ok := s.statusCodes[code]; ok {
+		return v
+	}
+	return code
+}
+
+// otelStatusCode returns the OTel status code of a stored value.
+func (s *SchemaMapping) otelStatusCode(v string) string {
+	if code, ok := s.otelStatusCodes[v]; ok {
+		return code
+	}
+	return v
+}
+
+// fillEmptyFields sets the empty string fields of dst to the fields of src.
+func fillEmptyFields(dst *SchemaMapping, src *SchemaMapping) {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for i := 0; i < d.NumField(); i++ {
+		field := d.Field(i)
+		if field.CanSet() && field.Kind() == reflect.String && field.String() == "" {
+			field.SetString(s.Field(i).String())
+		}
+	}
+}
+
+func mergeMaps(base map[string]string, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+func upperKeys(m map[string]string) map[string]string {
+	upper := make(map[string]string, len(m))
+	for k, v := range m {
+		upper[strings.ToUpper(k)] = v
+	}
+	return upper
+}
+
+func mappingAndInverse(m map[string]string) (map[string]string, map[string]string) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	inverse := make(map[string]string, len(m))
+	for k, v := range m {
+		inverse[v] = k
+	}
+	return m, inverse
+}
@@ -0,0 +1,293 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const legacySchemaProfile = `name: legacy
+schema_mapping:
+  timestamp: start_time
+  span_name: operation_name
+  span_attributes: tags
+  resource_attributes: process_tags
+column_types:
+  tags: json
+  process_tags: json
+span_kinds:
+  SPAN_KIND_INTERNAL: internal
+  SPAN_KIND_SERVER: server
+  SPAN_KIND_CLIENT: client
+status_codes:
+  STATUS_CODE_OK: OK
+  STATUS_CODE_ERROR: ERROR
+`
+
+func legacySchema(t *testing.T) *SchemaMapping {
+	file := filepath.Join(t.TempDir(), "profile.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(legacySchemaProfile), 0o600))
+
+	profile, err := loadSchemaProfile(SchemaProfileCustom, file)
+	require.NoError(t, err)
+
+	schema := &SchemaMapping{}
+	schema.applySchemaProfile(profile)
+	return schema
+}
+
+func TestSchemaProfileDefault(t *testing.T) {
+	profile, err := loadSchemaProfile(SchemaProfileOTelDorisExporterV1, "")
+	require.NoError(t, err)
+
+	schema := &SchemaMapping{Timestamp: "trace_time"}
+	schema.applySchemaProfile(profile)
+
+	require.Equal(t, "trace_time", schema.Timestamp)
+	require.Equal(t, "span_attributes", schema.SpanAttributes)
+	require.Equal(t, ColumnTypeMap, schema.columnType(schema.SpanAttributes))
+	require.Equal(t, SpanKindServer, schema.spanKindValue(SpanKindServer))
+	require.Equal(t, StatusCodeError, schema.otelStatusCode(StatusCodeError))
+}
+
+func TestSchemaProfileLegacy(t *testing.T) {
+	schema := legacySchema(t)
+
+	require.Equal(t, "start_time", schema.Timestamp)
+	require.Equal(t, "operation_name", schema.SpanName)
+	require.Equal(t, "service_name", schema.ServiceName)
+	require.Equal(t, ColumnTypeJSON, schema.columnType("tags"))
+	require.Equal(t, "server", schema.spanKindValue(SpanKindServer))
+	require.Equal(t, SpanKindServer, schema.otelSpanKind("server"))
+	require.Equal(t, "ERROR", schema.statusCodeValue(StatusCodeError))
+
+	param := spanstore.OperationQueryParameters{ServiceName: "test-service", SpanKind: "server"}
+	want := `SELECT operation_name, span_kind FROM jaeger.spans WHERE service_name = "test-service" AND span_kind = "server" GROUP BY operation_name, span_kind`
+	require.Equal(t, want, queryGetOperations(schema, "jaeger.spans", param, time.Now(), 0, time.Local))
+
+	query, err := parseTraceQL(`{ status = error && kind = client }`)
+	require.NoError(t, err)
+	c := &traceQLCompiler{schema: schema, tableName: "jaeger.spans", location: time.UTC}
+	require.Equal(t,
+		`trace_id IN (SELECT s0.trace_id FROM jaeger.spans s0 WHERE (s0.status_code = 'ERROR' AND s0.span_kind = 'client'))`,
+		query.spansets.sql(c))
+}
+
+func TestSchemaProfileLegacyRecordToSpan(t *testing.T) {
+	cfg := &Config{Doris: &DorisConfig{SchemaMapping: legacySchema(t), Location: time.UTC}}
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	record := map[string]string{
+		"service_name":   "test-service",
+		"start_time":     "2024-01-01 01:01:01.000001",
+		"trace_id":       "01020301000000000000000000000000",
+		"span_id":        "0102030100000000",
+		"operation_name": "test-operation",
+		"span_kind":      "client",
+		"duration":       "1000",
+		"tags":           `{"http.method":"GET"}`,
+		"status_code":    "ERROR",
+		"process_tags":   `{"host.name":"localhost"}`,
+	}
+
+	span, err := recordToSpan(ctx, cfg, record)
+	require.NoError(t, err)
+	require.Equal(t, "test-operation", span.OperationName)
+
+	tags := model.KeyValues(span.Tags)
+	kind, ok := tags.FindByKey("span.kind")
+	require.True(t, ok)
+	require.Equal(t, "client", kind.VStr)
+	errorTag, ok := tags.FindByKey("error")
+	require.True(t, ok)
+	require.True(t, errorTag.Bool())
+	method, ok := tags.FindByKey("http.method")
+	require.True(t, ok)
+	require.Equal(t, "GET", method.VStr)
+}
+
+func TestSchemaProfileJaegerNativeRecordToSpan(t *testing.T) {
+	profile, err := loadSchemaProfile(SchemaProfileJaegerNative, "")
+	require.NoError(t, err)
+	schema := &SchemaMapping{}
+	schema.applySchemaProfile(profile)
+	cfg := &Config{Doris: &DorisConfig{SchemaMapping: schema, Location: time.UTC}}
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+
+	record := map[string]string{
+		"service_name":   "test-service",
+		"start_time":     "2024-01-01 01:01:01.000001",
+		"trace_id":       "01020301000000000000000000000000",
+		"span_id":        "0102030100000000",
+		"parent_span_id": "0102030200000000",
+		"operation_name": "test-operation",
+		"span_kind":      "server",
+		"duration":       "1000",
+		"tags":           `{"http.method":"GET"}`,
+		"status_code":    "",
+		"logs": `[{"timestamp":1704070861000002,"fields":[` +
+			`{"key":"event","type":"string","value":"retry"},` +
+			`{"key":"attempt","type":"int64","value":"2"},` +
+			`{"key":"payload","type":"binary","value":"0102"}]},` +
+			`{"timestamp":1704070861000003,"fields":[{"key":"done","type":"bool","value":"true"}]}]`,
+		"references": `[{"refType":"CHILD_OF","traceID":"01020301000000000000000000000000","spanID":"0102030200000000"},` +
+			`{"refType":"FOLLOWS_FROM","traceID":"01020301000000000000000000000001","spanID":"0102030300000000"}]`,
+	}
+
+	span, err := recordToSpan(ctx, cfg, record)
+	require.NoError(t, err)
+	require.Equal(t, "test-operation", span.OperationName)
+
+	require.Len(t, span.References, 2)
+	require.Equal(t, model.ChildOf, span.References[0].RefType)
+	require.Equal(t, "0102030200000000", span.References[0].SpanID.String())
+	require.Equal(t, model.FollowsFrom, span.References[1].RefType)
+	require.Equal(t, "0102030300000000", span.References[1].SpanID.String())
+
+	require.Len(t, span.Logs, 2)
+	require.Equal(t, time.Date(2024, 1, 1, 1, 1, 1, 2000, time.UTC), span.Logs[0].Timestamp)
+	fields := model.KeyValues(span.Logs[0].Fields)
+	event, ok := fields.FindByKey("event")
+	require.True(t, ok)
+	require.Equal(t, "retry", event.VStr)
+	attempt, ok := fields.FindByKey("attempt")
+	require.True(t, ok)
+	require.Equal(t, int64(2), attempt.Int64())
+	payload, ok := fields.FindByKey("payload")
+	require.True(t, ok)
+	require.Equal(t, []byte{1, 2}, payload.Binary())
+
+	fields = model.KeyValues(span.Logs[1].Fields)
+	require.Len(t, fields, 1)
+	done, ok := fields.FindByKey("done")
+	require.True(t, ok)
+	require.True(t, done.Bool())
+
+	tags := model.KeyValues(span.Tags)
+	kind, ok := tags.FindByKey("span.kind")
+	require.True(t, ok)
+	require.Equal(t, "server", kind.VStr)
+	_, ok = tags.FindByKey("error")
+	require.False(t, ok)
+}
+
+func TestSchemaProfileBinaryIDs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "profile.yaml")
+	err := os.WriteFile(file, []byte(`name: binary
+id_encoding: binary
+`), 0o600)
+	require.NoError(t, err)
+
+	profile, err := loadSchemaProfile(SchemaProfileCustom, file)
+	require.NoError(t, err)
+	schema := &SchemaMapping{}
+	schema.applySchemaProfile(profile)
+
+	traceID := "01020301000000000000000000000000"
+	require.Equal(t,
+		`SELECT * FROM otel2.traces WHERE trace_id = UNHEX('01020301000000000000000000000000')`,
+		queryGetTrace(schema, "otel2.traces", traceID, time.Time{}, time.Time{}, time.Local, 0))
+	require.Equal(t,
+		`SELECT * FROM otel2.traces WHERE trace_id IN (UNHEX('01020301000000000000000000000000'))`,
+		queryFindTraces(schema, "otel2.traces", []string{traceID}, time.Time{}, time.Time{}, time.Local))
+
+	cfg := &Config{Doris: &DorisConfig{SchemaMapping: schema, Location: time.UTC}}
+	ctx := LoggerWithContext(context.Background(), zap.NewNop())
+	record := map[string]string{
+		"service_name":   "test-service",
+		"timestamp":      "2024-01-01 01:01:01.000001",
+		"trace_id":       "\x01\x02\x03\x01" + strings.Repeat("\x00", 12),
+		"span_id":        "\x01\x02\x03\x01" + strings.Repeat("\x00", 4),
+		"parent_span_id": "\x01\x02\x03\x02" + strings.Repeat("\x00", 4),
+		"span_name":      "test-operation",
+		"span_kind":      SpanKindServer,
+		"duration":       "1000",
+	}
+
+	span, err := recordToSpan(ctx, cfg, record)
+	require.NoError(t, err)
+	require.Equal(t, traceID, traceIDToString(span.TraceID))
+	require.Equal(t, "0102030100000000", span.SpanID.String())
+	require.Equal(t, "0102030200000000", span.ParentSpanID().String())
+}
+
+func TestSchemaProfileCustom(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "profile.yaml")
+	err := os.WriteFile(file, []byte(`name: variant
+base: otel-doris-exporter-v2
+schema_mapping:
+  timestamp: ts
+column_types:
+  span_attributes: json
+status_codes:
+  STATUS_CODE_ERROR: "2"
+`), 0o600)
+	require.NoError(t, err)
+
+	profile, err := loadSchemaProfile(SchemaProfileCustom, file)
+	require.NoError(t, err)
+	require.Equal(t, "variant", profile.Name)
+
+	schema := &SchemaMapping{}
+	schema.applySchemaProfile(profile)
+	require.Equal(t, "ts", schema.Timestamp)
+	require.Equal(t, "span_name", schema.SpanName)
+	require.Equal(t, ColumnTypeJSON, schema.columnType("span_attributes"))
+	require.Equal(t, ColumnTypeVariant, schema.columnType("resource_attributes"))
+	require.Equal(t, "2", schema.statusCodeValue(StatusCodeError))
+	require.Equal(t, StatusCodeError, schema.otelStatusCode("2"))
+	require.Equal(t, StatusCodeOk, schema.statusCodeValue(StatusCodeOk))
+	require.Equal(t, SpanKindServer, schema.spanKindValue(SpanKindServer))
+}
+
+func TestSchemaProfileErrors(t *testing.T) {
+	_, err := loadSchemaProfile("unknown", "")
+	require.ErrorContains(t, err, "doris.schema_profile must be one of")
+
+	_, err = loadSchemaProfile(SchemaProfileOTelDorisExporterV2, "profile.yaml")
+	require.ErrorContains(t, err, "requires doris.schema_profile: custom")
+
+	_, err = loadSchemaProfile(SchemaProfileCustom, "")
+	require.ErrorContains(t, err, "doris.schema_profile_file must be specified")
+
+	_, err = loadSchemaProfile(SchemaProfileCustom, filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorContains(t, err, "failed to read doris.schema_profile_file")
+
+	file := filepath.Join(t.TempDir(), "profile.yaml")
+	err = os.WriteFile(file, []byte(`base: otel-doris-exporter-v3
+`), 0o600)
+	require.NoError(t, err)
+	_, err = loadSchemaProfile(SchemaProfileCustom, file)
+	require.ErrorContains(t, err, `unknown base "otel-doris-exporter-v3"`)
+
+	err = os.WriteFile(file, []byte(`column_types:
+  tags: array
+span_kinds:
+  SPAN_KIND_UNKNOWN: unknown
+status_codes:
+  STATUS_CODE_FAILED: failed
+`), 0o600)
+	require.NoError(t, err)
+	_, err = loadSchemaProfile(SchemaProfileCustom, file)
+	require.ErrorContains(t, err, "column_types.tags must be one of")
+	require.ErrorContains(t, err, `unknown span kind "SPAN_KIND_UNKNOWN"`)
+	require.ErrorContains(t, err, `unknown status code "STATUS_CODE_FAILED"`)
+
+	err = os.WriteFile(file, []byte(`id_encoding: base64
+events_format: zipkin
+links_format: zipkin
+`), 0o600)
+	require.NoError(t, err)
+	_, err = loadSchemaProfile(SchemaProfileCustom, file)
+	require.ErrorContains(t, err, "id_encoding must be one of")
+	require.ErrorContains(t, err, "events_format must be one of")
+	require.ErrorContains(t, err, "links_format must be one of")
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/jaegertracing/jaeger/model"
+)
+
+const (
+	truncationWarningServiceName   = "jaeger-doris"
+	truncationWarningOperationName = "trace truncated"
+)
+
+// spanStream passes spans to fn in batches of bufferSize instead of buffering whole traces.
+// The spans of a trace must be added contiguously. Spans exceeding maxSpans (0 means
+// unlimited) per trace are dropped and replaced by a truncation warning span.
+type spanStream struct {
+	bufferSize int
+	maxSpans   int
+	sanitize   *SanitizeConfig
+	fn         func(spans []*model.Span) error
+
+	buffer []*model.Span
+	total  int
+
+	first *model.Span // first span of the current trace
+	count int         // spans of the current trace, including dropped ones
+}
+
+func newSpanStream(bufferSize int, maxSpans int, sanitize *SanitizeConfig, fn func(spans []*model.Span) error) *spanStream {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &spanStream{
+		bufferSize: bufferSize,
+		maxSpans:   maxSpans,
+		sanitize:   sanitize,
+		fn:         fn,
+		buffer:     make([]*model.Span, 0, bufferSize),
+	}
+}
+
+func (ss *spanStream) Add(span *model.Span) error {
+	if ss.first == nil || ss.first.TraceID != span.TraceID {
+		err := ss.endTrace()
+		if err != nil {
+			return err
+		}
+		ss.first = span
+		ss.count = 0
+	}
+
+	ss.count++
+	if ss.maxSpans > 0 && ss.count > ss.maxSpans {
+		return nil
+	}
+
+	return ss.push(sanitizeSpan(ss.sanitize, span))
+}
+
+// Close flushes the remaining spans, it returns the number of delivered spans.
+func (ss *spanStream) Close() (int, error) {
+	err := ss.endTrace()
+	if err != nil {
+		return ss.total, err
+	}
+	return ss.total, ss.flush()
+}
+
+func (ss *spanStream) endTrace() error {
+	if ss.first == nil || ss.maxSpans <= 0 || ss.count <= ss.maxSpans {
+		return nil
+	}
+	return ss.push(truncationWarningSpan(ss.first, ss.maxSpans))
+}
+
+func (ss *spanStream) push(span *model.Span) error {
+	ss.buffer = append(ss.buffer, span)
+	ss.total++
+	if len(ss.buffer) >= ss.bufferSize {
+		return ss.flush()
+	}
+	return nil
+}
+
+func (ss *spanStream) flush() error {
+	if len(ss.buffer) == 0 {
+		return nil
+	}
+	err := ss.fn(ss.buffer)
+	// the receiver may keep the slice, so it is not reused
+	ss.buffer = make([]*model.Span, 0, ss.bufferSize)
+	return err
+}
+
+// truncateSpans keeps the first maxSpans spans (0 means unlimited) of a trace and appends
+// a truncation warning span if spans were dropped.
+func truncateSpans(spans []*model.Span, maxSpans int) []*model.Span {
+	if maxSpans <= 0 || len(spans) <= maxSpans {
+		return spans
+	}
+	spans = spans[:maxSpans]
+	return append(spans, truncationWarningSpan(spans[0], maxSpans))
+}
+
+// truncationWarningSpan returns a span which tells users in the UI that the trace is incomplete.
+func truncationWarningSpan(first *model.Span, maxSpans int) *model.Span {
+	return &model.Span{
+		TraceID:       first.TraceID,
+		SpanID:        model.NewSpanID(rand.Uint64()),
+		OperationName: truncationWarningOperationName,
+		References: []model.SpanRef{
+			model.NewChildOfRef(first.TraceID, first.SpanID),
+		},
+		StartTime: first.StartTime,
+		Process: &model.Process{
+			ServiceName: truncationWarningServiceName,
+		},
+		Warnings: []string{
+			fmt.Sprintf("trace truncated to %d spans, see doris.max_spans_per_trace", maxSpans),
+		},
+	}
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanStream(t *testing.T) {
+	var batches [][]*model.Span
+	stream := newSpanStream(2, 2, &SanitizeConfig{MaxValueLength: -1}, func(spans []*model.Span) error {
+		batches = append(batches, spans)
+		return nil
+	})
+
+	traceID1 := model.NewTraceID(0, 1)
+	traceID2 := model.NewTraceID(0, 2)
+	for i, traceID := range []model.TraceID{traceID1, traceID1, traceID1, traceID2} {
+		require.NoError(t, stream.Add(&model.Span{TraceID: traceID, SpanID: model.NewSpanID(uint64(i + 1))}))
+	}
+
+	total, err := stream.Close()
+	require.NoError(t, err)
+	require.Equal(t, 4, total)
+	require.Len(t, batches, 2)
+
+	warning := batches[1][0]
+	require.Equal(t, traceID1, warning.TraceID)
+	require.Equal(t, truncationWarningOperationName, warning.OperationName)
+	require.Equal(t, model.NewSpanID(1), warning.ParentSpanID())
+	require.Len(t, warning.Warnings, 1)
+	require.Equal(t, traceID2, batches[1][1].TraceID)
+}
+
+func TestTruncateSpans(t *testing.T) {
+	spans := []*model.Span{{SpanID: 1}, {SpanID: 2}, {SpanID: 3}}
+	require.Len(t, truncateSpans(spans, 0), 3)
+	require.Len(t, truncateSpans(spans, 3), 3)
+
+	truncated := truncateSpans(spans, 1)
+	require.Len(t, truncated, 2)
+	require.Equal(t, truncationWarningOperationName, truncated[1].OperationName)
+}
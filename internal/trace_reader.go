@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/jaegertracing/jaeger/storage/spanstore"
+	spanstore_v2 "github.com/jaegertracing/jaeger/storage_v2/spanstore"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/simonasgal/jaeger-doris/thrid_party/jaeger/plugin/storage/grpc/shared"
+)
+
+var _ spanstore_v2.Reader = (*traceReader)(nil)
+
+// traceReader is the OTLP reader of the jaeger v2 storage API, its traces are built by otlpTraceBuilder.
+type traceReader struct {
+	reader *accessControlledReader
+}
+
+func (tr *traceReader) GetTrace(ctx context.Context, traceID pcommon.TraceID) (ptrace.Traces, error) {
+	return tr.reader.dr.getOTLPTrace(ctx, shared.GetTraceParameters{TraceID: modelTraceID(traceID)}, tr.reader.deniedSpans)
+}
+
+func (tr *traceReader) GetServices(ctx context.Context) ([]string, error) {
+	return tr.reader.GetServices(ctx)
+}
+
+func (tr *traceReader) GetOperations(ctx context.Context, query spanstore_v2.OperationQueryParameters) ([]spanstore_v2.Operation, error) {
+	operations, err := tr.reader.GetOperations(ctx, spanstore.OperationQueryParameters{
+		ServiceName: query.ServiceName,
+		SpanKind:    query.SpanKind,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]spanstore_v2.Operation, 0, len(operations))
+	for _, operation := range operations {
+		result = append(result, spanstore_v2.Operation{Name: operation.Name, SpanKind: operation.SpanKind})
+	}
+	return result, nil
+}
+
+func (tr *traceReader) FindTraces(ctx context.Context, query spanstore_v2.TraceQueryParameters) ([]ptrace.Traces, error) {
+	var traces []ptrace.Traces
+	err := tr.reader.dr.streamOTLPTraces(ctx, traceQueryFromV2(query), tr.reader.deniedSpans, func(td ptrace.Traces) error {
+		traces = append(traces, td)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+func (tr *traceReader) FindTraceIDs(ctx context.Context, query spanstore_v2.TraceQueryParameters) ([]pcommon.TraceID, error) {
+	traceIDs, err := tr.reader.FindTraceIDs(ctx, traceQueryFromV2(query))
+	if err != nil || len(traceIDs) == 0 {
+		return nil, err
+	}
+
+	result := make([]pcommon.TraceID, 0, len(traceIDs))
+	for _, traceID := range traceIDs {
+		result = append(result, otlpTraceIDFromModel(traceID))
+	}
+	return result, nil
+}
+
+func traceQueryFromV2(query spanstore_v2.TraceQueryParameters) *spanstore.TraceQueryParameters {
+	tags := query.Tags
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	return &spanstore.TraceQueryParameters{
+		ServiceName:   query.ServiceName,
+		OperationName: query.OperationName,
+		Tags:          tags,
+		StartTimeMin:  query.StartTimeMin,
+		StartTimeMax:  query.StartTimeMax,
+		DurationMin:   query.DurationMin,
+		DurationMax:   query.DurationMax,
+		NumTraces:     query.NumTraces,
+	}
+}
+
+func modelTraceID(traceID pcommon.TraceID) model.TraceID {
+	return model.NewTraceID(binary.BigEndian.Uint64(traceID[:8]), binary.BigEndian.Uint64(traceID[8:]))
+}
+
+// getOTLPTrace is GetTraceWithParameters for OTLP traces.
+func (dr *dorisReader) getOTLPTrace(ctx context.Context, query shared.GetTraceParameters, deniedSpans string) (ptrace.Traces, error) {
+	builder := newOTLPTraceBuilder(ctx, dr.cfg, dr.redactor, deniedSpans)
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		err := builder.Add(ctx, record)
+		if err != nil {
+			dr.logger.Warn("Failed to convert record to span", zap.Error(err))
+		}
+		return nil
+	}
+
+	err := executeQuery(ctx, dr.db, dr.cfg, dr.getTraceQuery(ctx, query), f)
+	if err != nil {
+		return ptrace.Traces{}, err
+	}
+	if builder.Len() == 0 {
+		return ptrace.Traces{}, spanstore.ErrTraceNotFound
+	}
+	return builder.Traces(), nil
+}
+
+// streamOTLPTraces is StreamTraces for OTLP traces, fn is called with each matching trace.
+func (dr *dorisReader) streamOTLPTraces(ctx context.Context, query *spanstore.TraceQueryParameters, deniedSpans string, fn func(td ptrace.Traces) error) error {
+	err := checkServicePermission(ctx, query.ServiceName)
+	if err != nil {
+		return err
+	}
+
+	spansQuery, _, err := dr.findTracesQuery(ctx, query, true)
+	if err != nil || spansQuery == "" {
+		return err
+	}
+
+	schema := dr.cfg.Doris.SchemaMapping
+	var traceID string
+	var builder *otlpTraceBuilder
+	flush := func() error {
+		if builder == nil || builder.Len() == 0 {
+			return nil
+		}
+		return fn(builder.Traces())
+	}
+
+	// the spans of each trace are returned contiguously
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		if builder == nil || record[schema.TraceID] != traceID {
+			err := flush()
+			if err != nil {
+				return err
+			}
+			traceID = record[schema.TraceID]
+			builder = newOTLPTraceBuilder(ctx, dr.cfg, dr.redactor, deniedSpans)
+		}
+
+		err := builder.Add(ctx, record)
+		if err != nil {
+			dr.logger.Warn("Failed to convert record to span", zap.Error(err))
+		}
+		return nil
+	}
+
+	err = executeQuery(ctx, dr.db, dr.cfg, spansQuery, f)
+	if err != nil {
+		return err
+	}
+	return flush()
+}
@@ -0,0 +1,696 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jaegertracing/jaeger/pkg/tenancy"
+	"go.uber.org/zap"
+)
+
+// traceQLQuery is a query of the supported TraceQL subset:
+//
+//	query      = spansets { "|" ( "duration" | "traceDuration" ) op duration }
+//	spansets   = and { "||" and }
+//	and        = child { "&&" child }
+//	child      = spanset { ">" spanset } | "(" spansets ")"
+//	spanset    = "{" [ expr ] "}"
+//	expr       = term { "||" term }
+//	term       = factor { "&&" factor }
+//	factor     = field op value | "(" expr ")"
+//
+// Fields are the intrinsics name, status, statusMessage, kind and duration, and attributes
+// scoped by "span.", "resource." or unscoped with a leading ".". Pipeline duration filters
+// apply to the duration of the whole trace.
+type traceQLQuery struct {
+	spansets  traceQLSpansetExpr
+	durations []*traceQLComparison
+}
+
+// traceQLSpansetExpr selects traces.
+type traceQLSpansetExpr interface {
+	sql(c *traceQLCompiler) string
+}
+
+// traceQLSpansetOp matches the traces matching both or either of its operands.
+type traceQLSpansetOp struct {
+	op          string
+	left, right traceQLSpansetExpr
+}
+
+// traceQLChain matches the traces containing a span matching the last spanset, whose parent
+// matches the spanset before, and so on. A nil spanset matches any span.
+type traceQLChain struct {
+	spansets []traceQLCondition
+}
+
+// traceQLCondition matches spans.
+type traceQLCondition interface {
+	sql(c *traceQLCompiler, alias string) string
+}
+
+type traceQLBinary struct {
+	op          string
+	left, right traceQLCondition
+}
+
+type traceQLComparison struct {
+	field traceQLField
+	op    string
+	value traceQLValue
+}
+
+type traceQLField struct {
+	scope string // span, resource, "" for both, or intrinsic
+	name  string
+}
+
+const (
+	traceQLScopeSpan      = "span"
+	traceQLScopeResource  = "resource"
+	traceQLScopeIntrinsic = "intrinsic"
+)
+
+type traceQLValueType int
+
+const (
+	traceQLString traceQLValueType = iota
+	traceQLNumber
+	traceQLDuration
+	traceQLBool
+	traceQLStatus
+	traceQLKind
+)
+
+type traceQLValue struct {
+	typ      traceQLValueType
+	str      string // string, bool, or the doris value of a status or kind
+	number   float64
+	duration time.Duration
+}
+
+// traceQLIntrinsics maps the intrinsic fields, in both syntaxes of Tempo, to their names.
+var traceQLIntrinsics = map[string]string{
+	"name":               "name",
+	"span:name":          "name",
+	"status":             "status",
+	"span:status":        "status",
+	"statusMessage":      "statusMessage",
+	"span:statusMessage": "statusMessage",
+	"kind":               "kind",
+	"span:kind":          "kind",
+	"duration":           "duration",
+	"span:duration":      "duration",
+}
+
+var traceQLStatuses = map[string]string{
+	"error": StatusCodeError,
+	"ok":    StatusCodeOk,
+	"unset": StatusCodeUnset,
+}
+
+var traceQLKinds = map[string]string{
+	"unspecified": SpanKindUnspecified,
+	"internal":    SpanKindInternal,
+	"server":      SpanKindServer,
+	"client":      SpanKindClient,
+	"producer":    SpanKindProducer,
+	"consumer":    SpanKindConsumer,
+}
+
+// traceSummary is a trace of the search results, in the format of Tempo.
+type traceSummary struct {
+	TraceID           string `json:"traceID"`
+	RootServiceName   string `json:"rootServiceName,omitempty"`
+	RootTraceName     string `json:"rootTraceName,omitempty"`
+	StartTimeUnixNano string `json:"startTimeUnixNano"`
+	DurationMs        int64  `json:"durationMs"`
+	SpanCount         int    `json:"spanCount"`
+}
+
+// searchTraceQL returns summaries of the newest traces matching query. Services restricts all spans,
+// including the matched ones, to the given services unless it is nil.
+func (dr *dorisReader) searchTraceQL(ctx context.Context, query *traceQLQuery, startTimeMin, startTimeMax time.Time, limit int, services []string) ([]*traceSummary, error) {
+	schema := dr.cfg.Doris.SchemaMapping
+	c := &traceQLCompiler{
+		schema:       schema,
+		tableName:    dr.cfg.Doris.TableFullName(),
+		startTimeMin: startTimeMin,
+		startTimeMax: startTimeMax,
+		location:     dr.cfg.Doris.Location,
+		services:     services,
+		guard:        dr.redactor.attributeGuard(tenancy.GetTenant(ctx)),
+	}
+	sql := queryTraceSummaries(schema, c.tableName, query.spansets.sql(c), c.havingPredicates(query.durations), c.spanPredicates(""), limit)
+
+	summaries := make([]*traceSummary, 0)
+	f := func(ctx context.Context, cfg *Config, record map[string]string) error {
+		startTime, err := time.ParseInLocation(timeFormat, record["t"], cfg.Doris.Location)
+		if err != nil {
+			dr.logger.Warn("Failed to parse start time of trace", zap.Error(err))
+			return nil
+		}
+		duration, _ := strconv.ParseInt(record["d"], 10, 64)
+		count, _ := strconv.Atoi(record["c"])
+		summaries = append(summaries, &traceSummary{
+			TraceID:           schema.decodeID(record[schema.TraceID]),
+			RootServiceName:   record["root_service_name"],
+			RootTraceName:     record["root_span_name"],
+			StartTimeUnixNano: strconv.FormatInt(startTime.UnixNano(), 10),
+			DurationMs:        time.Duration(duration * int64(time.Microsecond)).Milliseconds(),
+			SpanCount:         count,
+		})
+		return nil
+	}
+
+	err := executeQuery(ctx, dr.db, dr.cfg, sql, f)
+	if err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// traceQLCompiler compiles queries to predicates on the span table.
+type traceQLCompiler struct {
+	schema       *SchemaMapping
+	tableName    string
+	startTimeMin time.Time
+	startTimeMax time.Time
+	location     *time.Location
+	services     []string
+	guard        attributeGuard
+}
+
+// spanPredicates restricts the spans of alias to the time range and the services.
+func (c *traceQLCompiler) spanPredicates(alias string) []string {
+	prefix := ""
+	if alias != "" {
+		prefix = alias + "."
+	}
+	predicates := timeRangePredicates(c.schema, prefix, c.startTimeMin, c.startTimeMax, c.location)
+	if c.services != nil {
+		quoted := quoteStringLiterals(c.services)
+		if quoted == "" {
+			// no service may be read
+			quoted = "NULL"
+		}
+		predicates = append(predicates, fmt.Sprintf(`%s%s IN (%s)`, prefix, c.schema.ServiceName, quoted))
+	}
+	return predicates
+}
+
+func (c *traceQLCompiler) havingPredicates(durations []*traceQLComparison) []string {
+	predicates := make([]string, 0, len(durations))
+	for _, duration := range durations {
+		predicates = append(predicates, fmt.Sprintf(`d %s %d`, traceQLSQLOperator(duration.op), duration.value.duration.Microseconds()))
+	}
+	return predicates
+}
+
+func (e *traceQLSpansetOp) sql(c *traceQLCompiler) string {
+	operator := "AND"
+	if e.op == "||" {
+		operator = "OR"
+	}
+	return fmt.Sprintf(`(%s %s %s)`, e.left.sql(c), operator, e.right.sql(c))
+}
+
+func (e *traceQLChain) sql(c *traceQLCompiler) string {
+	schema := c.schema
+	last := fmt.Sprintf("s%d", len(e.spansets)-1)
+	query := fmt.Sprintf(`SELECT %s.%s FROM %s s0`, last, schema.TraceID, c.tableName)
+
+	predicates := make([]string, 0)
+	for i, spanset := range e.spansets {
+		alias := fmt.Sprintf("s%d", i)
+		if i > 0 {
+			parent := fmt.Sprintf("s%d", i-1)
+			query += fmt.Sprintf(
+				` INNER JOIN %s %s ON %s.%s = %s.%s AND %s.%s = %s.%s`,
+				c.tableName, alias,
+				alias, schema.TraceID, parent, schema.TraceID,
+				alias, schema.ParentSpanID, parent, schema.SpanID,
+			)
+		}
+		predicates = append(predicates, c.spanPredicates(alias)...)
+		if spanset != nil {
+			predicates = append(predicates, spanset.sql(c, alias))
+		}
+	}
+	if len(predicates) > 0 {
+		query += " WHERE " + strings.Join(predicates, " AND ")
+	}
+
+	return fmt.Sprintf(`%s IN (%s)`, schema.TraceID, query)
+}
+
+func (e *traceQLBinary) sql(c *traceQLCompiler, alias string) string {
+	operator := "AND"
+	if e.op == "||" {
+		operator = "OR"
+	}
+	return fmt.Sprintf(`(%s %s %s)`, e.left.sql(c, alias), operator, e.right.sql(c, alias))
+}
+
+func (e *traceQLComparison) sql(c *traceQLCompiler, alias string) string {
+	schema := c.schema
+	column := func(name string) string {
+		return alias + "." + name
+	}
+
+	switch e.field.scope {
+	case traceQLScopeIntrinsic:
+		switch e.field.name {
+		case "name":
+			return traceQLCompare(column(schema.SpanName), e.op, e.value)
+		case "statusMessage":
+			return c.guard.apply(column(schema.ServiceName), SpanTagKeyStatusDescription, traceQLCompare(column(schema.StatusMessage), e.op, e.value))
+		case "status":
+			value := e.value
+			value.str = schema.statusCodeValue(value.str)
+			return traceQLCompare(column(schema.StatusCode), e.op, value)
+		case "kind":
+			value := e.value
+			value.str = schema.spanKindValue(value.str)
+			return traceQLCompare(column(schema.SpanKind), e.op, value)
+		default:
+			return fmt.Sprintf(`%s %s %d`, column(schema.Duration), traceQLSQLOperator(e.op), e.value.duration.Microseconds())
+		}
+	case traceQLScopeSpan:
+		return c.guard.apply(column(schema.ServiceName), e.field.name, traceQLCompare(e.attribute(schema, alias, schema.SpanAttributes), e.op, e.value))
+	case traceQLScopeResource:
+		if e.field.name == resourceKeyServiceName {
+			return traceQLCompare(column(schema.ServiceName), e.op, e.value)
+		}
+		return c.guard.apply(column(schema.ServiceName), e.field.name, traceQLCompare(e.attribute(schema, alias, schema.ResourceAttributes), e.op, e.value))
+	default:
+		span := &traceQLComparison{field: traceQLField{scope: traceQLScopeSpan, name: e.field.name}, op: e.op, value: e.value}
+		resource := &traceQLComparison{field: traceQLField{scope: traceQLScopeResource, name: e.field.name}, op: e.op, value: e.value}
+		return fmt.Sprintf(`(%s OR %s)`, span.sql(c, alias), resource.sql(c, alias))
+	}
+}
+
+// attribute returns the value of the attribute of the comparison in column, cast to a number if
+// the value it is compared with is a number.
+func (e *traceQLComparison) attribute(schema *SchemaMapping, alias string, column string) string {
+	return schema.attributeExpr(alias+".", column, e.field.name, e.value.typ == traceQLNumber)
+}
+
+func traceQLCompare(expr string, op string, value traceQLValue) string {
+	var literal string
+	switch value.typ {
+	case traceQLNumber:
+		literal = strconv.FormatFloat(value.number, 'f', -1, 64)
+	case traceQLDuration:
+		literal = strconv.FormatInt(value.duration.Microseconds(), 10)
+	default:
+		literal = fmt.Sprintf(`'%s'`, escapeStringLiteral(value.str))
+	}
+
+	switch op {
+	case "=~":
+		// TraceQL regular expressions match the whole value
+		return fmt.Sprintf(`%s REGEXP '%s'`, expr, escapeStringLiteral("^(?:"+value.str+")$"))
+	case "!~":
+		return fmt.Sprintf(`NOT (%s REGEXP '%s')`, expr, escapeStringLiteral("^(?:"+value.str+")$"))
+	default:
+		return fmt.Sprintf(`%s %s %s`, expr, traceQLSQLOperator(op), literal)
+	}
+}
+
+func traceQLSQLOperator(op string) string {
+	if op == "!=" {
+		return "<>"
+	}
+	return op
+}
+
+// parseTraceQL parses a query of the supported subset of TraceQL.
+func parseTraceQL(s string) (*traceQLQuery, error) {
+	tokens, err := lexTraceQL(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &traceQLParser{tokens: tokens}
+
+	query := &traceQLQuery{}
+	query.spansets, err = p.parseSpansets()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().text == "|" {
+		p.next()
+		field := p.next()
+		if field.kind != traceQLTokenIdent || (field.text != "duration" && field.text != "traceDuration") {
+			return nil, p.errorf(field, "only duration filters are supported in the pipeline")
+		}
+		comparison, err := p.parseComparisonRest(traceQLField{scope: traceQLScopeIntrinsic, name: "duration"})
+		if err != nil {
+			return nil, err
+		}
+		query.durations = append(query.durations, comparison)
+	}
+
+	if t := p.peek(); t.kind != traceQLTokenEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return query, nil
+}
+
+type traceQLTokenKind int
+
+const (
+	traceQLTokenEOF traceQLTokenKind = iota
+	traceQLTokenIdent
+	traceQLTokenString
+	traceQLTokenNumber
+	traceQLTokenSymbol
+)
+
+type traceQLToken struct {
+	kind traceQLTokenKind
+	text string // the unquoted value of strings
+	pos  int
+}
+
+var traceQLSymbols = []string{"&&", "||", ">=", "<=", "!=", "=~", "!~", "{", "}", "(", ")", "|", ">", "<", "="}
+
+func lexTraceQL(s string) ([]traceQLToken, error) {
+	tokens := make([]traceQLToken, 0)
+	isIdent := func(r rune) bool {
+		return r >= utf8.RuneSelf || unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.:-/", r)
+	}
+
+	for i := 0; i < len(s); {
+		r := rune(s[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			value, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("malformed string at position %d: %w", i, err)
+			}
+			tokens = append(tokens, traceQLToken{kind: traceQLTokenString, text: value, pos: i})
+			i = end + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			end := i + 1
+			for end < len(s) && (unicode.IsDigit(rune(s[end])) || s[end] == '.' || unicode.IsLetter(rune(s[end])) || s[end] >= utf8.RuneSelf) {
+				end++
+			}
+			tokens = append(tokens, traceQLToken{kind: traceQLTokenNumber, text: s[i:end], pos: i})
+			i = end
+		case r == '.' || r == '_' || unicode.IsLetter(r):
+			end := i + 1
+			for end < len(s) && isIdent(rune(s[end])) {
+				end++
+			}
+			tokens = append(tokens, traceQLToken{kind: traceQLTokenIdent, text: s[i:end], pos: i})
+			i = end
+		default:
+			symbol := ""
+			for _, candidate := range traceQLSymbols {
+				if strings.HasPrefix(s[i:], candidate) {
+					symbol = candidate
+					break
+				}
+			}
+			if symbol == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", s[i], i)
+			}
+			tokens = append(tokens, traceQLToken{kind: traceQLTokenSymbol, text: symbol, pos: i})
+			i += len(symbol)
+		}
+	}
+
+	return append(tokens, traceQLToken{kind: traceQLTokenEOF, pos: len(s)}), nil
+}
+
+type traceQLParser struct {
+	tokens []traceQLToken
+	pos    int
+}
+
+func (p *traceQLParser) peek() traceQLToken {
+	return p.tokens[p.pos]
+}
+
+func (p *traceQLParser) next() traceQLToken {
+	t := p.tokens[p.pos]
+	if t.kind != traceQLTokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *traceQLParser) expect(symbol string) error {
+	t := p.next()
+	if t.kind != traceQLTokenSymbol || t.text != symbol {
+		return p.errorf(t, "expected %q", symbol)
+	}
+	return nil
+}
+
+func (p *traceQLParser) errorf(t traceQLToken, format string, args ...any) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), t.pos)
+}
+
+func (p *traceQLParser) parseSpansets() (traceQLSpansetExpr, error) {
+	left, err := p.parseSpansetsAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().text == "||" {
+		p.next()
+		right, err := p.parseSpansetsAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &traceQLSpansetOp{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *traceQLParser) parseSpansetsAnd() (traceQLSpansetExpr, error) {
+	left, err := p.parseChild()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseChild()
+		if err != nil {
+			return nil, err
+		}
+		left = &traceQLSpansetOp{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *traceQLParser) parseChild() (traceQLSpansetExpr, error) {
+	if p.peek().text == "(" {
+		p.next()
+		expr, err := p.parseSpansets()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+
+	chain := &traceQLChain{}
+	for {
+		spanset, err := p.parseSpanset()
+		if err != nil {
+			return nil, err
+		}
+		chain.spansets = append(chain.spansets, spanset)
+		if p.peek().text != ">" {
+			return chain, nil
+		}
+		p.next()
+	}
+}
+
+func (p *traceQLParser) parseSpanset() (traceQLCondition, error) {
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().text == "}" {
+		p.next()
+		return nil, nil
+	}
+	condition, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return condition, p.expect("}")
+}
+
+func (p *traceQLParser) parseExpr() (traceQLCondition, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().text == "||" {
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &traceQLBinary{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *traceQLParser) parseTerm() (traceQLCondition, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &traceQLBinary{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *traceQLParser) parseFactor() (traceQLCondition, error) {
+	if p.peek().text == "(" {
+		p.next()
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+
+	t := p.next()
+	if t.kind != traceQLTokenIdent {
+		return nil, p.errorf(t, "expected a field")
+	}
+	field, err := parseTraceQLField(t.text)
+	if err != nil {
+		return nil, p.errorf(t, "%s", err)
+	}
+	return p.parseComparisonRest(field)
+}
+
+func parseTraceQLField(s string) (traceQLField, error) {
+	if name, ok := traceQLIntrinsics[s]; ok {
+		return traceQLField{scope: traceQLScopeIntrinsic, name: name}, nil
+	}
+	for _, scope := range []string{traceQLScopeSpan, traceQLScopeResource} {
+		if name, ok := strings.CutPrefix(s, scope+"."); ok && name != "" {
+			return traceQLField{scope: scope, name: name}, nil
+		}
+	}
+	if name, ok := strings.CutPrefix(s, "."); ok && name != "" {
+		if name == resourceKeyServiceName {
+			return traceQLField{scope: traceQLScopeResource, name: name}, nil
+		}
+		return traceQLField{name: name}, nil
+	}
+	return traceQLField{}, fmt.Errorf("unsupported field %q", s)
+}
+
+// parseComparisonRest parses the operator and the value compared with field, and checks their types.
+func (p *traceQLParser) parseComparisonRest(field traceQLField) (*traceQLComparison, error) {
+	opToken := p.next()
+	op := opToken.text
+	switch op {
+	case "=", "!=", ">", ">=", "<", "<=", "=~", "!~":
+	default:
+		return nil, p.errorf(opToken, "expected a comparison operator")
+	}
+
+	t := p.next()
+	value, err := parseTraceQLValue(t)
+	if err != nil {
+		return nil, p.errorf(t, "%s", err)
+	}
+
+	ordered := op == ">" || op == ">=" || op == "<" || op == "<="
+	regex := op == "=~" || op == "!~"
+	intrinsic := field.scope == traceQLScopeIntrinsic
+
+	var typeErr error
+	switch {
+	case intrinsic && field.name == "duration":
+		if value.typ != traceQLDuration || regex {
+			typeErr = errors.New("duration must be compared with a duration, e.g. 100ms")
+		}
+	case intrinsic && field.name == "status":
+		if value.typ != traceQLStatus || (op != "=" && op != "!=") {
+			typeErr = errors.New("status must be compared with = or != to error, ok or unset")
+		}
+	case intrinsic && field.name == "kind":
+		if value.typ != traceQLKind || (op != "=" && op != "!=") {
+			typeErr = errors.New("kind must be compared with = or != to a span kind, e.g. server")
+		}
+	case intrinsic:
+		if value.typ != traceQLString {
+			typeErr = fmt.Errorf("%s must be compared with a string", field.name)
+		}
+	case regex && value.typ != traceQLString:
+		typeErr = errors.New("regular expressions must be strings")
+	case ordered && value.typ != traceQLNumber && value.typ != traceQLString:
+		typeErr = fmt.Errorf("%q cannot compare attributes with %s", op, t.text)
+	case value.typ == traceQLDuration || value.typ == traceQLStatus || value.typ == traceQLKind:
+		typeErr = fmt.Errorf("attributes cannot be compared with %s", t.text)
+	}
+	if typeErr != nil {
+		return nil, p.errorf(opToken, "%s", typeErr)
+	}
+
+	return &traceQLComparison{field: field, op: op, value: value}, nil
+}
+
+func parseTraceQLValue(t traceQLToken) (traceQLValue, error) {
+	switch t.kind {
+	case traceQLTokenString:
+		return traceQLValue{typ: traceQLString, str: t.text}, nil
+	case traceQLTokenNumber:
+		if n, err := strconv.ParseFloat(t.text, 64); err == nil {
+			return traceQLValue{typ: traceQLNumber, number: n}, nil
+		}
+		d, err := time.ParseDuration(t.text)
+		if err != nil {
+			return traceQLValue{}, fmt.Errorf("malformed number or duration %q", t.text)
+		}
+		return traceQLValue{typ: traceQLDuration, duration: d}, nil
+	case traceQLTokenIdent:
+		switch t.text {
+		case "true", "false":
+			return traceQLValue{typ: traceQLBool, str: t.text}, nil
+		}
+		if status, ok := traceQLStatuses[t.text]; ok {
+			return traceQLValue{typ: traceQLStatus, str: status}, nil
+		}
+		if kind, ok := traceQLKinds[t.text]; ok {
+			return traceQLValue{typ: traceQLKind, str: kind}, nil
+		}
+	}
+	return traceQLValue{}, fmt.Errorf("expected a value, got %q", t.text)
+}
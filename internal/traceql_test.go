@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTraceQLTestCompiler() *traceQLCompiler {
+	schema := &SchemaMapping{}
+	schema.FillDefaultValues()
+	return &traceQLCompiler{schema: schema, tableName: "otel.traces", location: time.UTC}
+}
+
+func TestTraceQL(t *testing.T) {
+	for _, tc := range []struct {
+		query     string
+		predicate string
+		having    []string
+	}{
+		{
+			`{ resource.service.name = "api" && span.http.status_code >= 500 } | duration > 1s`,
+			`trace_id IN (SELECT s0.trace_id FROM otel.traces s0 WHERE (s0.service_name = 'api' AND CAST(s0.span_attributes['http.status_code'] AS DOUBLE) >= 500))`,
+			[]string{"d > 1000000"},
+		},
+		{
+			`{}`,
+			`trace_id IN (SELECT s0.trace_id FROM otel.traces s0)`,
+			[]string{},
+		},
+		{
+			`{ name =~ "GET /api/.*" || (status = error && kind != server) } && { duration >= 150ms }`,
+			`(trace_id IN (SELECT s0.trace_id FROM otel.traces s0 WHERE (s0.span_name REGEXP '^(?:GET /api/.*)$' OR (s0.status_code = 'STATUS_CODE_ERROR' AND s0.span_kind <> 'SPAN_KIND_SERVER')))` +
+				` AND trace_id IN (SELECT s0.trace_id FROM otel.traces s0 WHERE s0.duration >= 150000))`,
+			[]string{},
+		},
+		{
+			`{ .service.name = "frontend" } > { .db.system = "mysql" } > { span:statusMessage != "it's ok" }`,
+			`trace_id IN (SELECT s2.trace_id FROM otel.traces s0` +
+				` INNER JOIN otel.traces s1 ON s1.trace_id = s0.trace_id AND s1.parent_span_id = s0.span_id` +
+				` INNER JOIN otel.traces s2 ON s2.trace_id = s1.trace_id AND s2.parent_span_id = s1.span_id` +
+				` WHERE s0.service_name = 'frontend'` +
+				` AND (s1.span_attributes['db.system'] = 'mysql' OR s1.resource_attributes['db.system'] = 'mysql')` +
+				` AND s2.status_message <> 'it\'s ok')`,
+			[]string{},
+		},
+		{
+			`({ span.retry = true } || { resource.k8s.pod.name !~ "canary-.*" }) | traceDuration <= 2m | duration > 1.5s`,
+			`(trace_id IN (SELECT s0.trace_id FROM otel.traces s0 WHERE s0.span_attributes['retry'] = 'true')` +
+				` OR trace_id IN (SELECT s0.trace_id FROM otel.traces s0 WHERE NOT (s0.resource_attributes['k8s.pod.name'] REGEXP '^(?:canary-.*)$')))`,
+			[]string{"d <= 120000000", "d > 1500000"},
+		},
+	} {
+		query, err := parseTraceQL(tc.query)
+		require.NoError(t, err, tc.query)
+		c := newTraceQLTestCompiler()
+		require.Equal(t, tc.predicate, query.spansets.sql(c), tc.query)
+		require.Equal(t, tc.having, c.havingPredicates(query.durations), tc.query)
+	}
+}
+
+func TestTraceQLSpanPredicates(t *testing.T) {
+	query, err := parseTraceQL(`{ span.user = "x" }`)
+	require.NoError(t, err)
+
+	c := newTraceQLTestCompiler()
+	c.startTimeMin = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.startTimeMax = c.startTimeMin.Add(time.Hour)
+	c.services = []string{"frontend", "o'reilly"}
+	require.Equal(t,
+		`trace_id IN (SELECT s0.trace_id FROM otel.traces s0 WHERE s0.timestamp >= '2024-01-01 00:00:00' AND s0.timestamp <= '2024-01-01 01:00:00'`+
+			` AND s0.service_name IN ('frontend', 'o\'reilly') AND s0.span_attributes['user'] = 'x')`,
+		query.spansets.sql(c))
+
+	c.services = []string{}
+	require.Equal(t, []string{`service_name IN (NULL)`}, c.spanPredicates("")[2:])
+}
+
+func TestTraceQLErrors(t *testing.T) {
+	for _, query := range []string{
+		``,
+		`{`,
+		`{ span.a = "b" `,
+		`{ span.a = "b" } > ({ span.c = "d" })`,
+		`{ span.a }`,
+		`{ span.a = }`,
+		`{ foo = "b" }`,
+		`{ span. = "b" }`,
+		`{ duration > 100 }`,
+		`{ duration =~ "1s" }`,
+		`{ status = "error" }`,
+		`{ status > error }`,
+		`{ kind = error }`,
+		`{ name = 1 }`,
+		`{ span.a =~ 1 }`,
+		`{ span.a > true }`,
+		`{ span.a = 1s }`,
+		`{ span.a = "unterminated }`,
+		`{ span.a = "b" } | count() > 1`,
+		`{ span.a = "b" } | duration > 1`,
+		`{ span.a = "b" } }`,
+		`{ !(span.a = "b") }`,
+	} {
+		_, err := parseTraceQL(query)
+		require.Error(t, err, query)
+	}
+}
+
+func TestTraceQLAttributeGuard(t *testing.T) {
+	r, err := newRedactor(&RedactionConfig{
+		Rules: []*RedactionRule{
+			{DenyKeys: []string{"user.email"}},
+			{Services: []string{"checkout"}, HashKeys: []string{"user.id"}},
+		},
+	})
+	require.NoError(t, err)
+
+	c := newTraceQLTestCompiler()
+	c.guard = r.attributeGuard("")
+	for _, tc := range []struct {
+		query     string
+		predicate string
+	}{
+		{
+			`{ span.user.email =~ "a.*" }`,
+			`trace_id IN (SELECT s0.trace_id FROM otel.traces s0 WHERE FALSE)`,
+		},
+		{
+			`{ .user.id = "42" }`,
+			`trace_id IN (SELECT s0.trace_id FROM otel.traces s0 WHERE ((s0.service_name NOT IN ('checkout') AND s0.span_attributes['user.id'] = '42')` +
+				` OR (s0.service_name NOT IN ('checkout') AND s0.resource_attributes['user.id'] = '42')))`,
+		},
+		{
+			`{ span.http.method = "GET" }`,
+			`trace_id IN (SELECT s0.trace_id FROM otel.traces s0 WHERE s0.span_attributes['http.method'] = 'GET')`,
+		},
+	} {
+		query, err := parseTraceQL(tc.query)
+		require.NoError(t, err, tc.query)
+		require.Equal(t, tc.predicate, query.spansets.sql(c), tc.query)
+	}
+}
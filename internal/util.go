@@ -1,22 +1,94 @@
 package internal
 
 import (
+	"unicode/utf8"
+
 	"github.com/jaegertracing/jaeger/model"
 )
 
-const maxLen = 1024
-
-func sanitizeTrace(trace *model.Trace) *model.Trace {
+func sanitizeTrace(cfg *SanitizeConfig, trace *model.Trace) *model.Trace {
 	if trace == nil || len(trace.Spans) == 0 {
 		return trace
 	}
 
 	for _, s := range trace.Spans {
-		for ti := range s.Tags {
-			if s.Tags[ti].VType == model.ValueType_STRING && len(s.Tags[ti].VStr) > maxLen {
-				s.Tags[ti].VStr = s.Tags[ti].VStr[:maxLen-3] + "..."
+		sanitizeSpan(cfg, s)
+	}
+	return trace
+}
+
+// sanitizeSpan cuts the span down to the limits of cfg: tags and logs beyond the maximum
+// count are dropped, and long values of span tags, log fields and process tags are
+// truncated. Spans which have been cut get the marker tag.
+func sanitizeSpan(cfg *SanitizeConfig, s *model.Span) *model.Span {
+	truncated := false
+
+	if cfg.MaxTagsPerSpan > 0 && len(s.Tags) > cfg.MaxTagsPerSpan {
+		s.Tags = s.Tags[:cfg.MaxTagsPerSpan]
+		truncated = true
+	}
+	if cfg.MaxLogsPerSpan > 0 && len(s.Logs) > cfg.MaxLogsPerSpan {
+		s.Logs = s.Logs[:cfg.MaxLogsPerSpan]
+		truncated = true
+	}
+
+	if truncateValues(cfg, s.Tags) {
+		truncated = true
+	}
+	for i := range s.Logs {
+		if truncateValues(cfg, s.Logs[i].Fields) {
+			truncated = true
+		}
+	}
+	if s.Process != nil && truncateValues(cfg, s.Process.Tags) {
+		truncated = true
+	}
+
+	if truncated {
+		s.Tags = append(s.Tags, model.Bool(cfg.MarkerTagKey, true))
+	}
+	return s
+}
+
+// truncateValues truncates the string and binary values exceeding the maximum length of their key,
+// it returns whether any value has been truncated.
+func truncateValues(cfg *SanitizeConfig, kvs []model.KeyValue) bool {
+	truncated := false
+	for i := range kvs {
+		maxLength := cfg.MaxValueLength
+		if l, ok := cfg.keyMaxValueLength[kvs[i].Key]; ok {
+			maxLength = l
+		}
+		if maxLength < 0 {
+			continue
+		}
+
+		switch kvs[i].VType {
+		case model.ValueType_STRING:
+			if len(kvs[i].VStr) > maxLength {
+				kvs[i].VStr = truncateString(kvs[i].VStr, maxLength)
+				truncated = true
+			}
+		case model.ValueType_BINARY:
+			if len(kvs[i].VBinary) > maxLength {
+				kvs[i].VBinary = kvs[i].VBinary[:maxLength]
+				truncated = true
 			}
 		}
 	}
-	return trace
+	return truncated
+}
+
+// truncateString cuts s to at most maxLength bytes ending with "...", without splitting UTF-8 characters.
+func truncateString(s string, maxLength int) string {
+	const ellipsis = "..."
+	if maxLength <= len(ellipsis) {
+		return s[:maxLength]
+	}
+
+	n := maxLength - len(ellipsis)
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + ellipsis
 }
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeSpan(t *testing.T) {
+	cfg := &SanitizeConfig{
+		MaxValueLength:    8,
+		keyMaxValueLength: map[string]int{"db.statement": 16, "raw": -1},
+		MaxTagsPerSpan:    4,
+		MaxLogsPerSpan:    1,
+		MarkerTagKey:      "truncated",
+	}
+
+	span := sanitizeSpan(cfg, &model.Span{
+		Tags: []model.KeyValue{
+			model.String("short", "abc"),
+			model.String("long", "abcdefghijkl"),
+			model.String("db.statement", "SELECT * FROM traces"),
+			model.String("raw", strings.Repeat("x", 32)),
+			model.String("dropped", "abc"),
+		},
+		Logs: []model.Log{
+			{Fields: []model.KeyValue{model.Binary("payload", []byte("0123456789"))}},
+			{Fields: []model.KeyValue{model.String("event", "dropped")}},
+		},
+		Process: &model.Process{
+			Tags: []model.KeyValue{model.String("host", "abc€defgh")},
+		},
+	})
+
+	require.Equal(t, []model.KeyValue{
+		model.String("short", "abc"),
+		model.String("long", "abcde..."),
+		model.String("db.statement", "SELECT * FROM..."),
+		model.String("raw", strings.Repeat("x", 32)),
+		model.Bool("truncated", true),
+	}, span.Tags)
+	require.Equal(t, []model.Log{
+		{Fields: []model.KeyValue{model.Binary("payload", []byte("01234567"))}},
+	}, span.Logs)
+	require.Equal(t, []model.KeyValue{model.String("host", "abc...")}, span.Process.Tags)
+
+	span = sanitizeSpan(cfg, &model.Span{Tags: []model.KeyValue{model.String("short", "abc")}})
+	require.Equal(t, []model.KeyValue{model.String("short", "abc")}, span.Tags)
+}
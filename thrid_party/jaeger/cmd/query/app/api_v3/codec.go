@@ -0,0 +1,45 @@
+// Copyright (c) 2024 The Jaeger Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package api_v3
+
+import (
+	"reflect"
+
+	gogoproto "github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/proto"
+
+	// registers the codec wrapped below
+	_ "github.com/jaegertracing/jaeger/pkg/gogocodec"
+)
+
+// changed: gogocodec uses gogo proto for the types of the jaeger packages only, the types of
+// this copy are outside of them and would lose e.g. their stdtime fields with the default codec.
+func init() {
+	encoding.RegisterCodec(&gogoCodec{next: encoding.GetCodec(proto.Name)})
+}
+
+type gogoCodec struct {
+	next encoding.Codec
+}
+
+var pkgPath = reflect.TypeOf(GetTraceRequest{}).PkgPath()
+
+func (*gogoCodec) Name() string {
+	return proto.Name
+}
+
+func (c *gogoCodec) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(gogoproto.Message); ok && reflect.TypeOf(v).Elem().PkgPath() == pkgPath {
+		return gogoproto.Marshal(m)
+	}
+	return c.next.Marshal(v)
+}
+
+func (c *gogoCodec) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(gogoproto.Message); ok && reflect.TypeOf(v).Elem().PkgPath() == pkgPath {
+		return gogoproto.Unmarshal(data, m)
+	}
+	return c.next.Unmarshal(data, v)
+}
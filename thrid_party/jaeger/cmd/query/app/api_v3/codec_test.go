@@ -0,0 +1,63 @@
+// Copyright (c) 2024 The Jaeger Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package api_v3
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// changed: round trip through gRPC to check the codec registered by this copy
+type echoServer struct {
+	UnimplementedQueryServiceServer
+}
+
+func (*echoServer) GetTrace(r *GetTraceRequest, stream QueryService_GetTraceServer) error {
+	td := ptrace.NewTraces()
+	span := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetName(r.TraceId)
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(*r.StartTime))
+	tracesData := TracesData(td)
+	return stream.Send(&tracesData)
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	RegisterQueryServiceServer(server, &echoServer{})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	startTime := time.Unix(100, 0).UTC()
+	stream, err := NewQueryServiceClient(conn).GetTrace(context.Background(), &GetTraceRequest{
+		TraceId:   "0123",
+		StartTime: &startTime,
+	})
+	require.NoError(t, err)
+	td, err := stream.Recv()
+	require.NoError(t, err)
+
+	span := td.ToTraces().ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, "0123", span.Name())
+	assert.Equal(t, startTime, span.StartTimestamp().AsTime())
+}
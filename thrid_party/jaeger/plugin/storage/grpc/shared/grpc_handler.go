@@ -5,7 +5,6 @@ package shared
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"google.golang.org/grpc"
@@ -14,6 +13,11 @@ import (
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
 	"io"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"github.com/jaegertracing/jaeger/model"
 	_ "github.com/jaegertracing/jaeger/pkg/gogocodec" // force gogo codec registration
@@ -25,6 +29,10 @@ import (
 // changed: default span batch size, may be overridden  by configuration
 const defaultSpanBatchSize = 1000
 
+// changed: default maximum size of a response message, the default maximum
+// receive message size of gRPC clients
+const defaultMaxMessageBytes = 4 * 1024 * 1024
+
 // GRPCHandler implements all methods of Remote Storage gRPC API.
 type GRPCHandler struct {
 	// impl        StoragePlugin
@@ -37,6 +45,12 @@ type GRPCHandler struct {
 // GRPCHandlerOptions contains grpc handler options
 type GRPCHandlerOptions struct {
 	SpanBatchSize int
+	// changed: StreamSpans sends spans while they are read by readers implementing StreamingSpanReader
+	StreamSpans bool
+	// changed: MaxMessageBytes limits the estimated size of a chunk of spans
+	MaxMessageBytes int
+	// changed: Logger logs failures to send spans
+	Logger *zap.Logger
 }
 
 // GRPCHandlerStorageImpl contains accessors for various storage implementations needed by the handler.
@@ -154,7 +168,24 @@ func (s *GRPCHandler) Close(context.Context, *storage_v1.CloseWriterRequest) (*s
 
 // GetTrace takes a traceID and streams a Trace associated with that traceID
 func (s *GRPCHandler) GetTrace(r *storage_v1.GetTraceRequest, stream storage_v1.SpanReaderPlugin_GetTraceServer) error {
-	trace, err := s.impl.SpanReader().GetTrace(stream.Context(), r.TraceID)
+	// changed: pass the time window hints to readers supporting them
+	var trace *model.Trace
+	var err error
+	reader := s.impl.SpanReader()
+	if sr, ok := reader.(StreamingSpanReader); ok && s.streamSpans() {
+		err = sr.StreamTrace(stream.Context(), getTraceParameters(r), func(spans []*model.Span) error {
+			return s.sendSpans(spans, stream.Send)
+		})
+		if errors.Is(err, spanstore.ErrTraceNotFound) {
+			return status.Error(codes.NotFound, spanstore.ErrTraceNotFound.Error())
+		}
+		return err
+	}
+	if pr, ok := reader.(TraceParametersReader); ok {
+		trace, err = pr.GetTraceWithParameters(stream.Context(), getTraceParameters(r))
+	} else {
+		trace, err = reader.GetTrace(stream.Context(), r.TraceID)
+	}
 	if errors.Is(err, spanstore.ErrTraceNotFound) {
 		return status.Error(codes.NotFound, spanstore.ErrTraceNotFound.Error())
 	}
@@ -207,7 +238,7 @@ func (s *GRPCHandler) GetOperations(
 
 // FindTraces streams traces that match the traceQuery
 func (s *GRPCHandler) FindTraces(r *storage_v1.FindTracesRequest, stream storage_v1.SpanReaderPlugin_FindTracesServer) error {
-	traces, err := s.impl.SpanReader().FindTraces(stream.Context(), &spanstore.TraceQueryParameters{
+	query := &spanstore.TraceQueryParameters{
 		ServiceName:   r.Query.ServiceName,
 		OperationName: r.Query.OperationName,
 		Tags:          r.Query.Tags,
@@ -216,7 +247,17 @@ func (s *GRPCHandler) FindTraces(r *storage_v1.FindTracesRequest, stream storage
 		DurationMin:   r.Query.DurationMin,
 		DurationMax:   r.Query.DurationMax,
 		NumTraces:     int(r.Query.NumTraces),
-	})
+	}
+
+	// changed: stream the spans with readers supporting it
+	reader := s.impl.SpanReader()
+	if sr, ok := reader.(StreamingSpanReader); ok && s.streamSpans() {
+		return sr.StreamTraces(stream.Context(), query, func(spans []*model.Span) error {
+			return s.sendSpans(spans, stream.Send)
+		})
+	}
+
+	traces, err := reader.FindTraces(stream.Context(), query)
 	if err != nil {
 		return err
 	}
@@ -256,33 +297,185 @@ func (s *GRPCHandler) sendSpans(spans []*model.Span, sendFn func(*storage_v1.Spa
 
 	// Changed: use batch size from the configuration, if available
 	spanBatchSize := defaultSpanBatchSize
+	maxMessageBytes := defaultMaxMessageBytes
 	if s.opts != nil {
 		spanBatchSize = s.opts.SpanBatchSize
+		if s.opts.MaxMessageBytes > 0 {
+			maxMessageBytes = s.opts.MaxMessageBytes
+		}
 	}
-	for i := 0; i < len(spans); i += spanBatchSize {
-		chunk = chunk[:0]
-		for j := i; j < len(spans) && j < i+spanBatchSize; j++ {
-			chunk = append(chunk, *spans[j])
+
+	// changed: also limit the estimated size of a chunk, so that it does not exceed
+	// the maximum gRPC message size of the client
+	chunkBytes := 0
+	send := func() error {
+		if len(chunk) == 0 {
+			return nil
 		}
 		pld := storage_v1.SpansResponseChunk{Spans: chunk}
 		if err := sendFn(&pld); err != nil {
+			s.logger().Error("Failed to send spans",
+				zap.Int("spans", len(chunk)),
+				zap.Int("estimated_bytes", chunkBytes),
+				zap.Error(err),
+			)
+			return fmt.Errorf("grpc plugin failed to send response: %w", err)
+		}
+		chunk = chunk[:0]
+		chunkBytes = 0
+		return nil
+	}
 
-			fmt.Printf("===== chunl len: %v\n", len(chunk))
-			b, _ := json.Marshal(pld)
-			fmt.Printf("===== len pld: %v\n", len(b))
-			//for _, item := range chunk {
-			// fmt.Printf("===== item: %+v\n", item)
-			//b, _ := json.Marshal(item)
-			//fmt.Printf("===== chunk len: %v\n", len(b))
-			//}
-			// fmt.Printf("==== chunk: %#v\n", chunk)
+	for _, span := range spans {
+		spanBytes := chunkedSpanSize(span)
+		if spanBytes > maxMessageBytes {
+			span = s.trimSpan(span, maxMessageBytes)
+			spanBytes = chunkedSpanSize(span)
+		}
+		if len(chunk) >= spanBatchSize || (len(chunk) > 0 && chunkBytes+spanBytes > maxMessageBytes) {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+		chunk = append(chunk, *span)
+		chunkBytes += spanBytes
+	}
 
-			return err
-			return fmt.Errorf("grpc plugin failed to send response: %w", err)
+	return send()
+}
+
+// changed: chunkedSpanSize estimates the encoded size of the span as an element of SpansResponseChunk.
+func chunkedSpanSize(span *model.Span) int {
+	size := span.Size()
+	return protowire.SizeTag(1) + protowire.SizeBytes(size)
+}
+
+// changed: trimSpan drops the largest tags, and then the largest logs, of a span which does not
+// fit into a single message. The returned span is a copy with a warning about the dropped data.
+func (s *GRPCHandler) trimSpan(span *model.Span, maxMessageBytes int) *model.Span {
+	trimmed := *span
+	trimmed.Tags = append([]model.KeyValue(nil), span.Tags...)
+	trimmed.Logs = append([]model.Log(nil), span.Logs...)
+	sort.SliceStable(trimmed.Tags, func(i, j int) bool {
+		return trimmed.Tags[i].Size() < trimmed.Tags[j].Size()
+	})
+	sort.SliceStable(trimmed.Logs, func(i, j int) bool {
+		return trimmed.Logs[i].Size() < trimmed.Logs[j].Size()
+	})
+
+	// reserve some room for the warning
+	limit := maxMessageBytes - 256
+	droppedTags, droppedLogs := 0, 0
+	for chunkedSpanSize(&trimmed) > limit && len(trimmed.Tags) > 0 {
+		trimmed.Tags = trimmed.Tags[:len(trimmed.Tags)-1]
+		droppedTags++
+	}
+	for chunkedSpanSize(&trimmed) > limit && len(trimmed.Logs) > 0 {
+		trimmed.Logs = trimmed.Logs[:len(trimmed.Logs)-1]
+		droppedLogs++
+	}
+
+	s.logger().Warn("Trimmed span exceeding the maximum message size",
+		zap.Stringer("trace_id", span.TraceID),
+		zap.Stringer("span_id", span.SpanID),
+		zap.Int("dropped_tags", droppedTags),
+		zap.Int("dropped_logs", droppedLogs),
+	)
+	trimmed.Warnings = append(append([]string(nil), span.Warnings...), fmt.Sprintf(
+		"%d tags and %d logs dropped, the span exceeds the maximum message size of %d bytes",
+		droppedTags, droppedLogs, maxMessageBytes,
+	))
+
+	return &trimmed
+}
+
+// changed: logger returns the logger of the options, or a no-op logger.
+func (s *GRPCHandler) logger() *zap.Logger {
+	if s.opts != nil && s.opts.Logger != nil {
+		return s.opts.Logger
+	}
+	return zap.NewNop()
+}
+
+// changed: streamSpans tells whether spans are streamed to the client.
+func (s *GRPCHandler) streamSpans() bool {
+	return s.opts != nil && s.opts.StreamSpans
+}
+
+// changed: getTraceParameters extracts the optional start_time (2) and end_time (3) fields,
+// which newer versions of jaeger-query send, from the unknown fields of the request.
+func getTraceParameters(r *storage_v1.GetTraceRequest) GetTraceParameters {
+	query := GetTraceParameters{TraceID: r.TraceID}
+
+	b := r.XXX_unrecognized
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+
+		if (num == 2 || num == 3) && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				break
+			}
+			b = b[n:]
+
+			if t, ok := parseTimestamp(v); ok {
+				if num == 2 {
+					query.StartTime = t
+				} else {
+					query.EndTime = t
+				}
+			}
+			continue
 		}
+
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
 	}
 
-	return nil
+	return query
+}
+
+// changed: parseTimestamp decodes a google.protobuf.Timestamp message.
+func parseTimestamp(b []byte) (time.Time, bool) {
+	var seconds, nanos int64
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return time.Time{}, false
+		}
+		b = b[n:]
+
+		if typ != protowire.VarintType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return time.Time{}, false
+			}
+			b = b[n:]
+			continue
+		}
+
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return time.Time{}, false
+		}
+		b = b[n:]
+
+		switch num {
+		case 1:
+			seconds = int64(v)
+		case 2:
+			nanos = int64(int32(v))
+		}
+	}
+
+	return time.Unix(seconds, nanos).UTC(), true
 }
 
 func (s *GRPCHandler) Capabilities(context.Context, *storage_v1.CapabilitiesRequest) (*storage_v1.CapabilitiesResponse, error) {
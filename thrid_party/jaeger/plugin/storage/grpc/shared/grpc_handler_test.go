@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/proto-gen/storage_v1"
@@ -25,6 +27,43 @@ import (
 	spanStoreMocks "github.com/jaegertracing/jaeger/storage/spanstore/mocks"
 )
 
+// changed: fixtures from grpc_client_test.go, which is not part of this copy
+var (
+	mockTraceID  = model.NewTraceID(0, 123456)
+	mockTraceID2 = model.NewTraceID(0, 123457)
+
+	mockTraceSpans = []model.Span{
+		{
+			TraceID: mockTraceID,
+			SpanID:  model.NewSpanID(1),
+			Process: &model.Process{},
+		},
+		{
+			TraceID: mockTraceID,
+			SpanID:  model.NewSpanID(2),
+			Process: &model.Process{},
+		},
+	}
+
+	mockTracesSpans = []model.Span{
+		{
+			TraceID: mockTraceID,
+			SpanID:  model.NewSpanID(1),
+			Process: &model.Process{},
+		},
+		{
+			TraceID: mockTraceID,
+			SpanID:  model.NewSpanID(2),
+			Process: &model.Process{},
+		},
+		{
+			TraceID: mockTraceID2,
+			SpanID:  model.NewSpanID(1),
+			Process: &model.Process{},
+		},
+	}
+)
+
 type mockStoragePlugin struct {
 	spanReader    *spanStoreMocks.Reader
 	spanWriter    *spanStoreMocks.Writer
@@ -80,7 +119,7 @@ func withGRPCServer(fn func(r *grpcServerTest)) {
 		streamWriter:  streamWriter,
 	}
 
-	handler := NewGRPCHandlerWithPlugins(impl, impl, impl)
+	handler := NewGRPCHandlerWithPlugins(impl, impl, impl, nil)
 	defer handler.Close(context.Background(), &storage_v1.CloseWriterRequest{})
 	r := &grpcServerTest{
 		server: handler,
@@ -445,8 +484,81 @@ func TestNewGRPCHandlerWithPlugins_Nils(t *testing.T) {
 		depsReader: depReader,
 	}
 
-	handler := NewGRPCHandlerWithPlugins(impl, nil, nil)
+	handler := NewGRPCHandlerWithPlugins(impl, nil, nil, nil)
 	assert.Nil(t, handler.impl.ArchiveSpanReader())
 	assert.Nil(t, handler.impl.ArchiveSpanWriter())
 	assert.Nil(t, handler.impl.StreamingSpanWriter())
 }
+
+// changed: chunks are limited by the estimated message size, oversized spans are trimmed
+func TestSendSpansMaxMessageBytes(t *testing.T) {
+	handler := NewGRPCHandlerWithPlugins(&mockStoragePlugin{}, nil, nil, &GRPCHandlerOptions{
+		SpanBatchSize:   100,
+		MaxMessageBytes: 1024,
+	})
+
+	spans := make([]*model.Span, 0, 4)
+	for i := 0; i < 3; i++ {
+		spans = append(spans, &model.Span{
+			TraceID: mockTraceID,
+			SpanID:  model.NewSpanID(uint64(i + 1)),
+			Tags:    []model.KeyValue{model.String("payload", strings.Repeat("x", 400))},
+		})
+	}
+	oversized := &model.Span{
+		TraceID: mockTraceID,
+		SpanID:  model.NewSpanID(4),
+		Tags: []model.KeyValue{
+			model.String("small", "value"),
+			model.String("huge", strings.Repeat("x", 4096)),
+		},
+	}
+	spans = append(spans, oversized)
+
+	var chunks []*storage_v1.SpansResponseChunk
+	err := handler.sendSpans(spans, func(chunk *storage_v1.SpansResponseChunk) error {
+		assert.LessOrEqual(t, chunk.Size(), 1024)
+		chunks = append(chunks, &storage_v1.SpansResponseChunk{Spans: append([]model.Span(nil), chunk.Spans...)})
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	assert.Len(t, chunks[0].Spans, 2)
+
+	last := chunks[1].Spans[len(chunks[1].Spans)-1]
+	assert.Equal(t, []model.KeyValue{model.String("small", "value")}, last.Tags)
+	assert.Len(t, last.Warnings, 1)
+	assert.Len(t, oversized.Tags, 2)
+
+	err = handler.sendSpans(spans[:1], func(*storage_v1.SpansResponseChunk) error {
+		return errors.New("send failed")
+	})
+	require.ErrorContains(t, err, "send failed")
+}
+
+// changed: time window hints sent by newer versions of jaeger-query
+func TestGetTraceParameters(t *testing.T) {
+	start := time.Date(2024, 1, 1, 1, 1, 1, 1000, time.UTC)
+	end := start.Add(time.Hour)
+
+	timestamp := func(ts time.Time) []byte {
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(ts.Unix()))
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(ts.Nanosecond()))
+		return b
+	}
+
+	var unknown []byte
+	unknown = protowire.AppendTag(unknown, 2, protowire.BytesType)
+	unknown = protowire.AppendBytes(unknown, timestamp(start))
+	unknown = protowire.AppendTag(unknown, 3, protowire.BytesType)
+	unknown = protowire.AppendBytes(unknown, timestamp(end))
+
+	query := getTraceParameters(&storage_v1.GetTraceRequest{TraceID: mockTraceID, XXX_unrecognized: unknown})
+	assert.Equal(t, GetTraceParameters{TraceID: mockTraceID, StartTime: start, EndTime: end}, query)
+
+	query = getTraceParameters(&storage_v1.GetTraceRequest{TraceID: mockTraceID})
+	assert.Equal(t, GetTraceParameters{TraceID: mockTraceID}, query)
+}
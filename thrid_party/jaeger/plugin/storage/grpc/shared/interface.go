@@ -4,6 +4,10 @@
 package shared
 
 import (
+	"context"
+	"time"
+
+	"github.com/jaegertracing/jaeger/model"
 	"github.com/jaegertracing/jaeger/storage/dependencystore"
 	"github.com/jaegertracing/jaeger/storage/spanstore"
 )
@@ -44,3 +48,26 @@ type PluginServices struct {
 	ArchiveStore        ArchiveStoragePlugin
 	StreamingSpanWriter StreamingSpanWriterPlugin
 }
+
+// changed: GetTraceParameters mirrors spanstore.GetTraceParameters of newer Jaeger versions.
+// StartTime and EndTime are optional hints which narrow down the time range to search.
+type GetTraceParameters struct {
+	TraceID   model.TraceID
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// changed: TraceParametersReader is implemented by span readers which can make use of
+// the time window hints of GetTraceParameters.
+type TraceParametersReader interface {
+	GetTraceWithParameters(ctx context.Context, query GetTraceParameters) (*model.Trace, error)
+}
+
+// changed: StreamingSpanReader is implemented by span readers which can pass spans to fn
+// in batches while they are read, instead of buffering whole traces. The spans of each
+// trace are passed contiguously. StreamTrace returns spanstore.ErrTraceNotFound if the
+// trace has no spans.
+type StreamingSpanReader interface {
+	StreamTrace(ctx context.Context, query GetTraceParameters, fn func(spans []*model.Span) error) error
+	StreamTraces(ctx context.Context, query *spanstore.TraceQueryParameters, fn func(spans []*model.Span) error) error
+}